@@ -4,20 +4,61 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ari/agent-usage/internal/config"
+	"github.com/ari/agent-usage/internal/notify"
+	"github.com/ari/agent-usage/internal/server"
 	"github.com/ari/agent-usage/internal/tracker"
 	"github.com/ari/agent-usage/internal/ui"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfgPath string
-	cfg     *config.Config
-	debug   bool
+	cfgPath  string
+	cfg      *config.Config
+	debug    bool
+	sortBy   string
+	format   string
+	since    string
+	until    string
+	tz       string
+	project  string
+	agentBy  string
+	modelBy  string
+	limit    int
+	page     int
+	rawQuery bool
+	output   string
+	status   bool
+	topN     int
+
+	// exportFormat, sessionSort and toolLimit are separate from format, sortBy
+	// and topN, whose defaults differ
+	exportFormat string
+	sessionSort  string
+	toolLimit    int
+
+	// debounce is how long watch waits for writes to a session file to settle
+	debounce time.Duration
+
+	// noSync skips the sync before budget check
+	noSync bool
+
+	serveAddr           string
+	serveWatch          bool
+	metricsProjectLabel bool
+
+	// syncOut receives sync progress; it is stderr when a machine-readable format is selected
+	syncOut io.Writer = os.Stdout
 )
 
 var rootCmd = &cobra.Command{
@@ -44,8 +85,9 @@ var infoCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("=== Configuration ===")
 		fmt.Printf("  Agents:\n")
-		fmt.Printf("    Codex: %v\n", cfg.Agents.Codex)
-		fmt.Printf("    Claude: %v\n", cfg.Agents.ClaudeCode)
+		for _, p := range tracker.Providers() {
+			fmt.Printf("    %s: %v\n", p.DisplayName(), cfg.IsAgentEnabled(string(p.Name())))
+		}
 
 		// Get last sync times from database
 		dbPath := cfg.GetDatabasePath()
@@ -64,61 +106,50 @@ var infoCmd = &cobra.Command{
 
 		ctx := context.Background()
 		fmt.Println("\n=== Last Sync ===")
-		if cfg.Agents.Codex {
-			syncTime, _ := db.GetLastSyncTime(ctx, "codex")
+		for _, p := range enabledProviders() {
+			syncTime, _ := db.GetLastSyncTime(ctx, string(p.Name()))
 			if syncTime > 0 {
-				fmt.Printf("  Codex: %s\n", time.Unix(syncTime, 0).Format("2006-01-02 15:04:05"))
+				fmt.Printf("  %s: %s\n", p.DisplayName(), time.Unix(syncTime, 0).Format("2006-01-02 15:04:05"))
 			} else {
-				fmt.Printf("  Codex: Never synced\n")
-			}
-		}
-		if cfg.Agents.ClaudeCode {
-			syncTime, _ := db.GetLastSyncTime(ctx, "claude")
-			if syncTime > 0 {
-				fmt.Printf("  Claude: %s\n", time.Unix(syncTime, 0).Format("2006-01-02 15:04:05"))
-			} else {
-				fmt.Printf("  Claude: Never synced\n")
+				fmt.Printf("  %s: Never synced\n", p.DisplayName())
 			}
 		}
 	},
 }
 
+const periodHelp = `Period can be a rolling day, week or month (default: day),
+a calendar period (today, yesterday, this-week, last-week, this-month, last-month),
+a month (2026-09) or a date (2026-09-15). --since and --until override the start
+and end of the period with RFC3339 times, dates or relative durations like 3d;
+a --until date includes that day.`
+
 var usageCmd = &cobra.Command{
 	Use:   "usage <agent> [period]",
 	Short: "Show usage statistics for an agent",
-	Long:  "Show usage statistics for Codex or Claude. Period can be day, week, or month (default: day)",
+	Long:  "Show usage statistics for a single agent. " + periodHelp,
 	Args:  cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
 		agentName := args[0]
-		period := tracker.PeriodDay
+		var periodName string
 		if len(args) > 1 {
-			switch args[1] {
-			case "day":
-				period = tracker.PeriodDay
-			case "week":
-				period = tracker.PeriodWeek
-			case "month":
-				period = tracker.PeriodMonth
-			default:
-				fmt.Printf("Invalid period: %s. Use day, week, or month\n", args[1])
-				os.Exit(1)
-			}
+			periodName = args[1]
 		}
+		period := parseTimeRange(periodName)
+
+		sort := parseSort()
+		projectFilter := parseProject()
+		renderer := newRenderer()
 
 		// Validate agent name
-		var agent tracker.Agent
-		switch agentName {
-		case "codex":
-			agent = tracker.AgentCodex
-		case "claude":
-			agent = tracker.AgentClaudeCode
-		default:
-			fmt.Printf("Invalid agent: %s. Use codex or claude\n", agentName)
+		provider, ok := tracker.LookupProvider(agentName)
+		if !ok {
+			fmt.Printf("Invalid agent: %s. Use %s\n", agentName, strings.Join(tracker.ProviderNames(), " or "))
 			os.Exit(1)
 		}
+		agent := provider.Name()
 
 		// Run sync for the selected agent
-		runSync(agentName)
+		runSync(provider)
 
 		// Get database path
 		dbPath := cfg.GetDatabasePath()
@@ -127,9 +158,10 @@ var usageCmd = &cobra.Command{
 		dir := filepath.Dir(dbPath)
 		if _, err := os.Stat(dir); os.IsNotExist(err) {
 			// Database doesn't exist yet, show empty stats
-			ui.DisplayUsageStats(agentName, period, &tracker.UsageStatsData{
+			render(renderer.RenderUsageStats(os.Stdout, agentName, period, &tracker.UsageStatsData{
 				TopModels: []tracker.ModelUsage{},
-			})
+				Project:   projectFilter,
+			}))
 			return
 		}
 
@@ -144,30 +176,20 @@ var usageCmd = &cobra.Command{
 		// Enable debug mode on tracker if flag is set
 		db.SetDebug(debug)
 
-		// Calculate time filter for debug output
+		// Show the time filter in debug output, which goes with sync progress
+		// to stderr when a machine-readable format is selected
 		if debug {
-			now := time.Now()
-			var startTime time.Time
-			switch period {
-			case tracker.PeriodDay:
-				startTime = now.AddDate(0, 0, -1)
-			case tracker.PeriodWeek:
-				startTime = now.AddDate(0, 0, -7)
-			case tracker.PeriodMonth:
-				startTime = now.AddDate(0, 0, -30)
-			default:
-				startTime = now.AddDate(0, 0, -1)
-			}
-			fmt.Printf("\n%s[DEBUG] Time Filter:%s\n", ui.ColorBlue, ui.ColorReset)
-			fmt.Printf("  Period: %s\n", period)
-			fmt.Printf("  Start:  %s (timestamp: %d)\n", startTime.Format("2006-01-02 15:04:05"), startTime.Unix())
-			fmt.Printf("  End:    %s (timestamp: %d)\n", now.Format("2006-01-02 15:04:05"), now.Unix())
-			fmt.Printf("  Agent:  %s\n\n", agent)
+			fmt.Fprintf(syncOut, "\n%s[DEBUG] Time Filter:%s\n", ui.ColorBlue, ui.ColorReset)
+			fmt.Fprintf(syncOut, "  Period: %s\n", period)
+			fmt.Fprintf(syncOut, "  Start:  %s (timestamp: %d)\n", period.Start.Format("2006-01-02 15:04:05"), period.Start.Unix())
+			fmt.Fprintf(syncOut, "  End:    %s (timestamp: %d)\n", period.End.Format("2006-01-02 15:04:05"), period.End.Unix())
+			fmt.Fprintf(syncOut, "  Agent:  %s\n", agent)
+			fmt.Fprintf(syncOut, "  Project: %s\n\n", projectFilter)
 		}
 
 		// Get usage stats
 		ctx := context.Background()
-		stats, err := db.GetUsageStats(ctx, agent, period)
+		stats, err := db.GetUsageStats(ctx, agent, period, projectFilter, sort)
 		if err != nil {
 			ui.Error(fmt.Sprintf("Error getting usage stats: %v", err))
 			os.Exit(1)
@@ -175,58 +197,53 @@ var usageCmd = &cobra.Command{
 
 		// Show debug output for raw session data
 		if debug {
-			sessions, err := db.GetSessionsInPeriod(ctx, agent, period)
+			sessions, err := db.GetSessionsInPeriod(ctx, agent, period, projectFilter)
 			if err != nil {
 				ui.Error(fmt.Sprintf("Error getting sessions: %v", err))
 				os.Exit(1)
 			}
 
-			fmt.Printf("%s[DEBUG] Sessions Data (%d sessions):%s\n", ui.ColorBlue, len(sessions), ui.ColorReset)
+			fmt.Fprintf(syncOut, "%s[DEBUG] Sessions Data (%d sessions):%s\n", ui.ColorBlue, len(sessions), ui.ColorReset)
 			for i, s := range sessions {
-				fmt.Printf("  %d. ID: %s\n", i+1, s.ExternalID)
-				fmt.Printf("     Model: %s, Project: %s\n", s.Model, s.ProjectPath)
-				fmt.Printf("     Started: %s\n", ui.FormatDateTime(s.StartedAt))
+				fmt.Fprintf(syncOut, "  %d. ID: %s\n", i+1, s.ExternalID)
+				fmt.Fprintf(syncOut, "     Model: %s, Project: %s\n", s.Model, s.ProjectPath)
+				fmt.Fprintf(syncOut, "     Started: %s\n", ui.FormatDateTime(s.StartedAt))
 				if s.EndedAt != nil {
 					duration := *s.EndedAt - s.StartedAt
-					fmt.Printf("     Ended: %s, Duration: %s\n", ui.FormatDateTime(*s.EndedAt), ui.FormatDuration(duration))
+					fmt.Fprintf(syncOut, "     Ended: %s, Duration: %s\n", ui.FormatDateTime(*s.EndedAt), ui.FormatDuration(duration))
 				} else {
-					fmt.Printf("     Ended: (active)\n")
+					fmt.Fprintf(syncOut, "     Ended: (active)\n")
 				}
-				fmt.Printf("     Tokens: %s (in: %s, out: %s, cache: %s/%s)\n",
+				fmt.Fprintf(syncOut, "     Tokens: %s (in: %s, out: %s, cache: %s/%s)\n",
 					ui.FormatTokens(s.TotalTokens),
 					ui.FormatTokens(s.InputTokens),
 					ui.FormatTokens(s.OutputTokens),
 					ui.FormatTokens(s.CacheCreationTokens),
 					ui.FormatTokens(s.CacheReadTokens))
 			}
-			fmt.Println()
+			fmt.Fprintln(syncOut)
 		}
 
 		// Display stats
-		ui.DisplayUsageStats(agentName, period, stats)
+		render(renderer.RenderUsageStats(os.Stdout, agentName, period, stats))
 	},
 }
 
 var statsCmd = &cobra.Command{
 	Use:   "stats [period]",
 	Short: "Show combined usage stats for all agents",
-	Long:  "Show usage statistics for all agents combined. Period can be day, week, or month (default: day)",
+	Long:  "Show usage statistics for all agents combined, and the usage of any configured budgets in their current day, week or month. " + periodHelp,
 	Args:  cobra.RangeArgs(0, 1),
 	Run: func(cmd *cobra.Command, args []string) {
-		period := tracker.PeriodDay
+		var periodName string
 		if len(args) > 0 {
-			switch args[0] {
-			case "day":
-				period = tracker.PeriodDay
-			case "week":
-				period = tracker.PeriodWeek
-			case "month":
-				period = tracker.PeriodMonth
-			default:
-				fmt.Printf("Invalid period: %s. Use day, week, or month\n", args[0])
-				os.Exit(1)
-			}
+			periodName = args[0]
 		}
+		period := parseTimeRange(periodName)
+
+		sort := parseSort()
+		projectFilter := parseProject()
+		renderer := newRenderer()
 
 		// Run sync for all enabled agents
 		runSyncAll()
@@ -238,9 +255,10 @@ var statsCmd = &cobra.Command{
 		dir := filepath.Dir(dbPath)
 		if _, err := os.Stat(dir); os.IsNotExist(err) {
 			// Database doesn't exist yet, show empty stats
-			ui.DisplayAllStats(period, &tracker.UsageStatsData{
+			render(renderer.RenderAllStats(os.Stdout, period, &tracker.UsageStatsData{
 				TopModels: []tracker.ModelUsage{},
-			}, []tracker.PerAgentStats{})
+				Project:   projectFilter,
+			}, []tracker.PerAgentStats{}))
 			return
 		}
 
@@ -254,50 +272,751 @@ var statsCmd = &cobra.Command{
 
 		// Get usage stats
 		ctx := context.Background()
-		stats, err := db.GetUsageStatsAll(ctx, period)
+		stats, err := db.GetUsageStatsAll(ctx, period, projectFilter, sort)
 		if err != nil {
 			ui.Error(fmt.Sprintf("Error getting usage stats: %v", err))
 			os.Exit(1)
 		}
 
 		// Get per-agent stats
-		perAgent, err := db.GetPerAgentStats(ctx, period)
+		perAgent, err := db.GetPerAgentStats(ctx, period, projectFilter)
 		if err != nil {
 			ui.Error(fmt.Sprintf("Error getting per-agent stats: %v", err))
 			os.Exit(1)
 		}
+		stats.Budgets = checkBudgets(ctx, db)
 
 		// Display stats
-		ui.DisplayAllStats(period, stats, perAgent)
+		render(renderer.RenderAllStats(os.Stdout, period, stats, perAgent))
 	},
 }
 
-// runSync runs the sync for a given agent
-func runSync(agentName string) {
-	var sessionsDir string
-	var parseFunc func(string) (interface{}, error)
-	var trackFunc func(*tracker.SQLiteTracker, context.Context, interface{}) error
+var usageTurnsCmd = &cobra.Command{
+	Use:   "turns [period]",
+	Short: "Show per-turn token usage and latency",
+	Long: "Show the distribution of tokens per model turn, the slowest turns measured from the prior user message, " +
+		"and the sessions whose context grows fastest per turn. " +
+		"Without a period, --since or --until, all sessions are included. " + periodHelp,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var periodName string
+		if len(args) > 0 {
+			periodName = args[0]
+		}
+		filter := parseSessionFilter(periodName)
+		renderer := newRenderer()
+
+		runSyncAll()
+
+		db := openTracker()
+		defer db.Close()
+
+		stats, err := db.GetTurnStats(context.Background(), filter, topN)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error getting turn stats: %v", err))
+			os.Exit(1)
+		}
+
+		render(renderer.RenderTurns(os.Stdout, &ui.TurnsReport{TurnStats: stats}))
+	},
+}
+
+var toolsCmd = &cobra.Command{
+	Use:   "tools [period]",
+	Short: "Show tool usage",
+	Long: "Show how often agents call each tool, how often the calls fail and how long they take, " +
+		"broken down by agent and project, with the most run shell commands and most edited files. " +
+		"Without a period, --since or --until, all sessions are included. " + periodHelp,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var periodName string
+		if len(args) > 0 {
+			periodName = args[0]
+		}
+		filter := parseSessionFilter(periodName)
+		renderer := newRenderer()
+
+		runSyncAll()
+
+		db := openTracker()
+		defer db.Close()
+
+		stats, err := db.GetToolStats(context.Background(), filter, toolLimit)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error getting tool stats: %v", err))
+			os.Exit(1)
+		}
+
+		render(renderer.RenderTools(os.Stdout, &ui.ToolsReport{ToolStats: stats}))
+	},
+}
+
+var projectsCmd = &cobra.Command{
+	Use:   "projects [period]",
+	Short: "Show usage per project",
+	Long:  "Show sessions, active time, tokens, cost, messages, top model and last activity for each project. " + periodHelp,
+	Args:  cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var periodName string
+		if len(args) > 0 {
+			periodName = args[0]
+		}
+		period := parseTimeRange(periodName)
+
+		sort := parseProjectSort()
+		report := &ui.ProjectsReport{Project: parseProject(), SortBy: sort}
+		renderer := newRenderer()
+
+		// Run sync for all enabled agents
+		runSyncAll()
+
+		// Get database path
+		dbPath := cfg.GetDatabasePath()
+
+		// Database doesn't exist yet, show an empty report
+		if _, err := os.Stat(filepath.Dir(dbPath)); os.IsNotExist(err) {
+			render(renderer.RenderProjects(os.Stdout, period, report))
+			return
+		}
+
+		// Open database
+		db, err := tracker.NewSQLiteTracker(dbPath)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error opening database: %v", err))
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		report.Projects, err = db.GetProjectStats(context.Background(), "", period, report.Project, sort)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error getting project stats: %v", err))
+			os.Exit(1)
+		}
+
+		render(renderer.RenderProjects(os.Stdout, period, report))
+	},
+}
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions [period]",
+	Short: "List sessions",
+	Long: "List stored sessions, newest first, filtered by agent, project, model and date. " +
+		"Without a period, --since or --until, all sessions are listed. " + periodHelp,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var periodName string
+		if len(args) > 0 {
+			periodName = args[0]
+		}
+		filter := parseSessionFilter(periodName)
+		filter.Sort = parseSessionSort()
+		renderer := newRenderer()
+
+		runSyncAll()
+
+		db := openTracker()
+		defer db.Close()
+
+		sessions, total, err := db.ListSessions(context.Background(), filter)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error listing sessions: %v", err))
+			os.Exit(1)
+		}
+
+		render(renderer.RenderSessions(os.Stdout, &ui.SessionsReport{
+			Total:    total,
+			Page:     page,
+			Limit:    limit,
+			SortBy:   filter.Sort,
+			Sessions: sessions,
+		}))
+	},
+}
+
+var sessionCmd = &cobra.Command{
+	Use:   "session <external-id>",
+	Short: "Show a single session",
+	Long:  "Show the metadata, token and cost breakdown, messages and tool calls of a session. A unique prefix of the session ID is enough.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		renderer := newRenderer()
+
+		runSyncAll()
+
+		db := openTracker()
+		defer db.Close()
+
+		detail, err := db.GetSessionDetail(context.Background(), args[0])
+		if err != nil {
+			ui.Error(err.Error())
+			os.Exit(1)
+		}
+
+		render(renderer.RenderSession(os.Stdout, detail))
+	},
+}
+
+var exportSessionCmd = &cobra.Command{
+	Use:   "export-session <external-id>",
+	Short: "Export a session transcript as Markdown, HTML or JSON",
+	Long: "Write the transcript of a stored session, with its token and cost summary, messages and tool calls. " +
+		"Thinking and tool calls are collapsible in Markdown and HTML. A unique prefix of the session ID is enough.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		transcriptFormat, err := ui.ParseExportFormat(exportFormat)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if output == "" {
+			syncOut = os.Stderr
+		}
+
+		runSyncAll()
+
+		db := openTracker()
+		defer db.Close()
+
+		detail, err := db.GetSessionDetail(context.Background(), args[0])
+		if err != nil {
+			ui.Error(err.Error())
+			os.Exit(1)
+		}
+
+		if output == "" {
+			render(ui.ExportSession(os.Stdout, transcriptFormat, detail))
+			return
+		}
+		f, err := os.Create(output)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error creating %s: %v", output, err))
+			os.Exit(1)
+		}
+		err = ui.ExportSession(f, transcriptFormat, detail)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		render(err)
+		fmt.Printf("Wrote %s\n", output)
+	},
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search stored conversations",
+	Long: "Search the content of stored messages and show matching snippets, best match first. " +
+		"Every word must match; words are stemmed, so \"fixing\" also finds \"fixed\". " +
+		"Use --raw for FTS5 query syntax such as OR, NOT, \"exact phrases\" and prefix*.",
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		text := strings.Join(args, " ")
+		query := text
+		if !rawQuery {
+			query = tracker.QuoteSearchTerms(text)
+		}
+		filter := parseSessionFilter("")
+		renderer := newRenderer()
+
+		runSyncAll()
+
+		db := openTracker()
+		defer db.Close()
+
+		results, err := db.Search(context.Background(), query, filter)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error searching messages: %v", err))
+			os.Exit(1)
+		}
+
+		render(renderer.RenderSearch(os.Stdout, &ui.SearchReport{Query: text, Results: results}))
+	},
+}
+
+var repriceCmd = &cobra.Command{
+	Use:   "reprice",
+	Short: "Recompute stored session costs with the current pricing table",
+	Long:  "Recompute the cost of every stored session from its token counts, using the built-in pricing table and any [pricing] overrides in config.toml",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dbPath := cfg.GetDatabasePath()
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			fmt.Println("No database found")
+			return
+		}
+
+		db, err := tracker.NewSQLiteTracker(dbPath)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error opening database: %v", err))
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		db.SetPricing(cfg.GetPricing())
+		updated, err := db.Reprice(context.Background())
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error repricing sessions: %v", err))
+			os.Exit(1)
+		}
+		fmt.Printf("Repriced %d sessions\n", updated)
+	},
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Sync sessions continuously as agents write them",
+	Long: "Sync all sessions once, then watch the agent session directories and sync each changed file " +
+		"as soon as writes to it settle for --debounce. Runs until interrupted with Ctrl-C or SIGTERM.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Stop handling signals last, so a second Ctrl-C still lets the PID file be removed
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		pidFile := getPIDFilePath()
+		if pid, err := readPIDFile(pidFile); err == nil && processRunning(pid) {
+			ui.Error(fmt.Sprintf("watch is already running (pid %d)", pid))
+			os.Exit(1)
+		}
+		if err := writePIDFile(pidFile); err != nil {
+			ui.Error(fmt.Sprintf("Error writing PID file: %v", err))
+			os.Exit(1)
+		}
+		defer os.Remove(pidFile)
+
+		runSyncAll()
+
+		db := openTracker()
+		defer db.Close()
+
+		w, counts := newSessionWatcher(db)
+		defer w.Close()
+
+		fmt.Println("[Watch] Press Ctrl-C to stop")
+		if err := w.Run(ctx); err != nil {
+			ui.Error(fmt.Sprintf("Error watching sessions: %v", err))
+			os.Exit(1)
+		}
+		fmt.Printf("\n[Watch] Stopped: %d new sessions, %d updated, %d failed\n", counts.added, counts.updated, counts.failed)
+	},
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a web dashboard and Prometheus metrics of usage",
+	Long: "Serve a web dashboard with token and cost charts, the per-agent breakdown, models, projects and a session browser, " +
+		"backed by JSON endpoints under /api that return the same documents as --format json, " +
+		"and Prometheus metrics on /metrics. " +
+		"Sessions are synced on start and, unless --watch=false, whenever their files change.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		cal, err := newCalendar()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		runSyncAll()
+
+		db := openTracker()
+		defer db.Close()
+
+		if serveWatch {
+			w, _ := newSessionWatcher(db)
+			defer w.Close()
+			// The dashboard stays up without live updates if watching fails
+			go func() {
+				if err := w.Run(ctx); err != nil {
+					ui.Error(fmt.Sprintf("Error watching sessions: %v", err))
+				}
+			}()
+		}
+
+		fmt.Printf("Serving dashboard on %s (Ctrl-C to stop)\n", dashboardURL(serveAddr))
+		srv := server.New(db, cal)
+		srv.MetricsProjectLabel = metricsProjectLabel
+		if err := srv.Run(ctx, serveAddr); err != nil {
+			ui.Error(err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+// dashboardURL returns the URL to open for a listen address such as :8787
+func dashboardURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// watchCounts counts the session files synced by a watcher
+type watchCounts struct {
+	added, updated, failed int
+}
+
+// newSessionWatcher returns a watcher of the session directories of the
+// enabled agents that logs every file it syncs. It exits if no directory can
+// be watched.
+func newSessionWatcher(db *tracker.SQLiteTracker) (*tracker.Watcher, *watchCounts) {
+	w, err := tracker.NewWatcher(db)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error starting watcher: %v", err))
+		os.Exit(1)
+	}
+	w.Debounce = debounce
+
+	watching := 0
+	for _, p := range enabledProviders() {
+		dir := p.DefaultSessionsDir()
+		if err := w.Add(p, dir); err != nil {
+			fmt.Printf("[Watch] Skipping %s: %v\n", p.DisplayName(), err)
+			continue
+		}
+		fmt.Printf("[Watch] Watching %s sessions in %s\n", p.DisplayName(), dir)
+		watching++
+	}
+	if watching == 0 {
+		w.Close()
+		ui.Error("No agent session directories to watch")
+		os.Exit(1)
+	}
+
+	if n := newNotifier(); n != nil {
+		w.AfterSync = func(ctx context.Context) { sendNotifications(ctx, n, db) }
+	}
+
+	w.OnError = func(err error) {
+		fmt.Printf("[Watch] %s %v; syncing all sessions again\n", time.Now().Format("15:04:05"), err)
+	}
+
+	counts := &watchCounts{}
+	w.OnSync = func(r tracker.WatchResult) {
+		var action string
+		switch {
+		case r.Err == nil:
+			action = "New session"
+			counts.added++
+		case errors.Is(r.Err, tracker.ErrSessionUpdated), errors.Is(r.Err, tracker.ErrSessionBackfilled):
+			action = "Updated"
+			counts.updated++
+		case errors.Is(r.Err, tracker.ErrSessionAlreadyTracked), errors.Is(r.Err, tracker.ErrNoSession):
+			return
+		default:
+			counts.failed++
+			fmt.Printf("[Watch] %s %s: failed to sync %s: %v\n", time.Now().Format("15:04:05"),
+				tracker.DisplayName(string(r.Source)), r.Path, r.Err)
+			return
+		}
+		fmt.Printf("[Watch] %s %s: %s %s\n", time.Now().Format("15:04:05"),
+			tracker.DisplayName(string(r.Source)), action, r.Path)
+	}
+	return w, counts
+}
+
+var budgetCmd = &cobra.Command{
+	Use:   "budget",
+	Short: "Check usage against the configured budgets",
+}
+
+var budgetCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Show budget usage and fail when a budget is exceeded",
+	Long: "Show the usage of each budget configured under [budgets] in the current day, week or month, " +
+		"and exit with status 2 when any budget is exceeded, e.g. to warn in a shell prompt or fail a CI job. " +
+		"Sessions are synced first unless --no-sync is given.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		renderer := newRenderer()
+		if !noSync {
+			runSyncAll()
+		}
+
+		db := openTracker()
+		statuses := checkBudgets(context.Background(), db)
+		db.Close()
+
+		render(renderer.RenderBudgets(os.Stdout, &ui.BudgetsReport{Budgets: statuses}))
+		if tracker.BudgetsExceeded(statuses) {
+			os.Exit(2)
+		}
+	},
+}
+
+// checkBudgets returns the usage of the budgets configured under [budgets],
+// or nil if there are none
+func checkBudgets(ctx context.Context, db *tracker.SQLiteTracker) []tracker.BudgetStatus {
+	budgets, err := cfg.GetBudgets()
+	if err != nil {
+		ui.Error(err.Error())
+		os.Exit(1)
+	}
+	if len(budgets) == 0 {
+		return nil
+	}
+	cal, err := newCalendar()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	statuses, err := db.CheckBudgets(ctx, budgets, time.Now(), cal)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error checking budgets: %v", err))
+		os.Exit(1)
+	}
+	return statuses
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the usage database",
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations",
+	Long: "Apply pending schema migrations to the database. Other commands migrate automatically; " +
+		"use --status to list applied and pending migrations without changing the database.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dbPath := cfg.GetDatabasePath()
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+			ui.Error(fmt.Sprintf("Error creating database directory: %v", err))
+			os.Exit(1)
+		}
+		db, err := tracker.OpenUnmigrated(dbPath)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error opening database: %v", err))
+			os.Exit(1)
+		}
+		defer db.Close()
 
-	switch agentName {
-	case "codex":
-		sessionsDir = tracker.GetDefaultSessionsDir()
-		parseFunc = func(path string) (interface{}, error) {
-			return tracker.ParseCodexSession(path)
+		ctx := context.Background()
+		if status {
+			migrations, err := db.MigrationStatus(ctx)
+			if err != nil {
+				ui.Error(fmt.Sprintf("Error reading migrations: %v", err))
+				os.Exit(1)
+			}
+			current := 0
+			for _, m := range migrations {
+				state := "pending"
+				if m.Applied() {
+					current = m.Version
+					state = "applied " + time.Unix(m.AppliedAt, 0).Format("2006-01-02 15:04:05")
+				}
+				fmt.Printf("  %3d  %-50s %s\n", m.Version, m.Name, state)
+			}
+			fmt.Printf("\nSchema version %d of %d\n", current, tracker.LatestSchemaVersion())
+			return
 		}
-		trackFunc = func(t *tracker.SQLiteTracker, ctx context.Context, sess interface{}) error {
-			return t.TrackSession(ctx, sess.(*tracker.CodexSession))
+
+		applied, err := db.Migrate(ctx)
+		for _, m := range applied {
+			fmt.Printf("Applied %d: %s\n", m.Version, m.Name)
 		}
-	case "claude":
-		sessionsDir = tracker.GetClaudeSessionsDir()
-		parseFunc = func(path string) (interface{}, error) {
-			return tracker.ParseClaudeSession(path)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error migrating database: %v", err))
+			os.Exit(1)
 		}
-		trackFunc = func(t *tracker.SQLiteTracker, ctx context.Context, sess interface{}) error {
-			return t.TrackClaudeSession(ctx, sess.(*tracker.ClaudeSession))
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
 		}
+	},
+}
+
+// parseTimeRange resolves the period argument and the --since and --until flags
+func parseTimeRange(periodName string) tracker.TimeRange {
+	cal, err := newCalendar()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	r, err := tracker.ResolveTimeRange(periodName, since, until, time.Now(), cal)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return r
+}
+
+// newCalendar builds the calendar from the --tz flag or the timezone and
+// week_start config keys, and displays timestamps in that time zone
+func newCalendar() (tracker.Calendar, error) {
+	cal := tracker.DefaultCalendar()
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return cal, fmt.Errorf("invalid timezone %q: %w", tz, err)
+		}
+		cal.Location = loc
+	} else {
+		loc, err := cfg.GetLocation()
+		if err != nil {
+			return cal, err
+		}
+		cal.Location = loc
+	}
+	if cfg.WeekStart != "" {
+		day, err := tracker.ParseWeekday(cfg.WeekStart)
+		if err != nil {
+			return cal, fmt.Errorf("invalid week_start: %w", err)
+		}
+		cal.FirstWeekday = day
+	}
+	ui.SetLocation(cal.Location)
+	return cal, nil
+}
+
+// parseSort validates the --sort flag
+func parseSort() tracker.SortOrder {
+	switch sort := tracker.SortOrder(sortBy); sort {
+	case tracker.SortSessions, tracker.SortCost:
+		return sort
 	default:
-		return
+		fmt.Printf("Invalid sort: %s. Use sessions or cost\n", sortBy)
+		os.Exit(1)
+		return ""
+	}
+}
+
+// parseProjectSort validates the --sort flag of the projects command
+func parseProjectSort() tracker.SortOrder {
+	names := make([]string, len(tracker.ProjectSortOrders))
+	for i, s := range tracker.ProjectSortOrders {
+		if string(s) == sortBy {
+			return s
+		}
+		names[i] = string(s)
+	}
+	fmt.Printf("Invalid sort: %s. Use %s\n", sortBy, strings.Join(names, ", "))
+	os.Exit(1)
+	return ""
+}
+
+// parseProject returns the --project filter, normalized so that it matches the
+// stored project paths
+func parseProject() string {
+	path, err := tracker.NormalizeProject(project)
+	if err != nil {
+		fmt.Printf("Invalid project: %v\n", err)
+		os.Exit(1)
+	}
+	return path
+}
+
+// parseSessionSort validates the --sort flag of the sessions command
+func parseSessionSort() tracker.SortOrder {
+	names := make([]string, len(tracker.SessionSortOrders))
+	for i, s := range tracker.SessionSortOrders {
+		if string(s) == sessionSort {
+			return s
+		}
+		names[i] = string(s)
+	}
+	fmt.Printf("Invalid sort: %s. Use %s\n", sessionSort, strings.Join(names, ", "))
+	os.Exit(1)
+	return ""
+}
+
+// parseSessionFilter builds a session filter from the period argument and the
+// --agent, --project, --model, --since, --until, --limit and --page flags
+func parseSessionFilter(periodName string) tracker.SessionFilter {
+	filter := tracker.SessionFilter{
+		Project: parseProject(),
+		Model:   modelBy,
+		Limit:   limit,
+	}
+
+	if agentBy != "" {
+		provider, ok := tracker.LookupProvider(agentBy)
+		if !ok {
+			fmt.Printf("Invalid agent: %s. Use %s\n", agentBy, strings.Join(tracker.ProviderNames(), " or "))
+			os.Exit(1)
+		}
+		filter.Source = string(provider.Name())
+	}
+
+	if limit < 1 || page < 1 {
+		fmt.Println("--limit and --page must be at least 1")
+		os.Exit(1)
+	}
+	filter.Offset = (page - 1) * limit
+
+	// Unlike stats, the list is unbounded unless a period or bound is given
+	if periodName != "" {
+		r := parseTimeRange(periodName)
+		filter.Since, filter.Until = r.Start.Unix(), r.End.Unix()
+		return filter
 	}
+	cal, err := newCalendar()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for _, bound := range []struct {
+		value string
+		dest  *int64
+	}{{since, &filter.Since}, {until, &filter.Until}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := tracker.ParseTime(bound.value, time.Now(), cal)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		*bound.dest = t.Unix()
+	}
+	return filter
+}
+
+// openTracker opens the database, creating its directory if needed
+func openTracker() *tracker.SQLiteTracker {
+	dbPath := cfg.GetDatabasePath()
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		ui.Error(fmt.Sprintf("Error creating database directory: %v", err))
+		os.Exit(1)
+	}
+	db, err := tracker.NewSQLiteTracker(dbPath)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error opening database: %v", err))
+		os.Exit(1)
+	}
+	db.SetPricing(cfg.GetPricing())
+	return db
+}
+
+// newRenderer returns the renderer for the --format flag
+func newRenderer() ui.Renderer {
+	renderer, err := ui.NewRenderer(format)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if ui.Format(format) != ui.FormatText {
+		syncOut = os.Stderr
+	}
+	return renderer
+}
+
+// render exits with an error if rendering the output failed
+func render(err error) {
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error writing output: %v", err))
+		os.Exit(1)
+	}
+}
+
+// runSync runs the sync for a given agent
+func runSync(provider tracker.AgentProvider) {
+	agentName := string(provider.Name())
 
 	// Get database path
 	dbPath := cfg.GetDatabasePath()
@@ -315,38 +1034,30 @@ func runSync(agentName string) {
 	}
 	defer db.Close()
 
-	// Find all session files recursively
-	var sessionFiles []string
-	filepath.Walk(sessionsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && filepath.Ext(info.Name()) == ".jsonl" {
-			sessionFiles = append(sessionFiles, path)
-		}
-		return nil
-	})
+	db.SetPricing(cfg.GetPricing())
 
-	if len(sessionFiles) == 0 {
+	// Find all session files recursively
+	sessionFiles, err := provider.FindSessions(provider.DefaultSessionsDir())
+	if err != nil || len(sessionFiles) == 0 {
 		return
 	}
 
 	// Show loading indicator
-	fmt.Printf("Syncing %s sessions...\n", agentName)
+	fmt.Fprintf(syncOut, "Syncing %s sessions...\n", agentName)
 
 	// Parse and track each session
 	ctx := context.Background()
 	tracked := 0
 	skipped := 0
 	backfilled := 0
+	updated := 0
 
 	for _, sessionPath := range sessionFiles {
-		session, err := parseFunc(sessionPath)
-		if err != nil {
-			continue
-		}
-
-		if err := trackFunc(db, ctx, session); err != nil {
+		if err := db.SyncFile(ctx, provider, sessionPath); err != nil {
+			if errors.Is(err, tracker.ErrSessionUpdated) {
+				updated++
+				continue
+			}
 			if errors.Is(err, tracker.ErrSessionBackfilled) {
 				backfilled++
 				continue
@@ -363,30 +1074,125 @@ func runSync(agentName string) {
 	}
 
 	if tracked > 0 {
-		fmt.Printf("[Sync] Synced %d new sessions for %s\n", tracked, agentName)
+		fmt.Fprintf(syncOut, "[Sync] Synced %d new sessions for %s\n", tracked, agentName)
+	}
+	if updated > 0 {
+		fmt.Fprintf(syncOut, "[Sync] Updated %d changed sessions for %s\n", updated, agentName)
 	}
 	if backfilled > 0 {
-		fmt.Printf("[Sync] Updated %d existing sessions for %s\n", backfilled, agentName)
+		fmt.Fprintf(syncOut, "[Sync] Backfilled messages for %d sessions for %s\n", backfilled, agentName)
 	}
-	if tracked == 0 && backfilled == 0 {
-		fmt.Printf("[Sync] %s sessions up to date\n", agentName)
+	if tracked == 0 && updated == 0 && backfilled == 0 {
+		fmt.Fprintf(syncOut, "[Sync] %s sessions up to date\n", agentName)
 	}
 
 	// Save last sync time
-	if tracked > 0 || skipped > 0 || backfilled > 0 {
+	if tracked > 0 || skipped > 0 || updated > 0 || backfilled > 0 {
 		ctx := context.Background()
 		db.SetLastSyncTime(ctx, agentName, time.Now().Unix())
 	}
 }
 
-// runSyncAll syncs all enabled agents from config
+// getPIDFilePath returns the path of the PID file written by watch
+func getPIDFilePath() string {
+	return filepath.Join(cfg.GetConfigDir(), "watch.pid")
+}
+
+// writePIDFile records the current process ID so a second watch refuses to start
+func writePIDFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create PID file directory: %w", err)
+	}
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644)
+}
+
+// readPIDFile returns the process ID recorded in a PID file
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse PID file: %w", err)
+	}
+	return pid, nil
+}
+
+// processRunning reports whether a process with the given ID exists.
+// It always reports false on Windows, where stale PID files are overwritten.
+func processRunning(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// runSyncAll syncs all enabled agents from config, then sends the
+// notifications of new events
 func runSyncAll() {
-	if cfg.Agents.Codex {
-		runSync("codex")
+	for _, p := range enabledProviders() {
+		runSync(p)
 	}
-	if cfg.Agents.ClaudeCode {
-		runSync("claude")
+
+	n := newNotifier()
+	if n == nil {
+		return
 	}
+	db, err := tracker.NewSQLiteTracker(cfg.GetDatabasePath())
+	if err != nil {
+		fmt.Fprintf(syncOut, "[Notify] %v\n", err)
+		return
+	}
+	defer db.Close()
+	sendNotifications(context.Background(), n, db)
+}
+
+// newNotifier returns the notifier of the [notify] config section, or nil if
+// no sink is configured or the section is invalid
+func newNotifier() *notify.Notifier {
+	if !cfg.Notify.Enabled() {
+		return nil
+	}
+	budgets, err := cfg.GetBudgets()
+	if err != nil {
+		fmt.Fprintf(syncOut, "[Notify] %v\n", err)
+		return nil
+	}
+	cal, err := newCalendar()
+	if err != nil {
+		fmt.Fprintf(syncOut, "[Notify] %v\n", err)
+		return nil
+	}
+	n, err := notify.New(cfg.Notify, budgets, cal)
+	if err != nil {
+		fmt.Fprintf(syncOut, "[Notify] %v\n", err)
+		return nil
+	}
+	return n
+}
+
+// sendNotifications sends the events since the last check and reports them
+func sendNotifications(ctx context.Context, n *notify.Notifier, db *tracker.SQLiteTracker) {
+	sent, err := n.Check(ctx, db)
+	for _, e := range sent {
+		fmt.Fprintf(syncOut, "[Notify] %s\n", e.Message)
+	}
+	if err != nil {
+		fmt.Fprintf(syncOut, "[Notify] Failed to send notifications: %v\n", err)
+	}
+}
+
+// enabledProviders returns the registered providers enabled in config
+func enabledProviders() []tracker.AgentProvider {
+	var enabled []tracker.AgentProvider
+	for _, p := range tracker.Providers() {
+		if cfg.IsAgentEnabled(string(p.Name())) {
+			enabled = append(enabled, p)
+		}
+	}
+	return enabled
 }
 
 func Execute() {
@@ -397,8 +1203,63 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgPath, "config", "c", "", "Path to config file (default: ~/.agent-usage/config.toml)")
+	rootCmd.PersistentFlags().StringVar(&tz, "tz", "", "IANA time zone for periods and daily/weekly buckets (default: timezone config or local)")
 	usageCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Show debug output (SQL queries, raw data, time filters)")
+	usageCmd.Flags().StringVar(&sortBy, "sort", string(tracker.SortSessions), "Rank top models and projects by sessions or cost")
+	statsCmd.Flags().StringVar(&sortBy, "sort", string(tracker.SortSessions), "Rank top models and projects by sessions or cost")
+	projectsCmd.Flags().StringVar(&sortBy, "sort", string(tracker.SortSessions), "Sort projects by sessions, cost, tokens, time or recent")
+	sessionsCmd.Flags().StringVar(&sessionSort, "sort", string(tracker.SortRecent), "Sort sessions by recent, oldest, cost, tokens or time")
+	sessionsCmd.Flags().StringVar(&agentBy, "agent", "", "Only list sessions of this agent")
+	sessionsCmd.Flags().StringVar(&modelBy, "model", "", "Only list sessions of this model (exact name or glob)")
+	sessionsCmd.Flags().IntVar(&limit, "limit", 20, "Sessions per page")
+	sessionsCmd.Flags().IntVar(&page, "page", 1, "Page to show")
+	searchCmd.Flags().StringVar(&agentBy, "agent", "", "Only search sessions of this agent")
+	searchCmd.Flags().IntVar(&limit, "limit", 20, "Maximum number of results")
+	searchCmd.Flags().IntVar(&page, "page", 1, "Page of results to show")
+	searchCmd.Flags().BoolVar(&rawQuery, "raw", false, "Pass the query to SQLite FTS5 unchanged")
+	usageTurnsCmd.Flags().StringVar(&agentBy, "agent", "", "Only include sessions of this agent")
+	usageTurnsCmd.Flags().StringVar(&modelBy, "model", "", "Only include sessions of this model (exact name or glob)")
+	usageTurnsCmd.Flags().IntVar(&topN, "limit", 10, "Number of slowest turns and fastest growing sessions to show")
+	toolsCmd.Flags().StringVar(&agentBy, "agent", "", "Only include sessions of this agent")
+	toolsCmd.Flags().StringVar(&modelBy, "model", "", "Only include sessions of this model (exact name or glob)")
+	toolsCmd.Flags().IntVar(&toolLimit, "limit", 20, "Number of entries to show in each section")
+	for _, c := range []*cobra.Command{usageCmd, statsCmd, projectsCmd, sessionsCmd, searchCmd, usageTurnsCmd, toolsCmd} {
+		c.Flags().StringVar(&project, "project", "", "Only include projects matching a path (and its subdirectories) or a glob")
+		c.Flags().StringVar(&since, "since", "", "Start of the range (RFC3339, YYYY-MM-DD or relative like 3d)")
+		c.Flags().StringVar(&until, "until", "", "End of the range (RFC3339, YYYY-MM-DD including that day, or relative like 3d)")
+	}
+	usageCmd.PersistentFlags().StringVarP(&format, "format", "f", string(ui.FormatText), "Output format: text, json, csv, tsv or markdown")
+	statsCmd.PersistentFlags().StringVarP(&format, "format", "f", string(ui.FormatText), "Output format: text, json, csv, tsv or markdown")
+	projectsCmd.PersistentFlags().StringVarP(&format, "format", "f", string(ui.FormatText), "Output format: text, json, csv, tsv or markdown")
+	sessionsCmd.PersistentFlags().StringVarP(&format, "format", "f", string(ui.FormatText), "Output format: text, json, csv, tsv or markdown")
+	sessionCmd.PersistentFlags().StringVarP(&format, "format", "f", string(ui.FormatText), "Output format: text, json, csv, tsv or markdown")
+	searchCmd.PersistentFlags().StringVarP(&format, "format", "f", string(ui.FormatText), "Output format: text, json, csv, tsv or markdown")
+	toolsCmd.PersistentFlags().StringVarP(&format, "format", "f", string(ui.FormatText), "Output format: text, json, csv, tsv or markdown")
+	budgetCheckCmd.Flags().StringVarP(&format, "format", "f", string(ui.FormatText), "Output format: text, json, csv, tsv or markdown")
+	budgetCheckCmd.Flags().BoolVar(&noSync, "no-sync", false, "Check the stored sessions without syncing first")
+	exportSessionCmd.Flags().StringVarP(&exportFormat, "format", "f", string(ui.ExportMarkdown), "Transcript format: md, html or json")
+	exportSessionCmd.Flags().StringVarP(&output, "output", "o", "", "Write the transcript to this file instead of stdout")
 	rootCmd.AddCommand(infoCmd)
+	usageCmd.AddCommand(usageTurnsCmd)
 	rootCmd.AddCommand(usageCmd)
 	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(projectsCmd)
+	rootCmd.AddCommand(sessionsCmd)
+	rootCmd.AddCommand(sessionCmd)
+	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(toolsCmd)
+	rootCmd.AddCommand(exportSessionCmd)
+	rootCmd.AddCommand(repriceCmd)
+	watchCmd.Flags().DurationVar(&debounce, "debounce", 2*time.Second, "How long writes to a session file must settle before it is synced")
+	rootCmd.AddCommand(watchCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8787", "Address to listen on; use :8787 to accept connections from other machines")
+	serveCmd.Flags().BoolVar(&serveWatch, "watch", true, "Sync session files as they change")
+	serveCmd.Flags().BoolVar(&metricsProjectLabel, "metrics-project-label", false, "Split /metrics by a project label")
+	serveCmd.Flags().DurationVar(&debounce, "debounce", 2*time.Second, "How long writes to a session file must settle before it is synced")
+	rootCmd.AddCommand(serveCmd)
+	budgetCmd.AddCommand(budgetCheckCmd)
+	rootCmd.AddCommand(budgetCmd)
+	dbMigrateCmd.Flags().BoolVar(&status, "status", false, "List applied and pending migrations without applying them")
+	dbCmd.AddCommand(dbMigrateCmd)
+	rootCmd.AddCommand(dbCmd)
 }
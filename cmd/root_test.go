@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"testing"
 )
@@ -63,3 +64,28 @@ func TestGetPIDFilePath(t *testing.T) {
 		t.Error("PID file should be named watch.pid")
 	}
 }
+
+func TestWriteReadPIDFile(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "nested", "watch.pid")
+
+	if err := writePIDFile(pidFile); err != nil {
+		t.Fatalf("writePIDFile() error = %v", err)
+	}
+	pid, err := readPIDFile(pidFile)
+	if err != nil {
+		t.Fatalf("readPIDFile() error = %v", err)
+	}
+	if pid != os.Getpid() {
+		t.Errorf("readPIDFile() = %d, want %d", pid, os.Getpid())
+	}
+	if runtime.GOOS != "windows" && !processRunning(pid) {
+		t.Error("processRunning() = false for the current process")
+	}
+
+	if err := os.WriteFile(pidFile, []byte("not a pid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPIDFile(pidFile); err == nil {
+		t.Error("readPIDFile() accepted an invalid PID")
+	}
+}
@@ -4,29 +4,29 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/ari/agent-usage/internal/notify"
+	"github.com/ari/agent-usage/internal/pricing"
+	"github.com/ari/agent-usage/internal/tracker"
 	"github.com/spf13/viper"
 )
 
 // Config represents the application configuration
 type Config struct {
-	Agents   AgentsConfig `mapstructure:"agents"`
-	Database string       `mapstructure:"database"`
-}
-
-// AgentsConfig contains the enabled agents
-type AgentsConfig struct {
-	Codex      bool `mapstructure:"codex"`
-	ClaudeCode bool `mapstructure:"claude"`
+	Agents    map[string]bool             `mapstructure:"agents"`
+	Database  string                      `mapstructure:"database"`
+	Timezone  string                      `mapstructure:"timezone"`
+	WeekStart string                      `mapstructure:"week_start"`
+	Pricing   map[string]pricing.Override `mapstructure:"pricing"`
+	Budgets   tracker.BudgetConfig        `mapstructure:"budgets"`
+	Notify    notify.Config               `mapstructure:"notify"`
 }
 
 // LoadConfig loads configuration from the specified path or default location
 func LoadConfig(configPath string) (*Config, error) {
-	viperInstance := viper.New()
-
-	// Set defaults
-	viperInstance.SetDefault("agents.codex", true)
-	viperInstance.SetDefault("agents.claude", true)
+	// Model names such as "gpt-5.2" contain dots, so keys must not be split on them
+	viperInstance := viper.NewWithOptions(viper.KeyDelimiter("::"))
 
 	// If custom config path provided, use it directly
 	if configPath != "" {
@@ -67,6 +67,38 @@ func LoadConfig(configPath string) (*Config, error) {
 	return &cfg, nil
 }
 
+// IsAgentEnabled reports whether the named agent is enabled.
+// Agents not listed under [agents] are enabled by default.
+func (c *Config) IsAgentEnabled(name string) bool {
+	enabled, ok := c.Agents[name]
+	if !ok {
+		return true
+	}
+	return enabled
+}
+
+// GetPricing returns the built-in pricing table with the configured overrides applied
+func (c *Config) GetPricing() *pricing.Table {
+	return pricing.Default().WithOverrides(c.Pricing)
+}
+
+// GetBudgets returns the caps configured under [budgets]
+func (c *Config) GetBudgets() ([]tracker.Budget, error) {
+	return c.Budgets.Budgets()
+}
+
+// GetLocation returns the configured time zone, defaulting to local time
+func (c *Config) GetLocation() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", c.Timezone, err)
+	}
+	return loc, nil
+}
+
 // GetDatabasePath returns the database path, using default if not specified
 func (c *Config) GetDatabasePath() string {
 	if c.Database != "" {
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	_ "time/tzdata"
 )
 
 func TestLoadConfig_MissingFile(t *testing.T) {
@@ -27,10 +30,189 @@ func TestLoadConfig_MissingFile(t *testing.T) {
 	}
 
 	// Verify defaults
-	if !cfg.Agents.Codex {
+	if !cfg.IsAgentEnabled("codex") {
 		t.Error("Expected default Codex=true")
 	}
-	if !cfg.Agents.ClaudeCode {
+	if !cfg.IsAgentEnabled("claude") {
 		t.Error("Expected default Claude=true")
 	}
 }
+
+func TestLoadConfig_DisabledAgent(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	content := "[agents]\ncodex = false\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.IsAgentEnabled("codex") {
+		t.Error("Expected Codex to be disabled")
+	}
+	// Agents not listed in the config stay enabled
+	if !cfg.IsAgentEnabled("claude") {
+		t.Error("Expected Claude to stay enabled")
+	}
+}
+
+func TestLoadConfig_PricingOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	content := "[pricing.\"gpt-5.2\"]\ninput = 2.0\noutput = 16.0\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	override, ok := cfg.Pricing["gpt-5.2"]
+	if !ok {
+		t.Fatalf("Expected override for gpt-5.2, got %v", cfg.Pricing)
+	}
+	if override.Input == nil || *override.Input != 2.0 {
+		t.Errorf("Expected input 2.0, got %v", override.Input)
+	}
+	if override.CacheRead != nil {
+		t.Errorf("Expected cache_read unset, got %v", *override.CacheRead)
+	}
+
+	rate, _ := cfg.GetPricing().Lookup("openai", "gpt-5.2-codex")
+	if rate.Output != 16.0 {
+		t.Errorf("Expected output 16.0, got %v", rate.Output)
+	}
+	// Unset fields keep the built-in gpt-5 rate
+	if rate.CacheRead != 0.125 {
+		t.Errorf("Expected cache read 0.125, got %v", rate.CacheRead)
+	}
+}
+
+func TestLoadConfig_Timezone(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	content := "timezone = \"America/New_York\"\nweek_start = \"sunday\"\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.WeekStart != "sunday" {
+		t.Errorf("Expected week_start sunday, got %q", cfg.WeekStart)
+	}
+
+	loc, err := cfg.GetLocation()
+	if err != nil {
+		t.Fatalf("GetLocation failed: %v", err)
+	}
+	if loc.String() != "America/New_York" {
+		t.Errorf("Expected America/New_York, got %s", loc)
+	}
+
+	cfg.Timezone = "Mars/Olympus"
+	if _, err := cfg.GetLocation(); err == nil {
+		t.Error("Expected error for unknown timezone")
+	}
+}
+
+func TestLoadConfig_Budgets(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	content := `[budgets]
+daily = 10.0
+monthly_tokens = 50000000
+
+[budgets.agents.claude]
+weekly = 40
+
+[budgets.models."gpt-5*"]
+daily_tokens = 2000000
+
+[budgets.projects.api]
+path = "/Users/Me/src/api"
+monthly = 100
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	budgets, err := cfg.GetBudgets()
+	if err != nil {
+		t.Fatalf("GetBudgets failed: %v", err)
+	}
+
+	var got []string
+	for _, b := range budgets {
+		got = append(got, fmt.Sprintf("%s=%g", b, b.Limit))
+	}
+	want := []string{
+		"daily cost=10",
+		"monthly tokens=5e+07",
+		"weekly cost of agent claude=40",
+		"monthly cost of project api=100",
+		"daily tokens of model gpt-5*=2e+06",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("GetBudgets() = %v; want %v", got, want)
+	}
+	// Paths keep their case although config keys are lowercased
+	if budgets[3].Match != "/Users/Me/src/api" {
+		t.Errorf("project path = %q; want /Users/Me/src/api", budgets[3].Match)
+	}
+
+	cfg.Budgets.Agents["gemini"] = cfg.Budgets.Agents["claude"]
+	if _, err := cfg.GetBudgets(); err == nil {
+		t.Error("Expected error for unknown agent")
+	}
+}
+
+func TestLoadConfig_Notify(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	content := `[notify]
+budget_thresholds = [0.5, 1.0]
+session_cost = 5
+new_models = true
+
+[[notify.webhooks]]
+url = "https://hooks.slack.com/services/T0/B0/X"
+format = "slack"
+
+[[notify.exec]]
+command = ["/usr/local/bin/page-me", "--quiet"]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	n := cfg.Notify
+	if !n.Enabled() || n.Desktop || !n.NewModels || n.SessionCost != 5 {
+		t.Errorf("Notify = %+v", n)
+	}
+	if fmt.Sprint(n.BudgetThresholds) != "[0.5 1]" {
+		t.Errorf("BudgetThresholds = %v; want [0.5 1]", n.BudgetThresholds)
+	}
+	if len(n.Webhooks) != 1 || n.Webhooks[0].URL != "https://hooks.slack.com/services/T0/B0/X" || n.Webhooks[0].Format != "slack" {
+		t.Errorf("Webhooks = %+v", n.Webhooks)
+	}
+	if len(n.Exec) != 1 || strings.Join(n.Exec[0].Command, " ") != "/usr/local/bin/page-me --quiet" {
+		t.Errorf("Exec = %+v", n.Exec)
+	}
+}
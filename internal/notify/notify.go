@@ -0,0 +1,301 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ari/agent-usage/internal/tracker"
+	"github.com/ari/agent-usage/internal/ui"
+)
+
+// DefaultBudgetThresholds are the fractions of a budget that are notified
+// when budget_thresholds is not set
+var DefaultBudgetThresholds = []float64{0.8, 1}
+
+// Config is the [notify] section of the config file
+type Config struct {
+	BudgetThresholds []float64       `mapstructure:"budget_thresholds"` // fractions of each budget, e.g. 0.8 for 80%
+	SessionCost      float64         `mapstructure:"session_cost"`      // notify sessions that cost more, in USD; 0 disables
+	NewModels        bool            `mapstructure:"new_models"`        // notify the first session of each model
+	Desktop          bool            `mapstructure:"desktop"`           // show desktop notifications
+	Webhooks         []WebhookConfig `mapstructure:"webhooks"`
+	Exec             []ExecConfig    `mapstructure:"exec"`
+}
+
+// Enabled reports whether any sink is configured
+func (c Config) Enabled() bool {
+	return c.Desktop || len(c.Webhooks) > 0 || len(c.Exec) > 0
+}
+
+// EventKind names what an event is about
+type EventKind string
+
+const (
+	EventBudget      EventKind = "budget"
+	EventSessionCost EventKind = "session_cost"
+	EventNewModel    EventKind = "new_model"
+)
+
+// Event is a notification about usage
+type Event struct {
+	Kind      EventKind             `json:"kind"`
+	Key       string                `json:"key"` // identifies the event so it is only sent once
+	Message   string                `json:"message"`
+	Time      int64                 `json:"time"`                // Unix time the event was detected
+	Threshold float64               `json:"threshold,omitempty"` // fraction of the budget, or USD for session_cost
+	Budget    *tracker.BudgetStatus `json:"budget,omitempty"`
+	Session   *tracker.SessionRow   `json:"session,omitempty"`
+	Model     *tracker.ModelSeen    `json:"model,omitempty"`
+}
+
+// Sink delivers events
+type Sink interface {
+	Send(ctx context.Context, e Event) error
+}
+
+// Notifier checks usage after a sync and sends each new event to its sinks
+type Notifier struct {
+	config   Config
+	budgets  []tracker.Budget
+	calendar tracker.Calendar
+	sinks    []namedSink
+	now      func() time.Time
+}
+
+// namedSink is a sink with a name that identifies it in the delivery records,
+// e.g. "webhook_0" for the first webhook
+type namedSink struct {
+	Sink
+	name string
+}
+
+// New returns a notifier for the configured sinks that checks the given budgets
+func New(cfg Config, budgets []tracker.Budget, cal tracker.Calendar) (*Notifier, error) {
+	n := &Notifier{config: cfg, budgets: budgets, calendar: cal, now: time.Now}
+	n.config.BudgetThresholds = append([]float64(nil), cfg.BudgetThresholds...)
+	if len(n.config.BudgetThresholds) == 0 {
+		n.config.BudgetThresholds = append(n.config.BudgetThresholds, DefaultBudgetThresholds...)
+	}
+	for _, t := range n.config.BudgetThresholds {
+		if t <= 0 {
+			return nil, fmt.Errorf("invalid budget threshold %g: must be greater than 0", t)
+		}
+	}
+	sort.Float64s(n.config.BudgetThresholds)
+
+	for i, w := range cfg.Webhooks {
+		sink, err := NewWebhookSink(w)
+		if err != nil {
+			return nil, err
+		}
+		n.sinks = append(n.sinks, namedSink{sink, fmt.Sprintf("webhook_%d", i)})
+	}
+	for i, e := range cfg.Exec {
+		sink, err := NewExecSink(e)
+		if err != nil {
+			return nil, err
+		}
+		n.sinks = append(n.sinks, namedSink{sink, fmt.Sprintf("exec_%d", i)})
+	}
+	if cfg.Desktop {
+		sink, err := NewDesktopSink()
+		if err != nil {
+			return nil, err
+		}
+		n.sinks = append(n.sinks, namedSink{sink, "desktop"})
+	}
+	return n, nil
+}
+
+// Check detects the events since the last check and sends those that were
+// not sent before. Delivery is recorded per sink, and events that a sink did
+// not accept are retried on that sink only on the next check. It returns the
+// events sent to at least one sink and any delivery errors.
+func (n *Notifier) Check(ctx context.Context, t *tracker.SQLiteTracker) ([]Event, error) {
+	events, silent, err := n.detect(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range silent {
+		if _, err := t.ClaimNotification(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	var sent []Event
+	var errs []error
+	for _, e := range events {
+		claimed, err := t.ClaimNotification(ctx, e.Key)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		delivered, failed := false, false
+		for _, sink := range n.sinks {
+			// Sinks that accepted the event in an earlier check are skipped
+			key := e.Key + "_" + sink.name
+			claimed, err := t.ClaimNotification(ctx, key)
+			if err != nil {
+				errs = append(errs, err)
+				failed = true
+				continue
+			}
+			if !claimed {
+				continue
+			}
+			if err := sink.Send(ctx, e); err != nil {
+				errs = append(errs, err)
+				failed = true
+				if err := t.ReleaseNotification(ctx, key); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
+			delivered = true
+		}
+		if failed {
+			if err := t.ReleaseNotification(ctx, e.Key); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if delivered {
+			sent = append(sent, e)
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// detect returns every event that holds now, including those already sent,
+// and the keys of events that are to be marked as sent without sending them
+func (n *Notifier) detect(ctx context.Context, t *tracker.SQLiteTracker) ([]Event, []string, error) {
+	now := n.now()
+	since, first, err := t.NotificationsSince(ctx, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var events []Event
+	var silent []string
+	if len(n.budgets) > 0 {
+		statuses, err := t.CheckBudgets(ctx, n.budgets, now, n.calendar)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, s := range statuses {
+			e, lower, ok := n.budgetEvent(s, now)
+			if ok {
+				events = append(events, e)
+			}
+			silent = append(silent, lower...)
+		}
+	}
+
+	if n.config.SessionCost > 0 {
+		sessions, err := t.GetSessionsOverCost(ctx, n.config.SessionCost, since)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range sessions {
+			events = append(events, sessionCostEvent(&sessions[i], n.config.SessionCost, now))
+		}
+	}
+
+	if n.config.NewModels {
+		models, err := t.GetModelsSeen(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range models {
+			e := newModelEvent(&models[i], now)
+			// The models in use when notifications are first checked are not new
+			if first {
+				silent = append(silent, e.Key)
+				continue
+			}
+			events = append(events, e)
+		}
+	}
+	return events, silent, nil
+}
+
+// budgetKey identifies a threshold of a budget in its current period
+func budgetKey(s tracker.BudgetStatus, threshold float64) string {
+	return fmt.Sprintf("budget_%s_%s_%s_%s_%d_%g", s.Scope, s.Name, s.Period, s.Unit, s.Start, threshold)
+}
+
+// budgetEvent returns the event of the highest threshold the budget reached,
+// and the keys of the lower ones, which are not worth sending once a higher
+// one is reached
+func (n *Notifier) budgetEvent(s tracker.BudgetStatus, now time.Time) (Event, []string, bool) {
+	var lower []string
+	reached := -1
+	for i, threshold := range n.config.BudgetThresholds {
+		if s.Fraction < threshold {
+			break
+		}
+		if reached >= 0 {
+			lower = append(lower, budgetKey(s, n.config.BudgetThresholds[reached]))
+		}
+		reached = i
+	}
+	if reached < 0 {
+		return Event{}, nil, false
+	}
+
+	threshold := n.config.BudgetThresholds[reached]
+	return Event{
+		Kind:      EventBudget,
+		Key:       budgetKey(s, threshold),
+		Message:   budgetMessage(s, threshold),
+		Time:      now.Unix(),
+		Threshold: threshold,
+		Budget:    &s,
+	}, lower, true
+}
+
+// budgetMessage describes a reached budget threshold, e.g.
+// "Daily cost budget 80% reached: $8.12 of $10.00"
+func budgetMessage(s tracker.BudgetStatus, threshold float64) string {
+	name := strings.ToUpper(string(s.Period[:1])) + string(s.Period[1:]) + " " + string(s.Unit) + " budget"
+	if s.Scope != tracker.ScopeGlobal {
+		name += " of " + string(s.Scope) + " " + s.Name
+	}
+	format := ui.FormatCost
+	if s.Unit == tracker.UnitTokens {
+		format = func(v float64) string { return ui.FormatTokens(int64(v)) }
+	}
+	return fmt.Sprintf("%s %.0f%% reached: %s of %s", name, threshold*100, format(s.Used), format(s.Limit))
+}
+
+func sessionCostEvent(s *tracker.SessionRow, limit float64, now time.Time) Event {
+	project := s.ProjectPath
+	if project == "" {
+		project = "no project"
+	}
+	return Event{
+		Kind: EventSessionCost,
+		Key:  fmt.Sprintf("session_cost_%s_%g", s.ExternalID, limit),
+		Message: fmt.Sprintf("%s session %s in %s cost %s, over %s", tracker.DisplayName(s.Source), s.ExternalID,
+			project, ui.FormatCost(s.Cost), ui.FormatCost(limit)),
+		Time:      now.Unix(),
+		Threshold: limit,
+		Session:   s,
+	}
+}
+
+func newModelEvent(m *tracker.ModelSeen, now time.Time) Event {
+	return Event{
+		Kind:    EventNewModel,
+		Key:     "model_" + m.Model,
+		Message: fmt.Sprintf("New model seen: %s in %s session %s", m.Model, tracker.DisplayName(m.Source), m.ExternalID),
+		Time:    now.Unix(),
+		Model:   m,
+	}
+}
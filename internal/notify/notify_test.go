@@ -0,0 +1,246 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ari/agent-usage/internal/pricing"
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+// webhookServer records the Slack messages posted to it and fails while fail is set
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	messages []string
+	fail     bool
+}
+
+func newWebhookServer(t *testing.T) *webhookServer {
+	s := &webhookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Text string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.messages = append(s.messages, body.Text)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// setFail makes the server fail or succeed
+func (s *webhookServer) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+// take returns and clears the recorded messages
+func (s *webhookServer) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := s.messages
+	s.messages = nil
+	return messages
+}
+
+// newTestTracker prices test-* models at $1 per million input tokens
+func newTestTracker(t *testing.T) *tracker.SQLiteTracker {
+	tr, err := tracker.NewSQLiteTracker(filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteTracker() error = %v", err)
+	}
+	t.Cleanup(func() { tr.Close() })
+	rate := 1.0
+	tr.SetPricing(pricing.Default().WithOverrides(map[string]pricing.Override{"test-": {Input: &rate}}))
+	return tr
+}
+
+// track stores a session of the given model costing dollars
+func track(t *testing.T, tr *tracker.SQLiteTracker, id, model string, start time.Time, dollars int) {
+	end := start.Add(30 * time.Minute)
+	tokens := dollars * 1_000_000
+	err := tr.Track(context.Background(), &tracker.NormalizedSession{
+		ID:        id,
+		Source:    tracker.AgentCodex,
+		Model:     model,
+		StartedAt: start,
+		EndedAt:   &end,
+		Tokens:    tracker.TokenUsage{Input: tokens, Total: tokens},
+	})
+	if err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+}
+
+func newTestNotifier(t *testing.T, url string) *Notifier {
+	budgets, err := tracker.BudgetConfig{BudgetLimits: tracker.BudgetLimits{Daily: 10}}.Budgets()
+	if err != nil {
+		t.Fatalf("Budgets() error = %v", err)
+	}
+	n, err := New(Config{
+		SessionCost: 4,
+		NewModels:   true,
+		Webhooks:    []WebhookConfig{{URL: url, Format: WebhookSlack}},
+	}, budgets, tracker.Calendar{Location: time.UTC, FirstWeekday: time.Monday})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return n
+}
+
+func TestCheckSendsEventsOnce(t *testing.T) {
+	ctx := context.Background()
+	server := newWebhookServer(t)
+	tr := newTestTracker(t)
+	n := newTestNotifier(t, server.URL)
+	at := func(hour int) time.Time { return time.Date(2026, 3, 5, hour, 0, 0, 0, time.UTC) }
+
+	check := func(hour int, want ...string) {
+		t.Helper()
+		n.now = func() time.Time { return at(hour) }
+		sent, err := n.Check(ctx, tr)
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		messages := server.take()
+		if len(sent) != len(want) || len(messages) != len(want) {
+			t.Fatalf("check at %d:00 sent %q; want %d events", hour, messages, len(want))
+		}
+		for i, w := range want {
+			if !strings.HasPrefix(messages[i], w) {
+				t.Errorf("message %d = %q; want prefix %q", i, messages[i], w)
+			}
+		}
+	}
+
+	// The first check only records what is already there
+	track(t, tr, "old", "test-a", at(8), 1)
+	check(9)
+
+	track(t, tr, "big", "test-b", at(10), 7)
+	check(11,
+		"Daily cost budget 80% reached: $8.00 of $10.00",
+		"Codex session big in no project cost $7.00, over $4.00",
+		"New model seen: test-b in Codex session big")
+	check(12)
+
+	// Only the highest threshold is sent once the budget is exceeded
+	track(t, tr, "more", "test-a", at(12), 3)
+	check(13, "Daily cost budget 100% reached: $11.00 of $10.00")
+}
+
+func TestCheckRetriesFailedDelivery(t *testing.T) {
+	ctx := context.Background()
+	server := newWebhookServer(t)
+	tr := newTestTracker(t)
+	n := newTestNotifier(t, server.URL)
+	n.now = func() time.Time { return time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC) }
+	if _, err := n.Check(ctx, tr); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	track(t, tr, "big", "test-a", time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC), 5)
+	n.now = func() time.Time { return time.Date(2026, 3, 5, 11, 0, 0, 0, time.UTC) }
+	server.setFail(true)
+	if sent, err := n.Check(ctx, tr); err == nil || len(sent) != 0 {
+		t.Fatalf("Check() = %d events, %v; want a delivery error", len(sent), err)
+	}
+
+	server.setFail(false)
+	sent, err := n.Check(ctx, tr)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(sent) != 2 {
+		t.Errorf("Check() after the webhook recovered sent %d events; want 2", len(sent))
+	}
+}
+
+func TestCheckRetriesOnlyFailedSinks(t *testing.T) {
+	ctx := context.Background()
+	good, bad := newWebhookServer(t), newWebhookServer(t)
+	tr := newTestTracker(t)
+	n, err := New(Config{
+		SessionCost: 4,
+		Webhooks:    []WebhookConfig{{URL: good.URL, Format: WebhookSlack}, {URL: bad.URL, Format: WebhookSlack}},
+	}, nil, tracker.Calendar{Location: time.UTC, FirstWeekday: time.Monday})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	n.now = func() time.Time { return time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC) }
+	if _, err := n.Check(ctx, tr); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	track(t, tr, "big", "test-a", time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC), 5)
+	n.now = func() time.Time { return time.Date(2026, 3, 5, 11, 0, 0, 0, time.UTC) }
+	bad.setFail(true)
+	if sent, err := n.Check(ctx, tr); err == nil || len(sent) != 1 {
+		t.Fatalf("Check() = %d events, %v; want 1 event and a delivery error", len(sent), err)
+	}
+	if messages := good.take(); len(messages) != 1 {
+		t.Fatalf("working webhook got %q; want 1 message", messages)
+	}
+
+	// Only the webhook that failed gets the event again
+	bad.setFail(false)
+	if _, err := n.Check(ctx, tr); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if messages := good.take(); len(messages) != 0 {
+		t.Errorf("working webhook got %q again", messages)
+	}
+	if messages := bad.take(); len(messages) != 1 {
+		t.Errorf("recovered webhook got %q; want 1 message", messages)
+	}
+
+	if _, err := n.Check(ctx, tr); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if messages := append(good.take(), bad.take()...); len(messages) != 0 {
+		t.Errorf("delivered event sent again: %q", messages)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []Config{
+		{BudgetThresholds: []float64{0.5, 0}, Exec: []ExecConfig{{Command: []string{"true"}}}},
+		{Webhooks: []WebhookConfig{{URL: "example.com/hook"}}},
+		{Webhooks: []WebhookConfig{{URL: "https://example.com/hook", Format: "teams"}}},
+		{Exec: []ExecConfig{{}}},
+	}
+	for _, c := range tests {
+		if _, err := New(c, nil, tracker.Calendar{Location: time.UTC}); err == nil {
+			t.Errorf("New() of %+v succeeded", c)
+		}
+	}
+}
+
+func TestDesktopCommand(t *testing.T) {
+	command, err := desktopCommand("darwin", `Say "hi" \ bye`)
+	if err != nil {
+		t.Fatalf("desktopCommand() error = %v", err)
+	}
+	want := `display notification "Say \"hi\" \\ bye" with title "Agent Usage"`
+	if command[0] != "osascript" || command[2] != want {
+		t.Errorf("desktopCommand() = %q; want osascript -e %q", command, want)
+	}
+	if _, err := desktopCommand("windows", "hi"); err == nil {
+		t.Error("desktopCommand() on windows succeeded")
+	}
+}
@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// sendTimeout bounds the delivery of one event to one sink
+const sendTimeout = 10 * time.Second
+
+// WebhookFormat is the shape of a webhook request body
+type WebhookFormat string
+
+const (
+	// WebhookJSON posts the event itself
+	WebhookJSON WebhookFormat = "json"
+	// WebhookSlack posts {"text": message} for Slack incoming webhooks
+	WebhookSlack WebhookFormat = "slack"
+	// WebhookDiscord posts {"content": message} for Discord webhooks
+	WebhookDiscord WebhookFormat = "discord"
+)
+
+// WebhookConfig is a [[notify.webhooks]] entry
+type WebhookConfig struct {
+	URL    string        `mapstructure:"url"`
+	Format WebhookFormat `mapstructure:"format"` // json (default), slack or discord
+}
+
+// WebhookSink posts events as JSON to a URL
+type WebhookSink struct {
+	url    string
+	format WebhookFormat
+	client *http.Client
+}
+
+// NewWebhookSink returns a sink posting to the configured URL
+func NewWebhookSink(c WebhookConfig) (*WebhookSink, error) {
+	if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return nil, fmt.Errorf("invalid webhook url %q: must start with http:// or https://", c.URL)
+	}
+	format := c.Format
+	switch format {
+	case "":
+		format = WebhookJSON
+	case WebhookJSON, WebhookSlack, WebhookDiscord:
+	default:
+		return nil, fmt.Errorf("invalid webhook format: %s. Use json, slack or discord", c.Format)
+	}
+	return &WebhookSink{url: c.URL, format: format, client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+// body returns the request body of an event
+func (s *WebhookSink) body(e Event) ([]byte, error) {
+	switch s.format {
+	case WebhookSlack:
+		return json.Marshal(map[string]string{"text": e.Message})
+	case WebhookDiscord:
+		return json.Marshal(map[string]string{"content": e.Message})
+	default:
+		return json.Marshal(e)
+	}
+}
+
+// Send posts the event and fails unless the response status is 2xx
+func (s *WebhookSink) Send(ctx context.Context, e Event) error {
+	body, err := s.body(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// ExecConfig is a [[notify.exec]] entry
+type ExecConfig struct {
+	Command []string `mapstructure:"command"` // program and arguments, run without a shell
+}
+
+// ExecSink runs a command per event with the event as JSON on stdin
+type ExecSink struct {
+	command []string
+}
+
+// NewExecSink returns a sink running the configured command
+func NewExecSink(c ExecConfig) (*ExecSink, error) {
+	if len(c.Command) == 0 || c.Command[0] == "" {
+		return nil, fmt.Errorf("invalid exec hook: command is empty")
+	}
+	return &ExecSink{command: c.Command}, nil
+}
+
+// Send runs the command, passing the kind and message of the event in the
+// AGENT_USAGE_EVENT and AGENT_USAGE_MESSAGE environment variables
+func (s *ExecSink) Send(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), "AGENT_USAGE_EVENT="+string(e.Kind), "AGENT_USAGE_MESSAGE="+e.Message)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("exec hook %s failed: %w: %s", s.command[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// DesktopSink shows events as desktop notifications through a local command
+type DesktopSink struct {
+	goos string
+}
+
+// NewDesktopSink returns a sink using notify-send on Linux and osascript on macOS
+func NewDesktopSink() (*DesktopSink, error) {
+	if _, err := desktopCommand(runtime.GOOS, ""); err != nil {
+		return nil, err
+	}
+	return &DesktopSink{goos: runtime.GOOS}, nil
+}
+
+// appleScriptEscaper escapes a message for an AppleScript string literal
+var appleScriptEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// desktopCommand returns the command that shows message on goos
+func desktopCommand(goos, message string) ([]string, error) {
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{"notify-send", "--app-name=agent-usage", "Agent Usage", message}, nil
+	case "darwin":
+		script := `display notification "` + appleScriptEscaper.Replace(message) + `" with title "Agent Usage"`
+		return []string{"osascript", "-e", script}, nil
+	default:
+		return nil, fmt.Errorf("desktop notifications are not supported on %s; use an exec hook", goos)
+	}
+}
+
+// Send shows the event message
+func (s *DesktopSink) Send(ctx context.Context, e Event) error {
+	command, err := desktopCommand(s.goos, e.Message)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("desktop notification failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package pricing
+
+import "strings"
+
+// Rate holds token prices in USD per million tokens
+type Rate struct {
+	Input      float64
+	Output     float64
+	CacheWrite float64
+	CacheRead  float64
+	Reasoning  float64 // billed on top of Output; 0 when the provider counts reasoning as output
+}
+
+// Usage is the token usage priced by a Rate
+type Usage struct {
+	Input      int64
+	Output     int64
+	CacheWrite int64
+	CacheRead  int64
+	Reasoning  int64
+}
+
+// Breakdown is the cost in USD of a usage split by token type
+type Breakdown struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+	Reasoning  float64 `json:"reasoning"`
+}
+
+// Total returns the sum of the breakdown
+func (b Breakdown) Total() float64 {
+	return b.Input + b.CacheWrite + b.CacheRead + b.Output + b.Reasoning
+}
+
+// Breakdown returns the cost in USD of each token type of the given usage
+func (r Rate) Breakdown(u Usage) Breakdown {
+	return Breakdown{
+		Input:      float64(u.Input) * r.Input / 1_000_000,
+		Output:     float64(u.Output) * r.Output / 1_000_000,
+		CacheWrite: float64(u.CacheWrite) * r.CacheWrite / 1_000_000,
+		CacheRead:  float64(u.CacheRead) * r.CacheRead / 1_000_000,
+		Reasoning:  float64(u.Reasoning) * r.Reasoning / 1_000_000,
+	}
+}
+
+// Cost returns the cost in USD of the given usage
+func (r Rate) Cost(u Usage) float64 {
+	return r.Breakdown(u).Total()
+}
+
+// Override replaces individual fields of a rate, as read from [pricing."model"] in config.toml
+type Override struct {
+	Input      *float64 `mapstructure:"input"`
+	Output     *float64 `mapstructure:"output"`
+	CacheWrite *float64 `mapstructure:"cache_write"`
+	CacheRead  *float64 `mapstructure:"cache_read"`
+	Reasoning  *float64 `mapstructure:"reasoning"`
+}
+
+// apply returns base with the fields set in the override replaced
+func (o Override) apply(base Rate) Rate {
+	if o.Input != nil {
+		base.Input = *o.Input
+	}
+	if o.Output != nil {
+		base.Output = *o.Output
+	}
+	if o.CacheWrite != nil {
+		base.CacheWrite = *o.CacheWrite
+	}
+	if o.CacheRead != nil {
+		base.CacheRead = *o.CacheRead
+	}
+	if o.Reasoning != nil {
+		base.Reasoning = *o.Reasoning
+	}
+	return base
+}
+
+// Table resolves model names to rates by prefix
+type Table struct {
+	models    map[string]Rate // keyed by lowercase model-name prefix
+	overrides map[string]Rate // user overrides, checked before models
+	providers map[string]Rate // fallback rates per provider
+	fallback  Rate
+}
+
+// fallbackRate is used for models and providers missing from the table
+var fallbackRate = Rate{Input: 3.00, Output: 15.00, CacheWrite: 3.75, CacheRead: 0.30}
+
+// Built-in rates in USD per million tokens. Longer prefixes win, so dated
+// snapshots (claude-3-5-sonnet-20241022) and variants (gpt-5-codex) resolve
+// to their family. OpenAI reports reasoning tokens as part of output tokens.
+var builtinModels = map[string]Rate{
+	// Anthropic
+	"claude-opus-4-5":   {Input: 5.00, Output: 25.00, CacheWrite: 6.25, CacheRead: 0.50},
+	"claude-opus-4":     {Input: 15.00, Output: 75.00, CacheWrite: 18.75, CacheRead: 1.50},
+	"claude-3-opus":     {Input: 15.00, Output: 75.00, CacheWrite: 18.75, CacheRead: 1.50},
+	"claude-sonnet-4":   {Input: 3.00, Output: 15.00, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-7-sonnet": {Input: 3.00, Output: 15.00, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-5-sonnet": {Input: 3.00, Output: 15.00, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-haiku-4-5":  {Input: 1.00, Output: 5.00, CacheWrite: 1.25, CacheRead: 0.10},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00, CacheWrite: 1.00, CacheRead: 0.08},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheRead: 0.03},
+
+	// OpenAI
+	"gpt-5":             {Input: 1.25, Output: 10.00, CacheRead: 0.125},
+	"gpt-5-mini":        {Input: 0.25, Output: 2.00, CacheRead: 0.025},
+	"gpt-5-nano":        {Input: 0.05, Output: 0.40, CacheRead: 0.005},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00, CacheRead: 0.50},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60, CacheRead: 0.10},
+	"gpt-4o":            {Input: 2.50, Output: 10.00, CacheRead: 1.25},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60, CacheRead: 0.075},
+	"o1":                {Input: 15.00, Output: 60.00, CacheRead: 7.50},
+	"o3":                {Input: 2.00, Output: 8.00, CacheRead: 0.50},
+	"o3-mini":           {Input: 1.10, Output: 4.40, CacheRead: 0.55},
+	"o4-mini":           {Input: 1.10, Output: 4.40, CacheRead: 0.275},
+	"codex-mini-latest": {Input: 1.50, Output: 6.00, CacheRead: 0.375},
+}
+
+// Built-in fallback rates for models a provider serves that are not listed above
+var builtinProviders = map[string]Rate{
+	"anthropic": {Input: 3.00, Output: 15.00, CacheWrite: 3.75, CacheRead: 0.30},
+	"openai":    {Input: 1.25, Output: 10.00, CacheRead: 0.125},
+}
+
+// Default returns the built-in pricing table
+func Default() *Table {
+	t := &Table{
+		models:    make(map[string]Rate, len(builtinModels)),
+		overrides: make(map[string]Rate),
+		providers: make(map[string]Rate, len(builtinProviders)),
+		fallback:  fallbackRate,
+	}
+	for prefix, rate := range builtinModels {
+		t.models[prefix] = rate
+	}
+	for provider, rate := range builtinProviders {
+		t.providers[provider] = rate
+	}
+	return t
+}
+
+// WithOverrides returns a copy of the table with the given per-model overrides.
+// Override keys are model-name prefixes; fields left unset keep the rate the
+// prefix resolves to without overrides.
+func (t *Table) WithOverrides(overrides map[string]Override) *Table {
+	result := &Table{
+		models:    t.models,
+		overrides: make(map[string]Rate, len(t.overrides)+len(overrides)),
+		providers: t.providers,
+		fallback:  t.fallback,
+	}
+	for prefix, rate := range t.overrides {
+		result.overrides[prefix] = rate
+	}
+	for prefix, o := range overrides {
+		prefix = normalizeModel(prefix)
+		base, _ := t.Lookup("", prefix)
+		result.overrides[prefix] = o.apply(base)
+	}
+	return result
+}
+
+// Lookup returns the rate for a model. Overrides are matched first, then
+// built-in model prefixes, then the provider's fallback rate. The boolean is
+// false when only the global fallback rate matched.
+func (t *Table) Lookup(provider, model string) (Rate, bool) {
+	model = normalizeModel(model)
+	if model != "" {
+		if rate, ok := longestPrefix(t.overrides, model); ok {
+			return rate, true
+		}
+		if rate, ok := longestPrefix(t.models, model); ok {
+			return rate, true
+		}
+	}
+	if rate, ok := t.providers[strings.ToLower(provider)]; ok {
+		return rate, true
+	}
+	return t.fallback, false
+}
+
+// Cost returns the cost in USD of usage on the given provider and model
+func (t *Table) Cost(provider, model string, u Usage) float64 {
+	rate, _ := t.Lookup(provider, model)
+	return rate.Cost(u)
+}
+
+// normalizeModel lowercases a model name and strips a provider path such as "openai/"
+func normalizeModel(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if idx := strings.LastIndex(model, "/"); idx >= 0 {
+		model = model[idx+1:]
+	}
+	return model
+}
+
+func longestPrefix(rates map[string]Rate, model string) (Rate, bool) {
+	var best string
+	var found bool
+	for prefix := range rates {
+		if strings.HasPrefix(model, prefix) && (!found || len(prefix) > len(best)) {
+			best = prefix
+			found = true
+		}
+	}
+	if !found {
+		return Rate{}, false
+	}
+	return rates[best], true
+}
@@ -0,0 +1,70 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestLookupPrefix(t *testing.T) {
+	table := Default()
+
+	tests := []struct {
+		provider string
+		model    string
+		input    float64
+		found    bool
+	}{
+		{"anthropic", "claude-3-5-sonnet-20241022", 3.00, true},
+		{"anthropic", "claude-opus-4-5-20251101", 5.00, true},
+		{"anthropic", "claude-opus-4-1-20250805", 15.00, true},
+		{"openai", "gpt-5-codex", 1.25, true},
+		{"openai", "gpt-5-mini", 0.25, true},
+		{"openai", "openai/GPT-4o-mini", 0.15, true},
+		{"anthropic", "claude-future", 3.00, true},
+		{"", "unknown-model", fallbackRate.Input, false},
+	}
+
+	for _, tt := range tests {
+		rate, found := table.Lookup(tt.provider, tt.model)
+		if rate.Input != tt.input || found != tt.found {
+			t.Errorf("Lookup(%q, %q) = %v, %v; want input %v, %v", tt.provider, tt.model, rate.Input, found, tt.input, tt.found)
+		}
+	}
+}
+
+func TestWithOverrides(t *testing.T) {
+	base := Default()
+	table := base.WithOverrides(map[string]Override{
+		"claude-sonnet-4": {Input: floatPtr(2.50)},
+		"my-local-model":  {Input: floatPtr(0), Output: floatPtr(0)},
+	})
+
+	rate, _ := table.Lookup("anthropic", "claude-sonnet-4-5-20250929")
+	if rate.Input != 2.50 || rate.Output != 15.00 {
+		t.Errorf("Expected overridden input with built-in output, got %+v", rate)
+	}
+
+	rate, found := table.Lookup("", "my-local-model")
+	if !found || rate.Input != 0 || rate.Output != 0 {
+		t.Errorf("Expected free local model, got %+v, %v", rate, found)
+	}
+
+	// The original table is left untouched
+	rate, _ = base.Lookup("anthropic", "claude-sonnet-4-5-20250929")
+	if rate.Input != 3.00 {
+		t.Errorf("Expected base table unchanged, got %+v", rate)
+	}
+}
+
+func TestCost(t *testing.T) {
+	usage := Usage{Input: 1000, Output: 500, CacheWrite: 200, CacheRead: 100}
+	cost := Default().Cost("anthropic", "claude-sonnet-4-20250514", usage)
+
+	// 1000*3 + 200*3.75 + 100*0.30 + 500*15 per million
+	expected := 0.01128
+	if math.Abs(cost-expected) > 1e-9 {
+		t.Errorf("Expected cost %v, got %v", expected, cost)
+	}
+}
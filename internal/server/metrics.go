@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+// label is a Prometheus label name and value
+type label struct {
+	name, value string
+}
+
+// labelEscaper escapes label values for the Prometheus text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetricHeader writes the HELP and TYPE lines of a metric
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample writes one sample of a metric
+func writeSample(w io.Writer, name string, labels []label, value float64) {
+	pairs := make([]string, len(labels))
+	for i, l := range labels {
+		pairs[i] = l.name + `="` + labelEscaper.Replace(l.value) + `"`
+	}
+	fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(pairs, ","), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// withProject appends the project label when metrics are split by project
+func withProject(labels []label, byProject bool, project string) []label {
+	if byProject {
+		return append(labels, label{"project", project})
+	}
+	return labels
+}
+
+// writeMetrics writes the metrics in the Prometheus text exposition format.
+// Usage totals are gauges because repricing and de-duplicating usage can lower
+// them; the sync error count only ever increases.
+func writeMetrics(w io.Writer, m *tracker.Metrics, byProject bool) {
+	writeMetricHeader(w, "agent_usage_tokens", "gauge", "Tokens used by agent, model and token type.")
+	for _, t := range m.Models {
+		for _, typed := range []struct {
+			name  string
+			value int64
+		}{
+			{"input", t.InputTokens},
+			{"output", t.OutputTokens},
+			{"cache_creation", t.CacheCreationTokens},
+			{"cache_read", t.CacheReadTokens},
+			{"reasoning", t.ReasoningTokens},
+		} {
+			labels := withProject([]label{{"agent", t.Source}, {"model", t.Model}, {"type", typed.name}}, byProject, t.ProjectPath)
+			writeSample(w, "agent_usage_tokens", labels, float64(typed.value))
+		}
+	}
+
+	writeMetricHeader(w, "agent_usage_cost_dollars", "gauge", "Estimated cost in US dollars by agent and model.")
+	for _, t := range m.Models {
+		labels := withProject([]label{{"agent", t.Source}, {"model", t.Model}}, byProject, t.ProjectPath)
+		writeSample(w, "agent_usage_cost_dollars", labels, t.Cost)
+	}
+
+	for _, total := range []struct {
+		name, help string
+		value      func(tracker.AgentTotals) int64
+	}{
+		{"agent_usage_sessions", "Sessions by agent.", func(a tracker.AgentTotals) int64 { return a.Sessions }},
+		{"agent_usage_messages", "Messages by agent.", func(a tracker.AgentTotals) int64 { return a.Messages }},
+		{"agent_usage_tool_calls", "Tool calls by agent.", func(a tracker.AgentTotals) int64 { return a.ToolCalls }},
+		{"agent_usage_tool_call_errors", "Failed tool calls by agent.", func(a tracker.AgentTotals) int64 { return a.ToolCallErrors }},
+	} {
+		writeMetricHeader(w, total.name, "gauge", total.help)
+		for _, a := range m.Agents {
+			writeSample(w, total.name, withProject([]label{{"agent", a.Source}}, byProject, a.ProjectPath), float64(total.value(a)))
+		}
+	}
+
+	agents := tracker.ProviderNames()
+	writeMetricHeader(w, "agent_usage_last_sync_timestamp_seconds", "gauge", "Unix time of the last sync of each agent, 0 if never synced.")
+	for _, agent := range agents {
+		writeSample(w, "agent_usage_last_sync_timestamp_seconds", []label{{"agent", agent}}, float64(m.LastSync[agent]))
+	}
+	writeMetricHeader(w, "agent_usage_sync_errors_total", "counter", "Session files that failed to sync by agent.")
+	for _, agent := range agents {
+		writeSample(w, "agent_usage_sync_errors_total", []label{{"agent", agent}}, float64(m.SyncErrors[agent]))
+	}
+}
+
+// handleMetrics serves the all-time totals for Prometheus
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	m, err := s.tracker.GetMetrics(r.Context(), s.MetricsProjectLabel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	writeMetrics(&buf, m, s.MetricsProjectLabel)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,285 @@
+// Package server serves the dashboard web UI and the JSON API behind it
+package server
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ari/agent-usage/internal/tracker"
+	"github.com/ari/agent-usage/internal/ui"
+)
+
+//go:embed static
+var static embed.FS
+
+// defaultSessionLimit is the page size of /api/sessions without a limit parameter
+const defaultSessionLimit = 50
+
+// Server answers dashboard requests from a tracker. The JSON endpoints return
+// the same documents as the CLI's --format json.
+type Server struct {
+	// MetricsProjectLabel splits the metrics of /metrics by project
+	MetricsProjectLabel bool
+
+	tracker  *tracker.SQLiteTracker
+	calendar tracker.Calendar
+	renderer ui.Renderer
+	now      func() time.Time
+}
+
+// New returns a server reading from t, resolving periods in cal
+func New(t *tracker.SQLiteTracker, cal tracker.Calendar) *Server {
+	renderer, _ := ui.NewRenderer(string(ui.FormatJSON))
+	return &Server{tracker: t, calendar: cal, renderer: renderer, now: time.Now}
+}
+
+// Handler returns the HTTP handler of the dashboard and the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/stats", s.handleStats)
+	mux.HandleFunc("GET /api/usage/{agent}", s.handleUsage)
+	mux.HandleFunc("GET /api/projects", s.handleProjects)
+	mux.HandleFunc("GET /api/sessions", s.handleSessions)
+	mux.HandleFunc("GET /api/sessions/{id}", s.handleSession)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	files, _ := fs.Sub(static, "static")
+	mux.Handle("GET /", http.FileServer(http.FS(files)))
+	return mux
+}
+
+// Run serves on addr until ctx is canceled, then waits for open requests to finish
+func (s *Server) Run(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	errs := make(chan error, 1)
+	go func() { errs <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+	return nil
+}
+
+// statusError is an error with the HTTP status it is reported with
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+
+// badRequest wraps err to be reported as 400 Bad Request
+func badRequest(err error) error {
+	return &statusError{status: http.StatusBadRequest, err: err}
+}
+
+// timeRange resolves the period, since and until query parameters like the
+// CLI's period argument and --since/--until flags
+func (s *Server) timeRange(r *http.Request) (tracker.TimeRange, error) {
+	q := r.URL.Query()
+	period, err := tracker.ResolveTimeRange(q.Get("period"), q.Get("since"), q.Get("until"), s.now(), s.calendar)
+	if err != nil {
+		return tracker.TimeRange{}, badRequest(err)
+	}
+	return period, nil
+}
+
+// sortOrder returns the sort query parameter, or def if it is empty. It must be one of allowed.
+func sortOrder(r *http.Request, def tracker.SortOrder, allowed []tracker.SortOrder) (tracker.SortOrder, error) {
+	value := r.URL.Query().Get("sort")
+	if value == "" {
+		return def, nil
+	}
+	for _, s := range allowed {
+		if string(s) == value {
+			return s, nil
+		}
+	}
+	return "", badRequest(fmt.Errorf("invalid sort: %s", value))
+}
+
+// intParam returns a positive integer query parameter, or def if it is empty
+func intParam(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, badRequest(fmt.Errorf("invalid %s: %s", name, value))
+	}
+	return n, nil
+}
+
+// agentParam resolves an agent name to the source stored in sessions, allowing an empty name
+func agentParam(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	p, ok := tracker.LookupProvider(name)
+	if !ok {
+		return "", &statusError{status: http.StatusNotFound, err: fmt.Errorf("unknown agent: %s", name)}
+	}
+	return string(p.Name()), nil
+}
+
+// respond writes the document produced by render, or the error as JSON.
+// The document is buffered so a failed render still gets an error status.
+func respond(w http.ResponseWriter, err error, render func(buf *bytes.Buffer) error) {
+	var buf bytes.Buffer
+	if err == nil {
+		err = render(&buf)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		status := http.StatusInternalServerError
+		var se *statusError
+		switch {
+		case errors.As(err, &se):
+			status = se.status
+		case errors.Is(err, tracker.ErrSessionNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, tracker.ErrAmbiguousSession):
+			status = http.StatusBadRequest
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+// handleStats serves the combined stats of all agents and the per-agent breakdown
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	period, err := s.timeRange(r)
+	if err != nil {
+		respond(w, err, nil)
+		return
+	}
+	sort, err := sortOrder(r, tracker.SortSessions, []tracker.SortOrder{tracker.SortSessions, tracker.SortCost})
+	respond(w, err, func(buf *bytes.Buffer) error {
+		project := r.URL.Query().Get("project")
+		stats, err := s.tracker.GetUsageStatsAll(r.Context(), period, project, sort)
+		if err != nil {
+			return err
+		}
+		perAgent, err := s.tracker.GetPerAgentStats(r.Context(), period, project)
+		if err != nil {
+			return err
+		}
+		return s.renderer.RenderAllStats(buf, period, stats, perAgent)
+	})
+}
+
+// handleUsage serves the stats of a single agent
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	period, err := s.timeRange(r)
+	if err != nil {
+		respond(w, err, nil)
+		return
+	}
+	agent, err := agentParam(r.PathValue("agent"))
+	if err != nil {
+		respond(w, err, nil)
+		return
+	}
+	sort, err := sortOrder(r, tracker.SortSessions, []tracker.SortOrder{tracker.SortSessions, tracker.SortCost})
+	respond(w, err, func(buf *bytes.Buffer) error {
+		stats, err := s.tracker.GetUsageStats(r.Context(), tracker.Agent(agent), period, r.URL.Query().Get("project"), sort)
+		if err != nil {
+			return err
+		}
+		return s.renderer.RenderUsageStats(buf, agent, period, stats)
+	})
+}
+
+// handleProjects serves the per-project report
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	period, err := s.timeRange(r)
+	if err != nil {
+		respond(w, err, nil)
+		return
+	}
+	sort, err := sortOrder(r, tracker.SortSessions, tracker.ProjectSortOrders)
+	respond(w, err, func(buf *bytes.Buffer) error {
+		project := r.URL.Query().Get("project")
+		projects, err := s.tracker.GetProjectStats(r.Context(), "", period, project, sort)
+		if err != nil {
+			return err
+		}
+		return s.renderer.RenderProjects(buf, period, &ui.ProjectsReport{Project: project, SortBy: sort, Projects: projects})
+	})
+}
+
+// handleSessions serves one page of the sessions list. Like the sessions
+// command, it lists all sessions unless a period, since or until is given.
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := tracker.SessionFilter{Project: q.Get("project"), Model: q.Get("model")}
+
+	var err error
+	if filter.Source, err = agentParam(q.Get("agent")); err != nil {
+		respond(w, badRequest(err), nil)
+		return
+	}
+	if q.Get("period") != "" || q.Get("since") != "" || q.Get("until") != "" {
+		period, err := s.timeRange(r)
+		if err != nil {
+			respond(w, err, nil)
+			return
+		}
+		filter.Since, filter.Until = period.Start.Unix(), period.End.Unix()
+	}
+	if filter.Sort, err = sortOrder(r, tracker.SortRecent, tracker.SessionSortOrders); err != nil {
+		respond(w, err, nil)
+		return
+	}
+	if filter.Limit, err = intParam(r, "limit", defaultSessionLimit); err != nil {
+		respond(w, err, nil)
+		return
+	}
+	page, err := intParam(r, "page", 1)
+	filter.Offset = (page - 1) * filter.Limit
+
+	respond(w, err, func(buf *bytes.Buffer) error {
+		sessions, total, err := s.tracker.ListSessions(r.Context(), filter)
+		if err != nil {
+			return err
+		}
+		return s.renderer.RenderSessions(buf, &ui.SessionsReport{
+			Total:    total,
+			Page:     page,
+			Limit:    filter.Limit,
+			SortBy:   filter.Sort,
+			Sessions: sessions,
+		})
+	})
+}
+
+// handleSession serves the detail of one session, selected by its external ID or a unique prefix
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	respond(w, nil, func(buf *bytes.Buffer) error {
+		detail, err := s.tracker.GetSessionDetail(r.Context(), r.PathValue("id"))
+		if err != nil {
+			return err
+		}
+		return s.renderer.RenderSession(buf, detail)
+	})
+}
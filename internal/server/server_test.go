@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+const testRollout = `{"timestamp":"2026-03-02T10:00:00Z","type":"session_meta","payload":{"id":"serve-session-1","cwd":"/src/api","model_provider":"openai"}}
+{"timestamp":"2026-03-02T10:00:00Z","type":"turn_context","payload":{"model":"gpt-5"}}
+{"timestamp":"2026-03-02T10:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Hello"}]}}
+{"timestamp":"2026-03-02T10:00:05Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Hi"}]}}
+{"timestamp":"2026-03-02T10:00:05Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":1000,"output_tokens":200,"total_tokens":1200}}}}
+`
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := tracker.NewSQLiteTracker(filepath.Join(dir, "usage.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteTracker() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	sessionFile := filepath.Join(dir, "rollout.jsonl")
+	if err := os.WriteFile(sessionFile, []byte(testRollout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider, _ := tracker.LookupProvider("codex")
+	if err := db.SyncFile(context.Background(), provider, sessionFile); err != nil {
+		t.Fatalf("SyncFile() error = %v", err)
+	}
+
+	cal := tracker.Calendar{Location: time.UTC, FirstWeekday: time.Monday}
+	s := New(db, cal)
+	s.now = func() time.Time { return time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func get(t *testing.T, h http.Handler, path string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: invalid JSON %q: %v", path, rec.Body.String(), err)
+	}
+	return rec.Code, body
+}
+
+func TestServerAPI(t *testing.T) {
+	h := newTestServer(t).Handler()
+
+	code, body := get(t, h, "/api/stats?period=week")
+	if code != http.StatusOK {
+		t.Fatalf("GET /api/stats = %d %v", code, body)
+	}
+	stats := body["stats"].(map[string]any)
+	if body["schema_version"] != float64(1) || stats["session_count"] != float64(1) || stats["total_tokens"] != float64(1200) {
+		t.Errorf("GET /api/stats = %v; want one session with 1200 tokens", body)
+	}
+	if perAgent := body["per_agent"].([]any); len(perAgent) != 1 {
+		t.Errorf("per_agent = %v; want codex only", perAgent)
+	}
+
+	if code, body = get(t, h, "/api/usage/codex?period=2026-03"); code != http.StatusOK || body["agent"] != "codex" {
+		t.Errorf("GET /api/usage/codex = %d %v", code, body)
+	}
+	if code, body = get(t, h, "/api/projects?period=week&sort=cost"); code != http.StatusOK || len(body["projects"].([]any)) != 1 {
+		t.Errorf("GET /api/projects = %d %v", code, body)
+	}
+
+	// The sessions list covers all sessions unless a period is given
+	if code, body = get(t, h, "/api/sessions?limit=10"); code != http.StatusOK || body["total"] != float64(1) {
+		t.Errorf("GET /api/sessions = %d %v", code, body)
+	}
+	if code, body = get(t, h, "/api/sessions?period=yesterday"); code != http.StatusOK || body["total"] != float64(0) {
+		t.Errorf("GET /api/sessions?period=yesterday = %d %v", code, body)
+	}
+
+	// Sessions are found by a unique prefix of their ID
+	code, body = get(t, h, "/api/sessions/serve-sess")
+	if code != http.StatusOK {
+		t.Fatalf("GET /api/sessions/serve-sess = %d %v", code, body)
+	}
+	if messages := body["messages"].([]any); len(messages) != 2 {
+		t.Errorf("messages = %v; want 2", messages)
+	}
+}
+
+func TestServerAPIErrors(t *testing.T) {
+	h := newTestServer(t).Handler()
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/api/stats?period=fortnight", http.StatusBadRequest},
+		{"/api/stats?sort=tokens", http.StatusBadRequest},
+		{"/api/usage/gemini", http.StatusNotFound},
+		{"/api/sessions?page=0", http.StatusBadRequest},
+		{"/api/sessions?agent=gemini", http.StatusBadRequest},
+		{"/api/sessions/missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		code, body := get(t, h, tt.path)
+		if code != tt.code || body["error"] == "" {
+			t.Errorf("GET %s = %d %v; want %d with an error", tt.path, code, body, tt.code)
+		}
+	}
+}
+
+func TestServerDashboard(t *testing.T) {
+	h := newTestServer(t).Handler()
+
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("GET %s = %d with %d bytes", path, rec.Code, rec.Body.Len())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), `<script src="app.js">`) {
+		t.Errorf("GET / did not serve the dashboard:\n%s", rec.Body.String())
+	}
+}
+
+func TestServerMetrics(t *testing.T) {
+	s := newTestServer(t)
+
+	scrape := func(h http.Handler) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+			t.Fatalf("GET /metrics = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		return rec.Body.String()
+	}
+
+	body := scrape(s.Handler())
+	for _, want := range []string{
+		"# TYPE agent_usage_tokens gauge\n",
+		`agent_usage_tokens{agent="codex",model="gpt-5",type="input"} 1000` + "\n",
+		`agent_usage_tokens{agent="codex",model="gpt-5",type="output"} 200` + "\n",
+		`agent_usage_cost_dollars{agent="codex",model="gpt-5"} `,
+		`agent_usage_sessions{agent="codex"} 1` + "\n",
+		`agent_usage_messages{agent="codex"} 2` + "\n",
+		`agent_usage_tool_calls{agent="codex"} 0` + "\n",
+		`agent_usage_last_sync_timestamp_seconds{agent="claude"} 0` + "\n",
+		`agent_usage_sync_errors_total{agent="codex"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "project=") {
+		t.Errorf("metrics have a project label without MetricsProjectLabel:\n%s", body)
+	}
+
+	s.MetricsProjectLabel = true
+	body = scrape(s.Handler())
+	if want := `agent_usage_sessions{agent="codex",project="/src/api"} 1` + "\n"; !strings.Contains(body, want) {
+		t.Errorf("metrics missing %q:\n%s", want, body)
+	}
+}
+
+func TestWriteSampleEscapesLabels(t *testing.T) {
+	var buf strings.Builder
+	writeSample(&buf, "m", []label{{"project", "/src/\"quoted\"\\dir\n"}}, 1.5)
+	if want := `m{project="/src/\"quoted\"\\dir\n"} 1.5` + "\n"; buf.String() != want {
+		t.Errorf("writeSample() = %q; want %q", buf.String(), want)
+	}
+}
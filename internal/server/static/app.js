@@ -0,0 +1,259 @@
+// Dashboard for the agent-usage JSON API. Every request reads the filters
+// from the form; the page refreshes itself every minute.
+"use strict";
+
+const REFRESH_MS = 60000;
+const SESSIONS_PER_PAGE = 25;
+
+const form = document.getElementById("filters");
+let sessionsPage = 1;
+
+function $(id) {
+  return document.getElementById(id);
+}
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  Object.assign(node, attrs || {});
+  for (const child of children) {
+    node.append(child instanceof Node ? child : String(child ?? ""));
+  }
+  return node;
+}
+
+function formatTokens(n) {
+  if (n >= 1e9) return (n / 1e9).toFixed(1) + "B";
+  if (n >= 1e6) return (n / 1e6).toFixed(1) + "M";
+  if (n >= 1e3) return (n / 1e3).toFixed(1) + "K";
+  return String(n);
+}
+
+function formatCost(c) {
+  return "$" + (c || 0).toFixed(2);
+}
+
+function formatDuration(seconds) {
+  const h = Math.floor(seconds / 3600);
+  const m = Math.floor((seconds % 3600) / 60);
+  return h > 0 ? `${h}h ${m}m` : `${m}m`;
+}
+
+function formatTime(unix) {
+  return unix ? new Date(unix * 1000).toLocaleString() : "-";
+}
+
+function projectName(path) {
+  return path ? path.split("/").pop() : "(no project)";
+}
+
+// query builds the API query string from the filters plus extra parameters
+function query(extra) {
+  const params = new URLSearchParams();
+  for (const [key, value] of new FormData(form)) {
+    if (value) params.set(key, value);
+  }
+  for (const [key, value] of Object.entries(extra || {})) {
+    if (value === undefined) params.delete(key);
+    else params.set(key, value);
+  }
+  return params.toString();
+}
+
+async function api(path) {
+  const res = await fetch(path);
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error || res.statusText);
+  return body;
+}
+
+// table fills a table from column definitions {label, value, num, path}
+function table(node, columns, rows, onClick) {
+  const head = el("tr", {}, ...columns.map((c) => el("th", { className: c.num ? "num" : "" }, c.label)));
+  const body = el("tbody");
+  for (const row of rows) {
+    const tr = el("tr", {}, ...columns.map((c) => {
+      const td = el("td", { className: c.num ? "num" : c.path ? "path" : "" }, c.value(row));
+      if (c.path) td.title = c.title ? c.title(row) : "";
+      return td;
+    }));
+    if (onClick) tr.addEventListener("click", () => onClick(row));
+    body.append(tr);
+  }
+  if (rows.length === 0) {
+    body.append(el("tr", {}, el("td", { colSpan: columns.length, className: "muted" }, "No data")));
+  }
+  node.replaceChildren(el("thead", {}, head), body);
+}
+
+// barChart draws one bar per bucket as SVG
+function barChart(node, buckets, value, format) {
+  if (buckets.length === 0) {
+    node.replaceChildren(el("p", { className: "empty" }, "Daily totals need a period longer than a day"));
+    return;
+  }
+  const ns = "http://www.w3.org/2000/svg";
+  const width = 600, height = 180, top = 16, bottom = 20;
+  const max = Math.max(...buckets.map(value), 1);
+  const step = width / buckets.length;
+  const svg = document.createElementNS(ns, "svg");
+  svg.setAttribute("viewBox", `0 0 ${width} ${height}`);
+  svg.setAttribute("preserveAspectRatio", "none");
+
+  buckets.forEach((b, i) => {
+    const h = ((height - top - bottom) * value(b)) / max;
+    const rect = document.createElementNS(ns, "rect");
+    rect.setAttribute("class", "bar");
+    rect.setAttribute("x", i * step + step * 0.15);
+    rect.setAttribute("y", height - bottom - h);
+    rect.setAttribute("width", step * 0.7);
+    rect.setAttribute("height", h);
+    const title = document.createElementNS(ns, "title");
+    title.textContent = `${b.label}: ${format(value(b))}`;
+    rect.append(title);
+
+    const label = document.createElementNS(ns, "text");
+    label.setAttribute("x", i * step + step / 2);
+    label.setAttribute("y", height - 6);
+    label.setAttribute("text-anchor", "middle");
+    label.textContent = b.label.slice(5);
+
+    const amount = document.createElementNS(ns, "text");
+    amount.setAttribute("x", i * step + step / 2);
+    amount.setAttribute("y", height - bottom - h - 4);
+    amount.setAttribute("text-anchor", "middle");
+    amount.textContent = format(value(b));
+    svg.append(rect, label, amount);
+  });
+  node.replaceChildren(svg);
+}
+
+async function loadStats() {
+  const report = await api("/api/stats?" + query());
+  const s = report.stats;
+
+  const cards = [
+    ["Sessions", s.session_count],
+    ["Session Time", formatDuration(s.total_session_time)],
+    ["Tokens", formatTokens(s.total_tokens)],
+    ["Cost", formatCost(s.total_cost)],
+    ["Messages", s.total_messages],
+    ["Tool Calls", s.total_tool_calls],
+    ["Projects", s.unique_projects],
+  ];
+  $("summary").replaceChildren(...cards.map(([label, value]) =>
+    el("div", { className: "card" }, el("div", { className: "label" }, label), el("div", { className: "value" }, value))));
+
+  const buckets = s.daily_summaries.length > 0
+    ? s.daily_summaries.map((d) => ({ ...d, label: d.date }))
+    : s.weekly_summaries.map((w) => ({ ...w, label: w.week_start }));
+  barChart($("tokens-chart"), buckets, (b) => b.total_tokens, formatTokens);
+  barChart($("cost-chart"), buckets, (b) => b.total_cost, formatCost);
+
+  table($("agents"), [
+    { label: "Agent", value: (a) => a.source },
+    { label: "Sessions", value: (a) => a.session_count, num: true },
+    { label: "Time", value: (a) => formatDuration(a.total_time), num: true },
+    { label: "Tokens", value: (a) => formatTokens(a.total_tokens), num: true },
+    { label: "Messages", value: (a) => a.total_messages, num: true },
+    { label: "Cost", value: (a) => formatCost(a.total_cost), num: true },
+  ], report.per_agent);
+
+  table($("models"), [
+    { label: "Model", value: (m) => m.model || "(unknown)" },
+    { label: "Sessions", value: (m) => m.session_count, num: true },
+    { label: "Tokens", value: (m) => formatTokens(m.total_tokens), num: true },
+    { label: "Cost", value: (m) => formatCost(m.total_cost), num: true },
+  ], s.top_models);
+
+  $("updated").textContent = "Updated " + new Date().toLocaleTimeString();
+}
+
+async function loadProjects() {
+  const report = await api("/api/projects?" + query());
+  table($("projects"), [
+    { label: "Project", value: (p) => projectName(p.project_path), path: true, title: (p) => p.project_path },
+    { label: "Sessions", value: (p) => p.session_count, num: true },
+    { label: "Time", value: (p) => formatDuration(p.total_time), num: true },
+    { label: "Tokens", value: (p) => formatTokens(p.total_tokens), num: true },
+    { label: "Cost", value: (p) => formatCost(p.total_cost), num: true },
+    { label: "Top Model", value: (p) => p.top_model },
+    { label: "Last Active", value: (p) => formatTime(p.last_activity) },
+  ], report.projects);
+}
+
+async function loadSessions() {
+  const report = await api("/api/sessions?" + query({ sort: undefined, page: sessionsPage, limit: SESSIONS_PER_PAGE }));
+  const pages = Math.max(1, Math.ceil(report.total / report.limit));
+  $("sessions-total").textContent = `(${report.total})`;
+  $("page").textContent = `Page ${report.page} of ${pages}`;
+  $("prev").disabled = report.page <= 1;
+  $("next").disabled = report.page >= pages;
+
+  table($("sessions"), [
+    { label: "Started", value: (s) => formatTime(s.started_at) },
+    { label: "Agent", value: (s) => s.source },
+    { label: "Model", value: (s) => s.model },
+    { label: "Project", value: (s) => projectName(s.project_path), path: true, title: (s) => s.project_path },
+    { label: "Tokens", value: (s) => formatTokens(s.total_tokens), num: true },
+    { label: "Messages", value: (s) => s.message_count, num: true },
+    { label: "Cost", value: (s) => formatCost(s.cost), num: true },
+  ], report.sessions, (s) => showSession(s.external_id).catch(showError));
+}
+
+async function showSession(id) {
+  const report = await api("/api/sessions/" + encodeURIComponent(id));
+  const s = report.session;
+  $("session-id").textContent = s.external_id;
+
+  const meta = [
+    ["Agent", s.source],
+    ["Model", s.model],
+    ["Project", s.project_path || "(no project)"],
+    ["Started", formatTime(s.started_at)],
+    ["Ended", formatTime(s.ended_at)],
+    ["Tokens", `${formatTokens(s.total_tokens)} (in ${formatTokens(s.input_tokens)}, out ${formatTokens(s.output_tokens)})`],
+    ["Cost", formatCost(s.cost)],
+    ["Tool Calls", report.tool_calls.length],
+  ];
+  $("session-meta").replaceChildren(...meta.flatMap(([k, v]) => [el("dt", {}, k), el("dd", {}, v)]));
+
+  table($("session-models"), [
+    { label: "Model", value: (m) => m.model },
+    { label: "Tokens", value: (m) => formatTokens(m.total_tokens), num: true },
+    { label: "Cost", value: (m) => formatCost(m.cost), num: true },
+  ], report.models);
+
+  $("session-messages").replaceChildren(...report.messages.map((m) =>
+    el("div", { className: "message" },
+      el("div", { className: "role" }, `${m.role} · ${formatTime(m.timestamp)}`),
+      el("pre", {}, m.content))));
+
+  $("session").hidden = false;
+  $("session").scrollIntoView({ behavior: "smooth" });
+}
+
+function showError(err) {
+  $("error").textContent = err.message;
+  $("error").hidden = false;
+}
+
+async function refresh() {
+  $("error").hidden = true;
+  try {
+    await Promise.all([loadStats(), loadProjects(), loadSessions()]);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+form.addEventListener("submit", (e) => {
+  e.preventDefault();
+  sessionsPage = 1;
+  refresh();
+});
+$("prev").addEventListener("click", () => { sessionsPage--; loadSessions().catch(showError); });
+$("next").addEventListener("click", () => { sessionsPage++; loadSessions().catch(showError); });
+$("close-session").addEventListener("click", () => { $("session").hidden = true; });
+
+refresh();
+setInterval(refresh, REFRESH_MS);
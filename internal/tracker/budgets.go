@@ -0,0 +1,270 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BudgetPeriod is the calendar period a budget cap applies to
+type BudgetPeriod string
+
+const (
+	BudgetDaily   BudgetPeriod = "daily"
+	BudgetWeekly  BudgetPeriod = "weekly"
+	BudgetMonthly BudgetPeriod = "monthly"
+)
+
+// calendarPeriod returns the ParsePeriod name of the period containing now
+func (p BudgetPeriod) calendarPeriod() string {
+	switch p {
+	case BudgetWeekly:
+		return "this-week"
+	case BudgetMonthly:
+		return "this-month"
+	default:
+		return "today"
+	}
+}
+
+// BudgetScope is what a budget counts the usage of
+type BudgetScope string
+
+const (
+	ScopeGlobal  BudgetScope = "global"
+	ScopeAgent   BudgetScope = "agent"
+	ScopeProject BudgetScope = "project"
+	ScopeModel   BudgetScope = "model"
+)
+
+// BudgetUnit is what a budget cap is measured in
+type BudgetUnit string
+
+const (
+	UnitCost   BudgetUnit = "cost"
+	UnitTokens BudgetUnit = "tokens"
+)
+
+// BudgetLimits are the caps of one budget scope in dollars and tokens. Caps
+// that are left out or zero are not checked.
+type BudgetLimits struct {
+	Daily         float64 `mapstructure:"daily"`
+	Weekly        float64 `mapstructure:"weekly"`
+	Monthly       float64 `mapstructure:"monthly"`
+	DailyTokens   int64   `mapstructure:"daily_tokens"`
+	WeeklyTokens  int64   `mapstructure:"weekly_tokens"`
+	MonthlyTokens int64   `mapstructure:"monthly_tokens"`
+}
+
+// ProjectBudget holds the caps of a project. Config keys are lowercased, so
+// the path is given as a value rather than as the table name.
+type ProjectBudget struct {
+	Path         string `mapstructure:"path"` // path or glob, normalized and matched like the --project flag
+	BudgetLimits `mapstructure:",squash"`
+}
+
+// BudgetConfig is the [budgets] section of the config file: global caps, and
+// caps per agent, per model name or glob, and per named project
+type BudgetConfig struct {
+	BudgetLimits `mapstructure:",squash"`
+	Agents       map[string]BudgetLimits  `mapstructure:"agents"`
+	Models       map[string]BudgetLimits  `mapstructure:"models"`
+	Projects     map[string]ProjectBudget `mapstructure:"projects"`
+}
+
+// Budget is a single cap on the usage of a scope in a calendar period
+type Budget struct {
+	Scope  BudgetScope  `json:"scope"`
+	Name   string       `json:"name,omitempty"`  // agent, model pattern or project name; empty for the global scope
+	Match  string       `json:"match,omitempty"` // agent, model pattern or project path the usage is filtered by
+	Period BudgetPeriod `json:"period"`
+	Unit   BudgetUnit   `json:"unit"`
+	Limit  float64      `json:"limit"`
+}
+
+// String describes the budget, e.g. "daily cost of agent claude"
+func (b Budget) String() string {
+	s := string(b.Period) + " " + string(b.Unit)
+	if b.Scope != ScopeGlobal {
+		s += " of " + string(b.Scope) + " " + b.Name
+	}
+	return s
+}
+
+// appendLimits appends a budget for each cap set in limits
+func appendLimits(budgets []Budget, scope BudgetScope, name, match string, limits BudgetLimits) ([]Budget, error) {
+	for _, c := range []struct {
+		period BudgetPeriod
+		unit   BudgetUnit
+		limit  float64
+	}{
+		{BudgetDaily, UnitCost, limits.Daily},
+		{BudgetDaily, UnitTokens, float64(limits.DailyTokens)},
+		{BudgetWeekly, UnitCost, limits.Weekly},
+		{BudgetWeekly, UnitTokens, float64(limits.WeeklyTokens)},
+		{BudgetMonthly, UnitCost, limits.Monthly},
+		{BudgetMonthly, UnitTokens, float64(limits.MonthlyTokens)},
+	} {
+		b := Budget{Scope: scope, Name: name, Match: match, Period: c.period, Unit: c.unit, Limit: c.limit}
+		if c.limit < 0 {
+			return nil, fmt.Errorf("invalid budget: %s is negative", b)
+		}
+		if c.limit > 0 {
+			budgets = append(budgets, b)
+		}
+	}
+	return budgets, nil
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Budgets returns the configured caps: global ones first, then those of each
+// agent, project and model in name order
+func (c BudgetConfig) Budgets() ([]Budget, error) {
+	budgets, err := appendLimits(nil, ScopeGlobal, "", "", c.BudgetLimits)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sortedKeys(c.Agents) {
+		if _, ok := LookupProvider(name); !ok {
+			return nil, fmt.Errorf("invalid budget: unknown agent %s. Use %s", name, strings.Join(ProviderNames(), ", "))
+		}
+		if budgets, err = appendLimits(budgets, ScopeAgent, name, name, c.Agents[name]); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range sortedKeys(c.Projects) {
+		p := c.Projects[name]
+		if p.Path == "" {
+			return nil, fmt.Errorf("invalid budget: project %s has no path", name)
+		}
+		path, err := NormalizeProject(p.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget: project %s: %w", name, err)
+		}
+		if budgets, err = appendLimits(budgets, ScopeProject, name, path, p.BudgetLimits); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range sortedKeys(c.Models) {
+		if budgets, err = appendLimits(budgets, ScopeModel, name, name, c.Models[name]); err != nil {
+			return nil, err
+		}
+	}
+	return budgets, nil
+}
+
+// BudgetStatus is the usage of a budget in its current period
+type BudgetStatus struct {
+	Budget
+	Start    int64   `json:"start"`
+	End      int64   `json:"end"`
+	Used     float64 `json:"used"`     // dollars or tokens, like Limit
+	Fraction float64 `json:"fraction"` // Used / Limit; above 1 once the cap is exceeded
+	Exceeded bool    `json:"exceeded"`
+}
+
+// spend is the cost and tokens of a scope in a period
+type spend struct {
+	tokens int64
+	cost   float64
+}
+
+// GetModelSpend returns the tokens and cost of a model in sessions started in
+// the period. The model is an exact name, or a glob if it contains *, ? or [.
+func (db *DB) GetModelSpend(ctx context.Context, model string, since, until int64) (int64, float64, error) {
+	query := `SELECT COALESCE(SUM(m.total_tokens), 0), COALESCE(SUM(m.cost), 0)
+		FROM session_models m JOIN sessions s ON s.id = m.session_id
+		WHERE s.started_at >= ? AND s.started_at < ?`
+	if strings.ContainsAny(model, "*?[") {
+		query += ` AND m.model GLOB ?`
+	} else {
+		query += ` AND m.model = ?`
+	}
+
+	var tokens int64
+	var cost float64
+	if err := db.db.QueryRowContext(ctx, query, since, until, model).Scan(&tokens, &cost); err != nil {
+		return 0, 0, fmt.Errorf("failed to get model spend: %w", err)
+	}
+	return tokens, cost, nil
+}
+
+// getSpend returns the usage counted by a budget scope in the period
+func (db *DB) getSpend(ctx context.Context, b Budget, since, until int64) (spend, error) {
+	var stats *AggregatedStats
+	var err error
+	switch b.Scope {
+	case ScopeAgent:
+		stats, err = db.GetAggregatedStats(ctx, b.Match, since, until, "")
+	case ScopeProject:
+		stats, err = db.GetAggregatedStatsAll(ctx, since, until, b.Match)
+	case ScopeModel:
+		tokens, cost, err := db.GetModelSpend(ctx, b.Match, since, until)
+		return spend{tokens: tokens, cost: cost}, err
+	default:
+		stats, err = db.GetAggregatedStatsAll(ctx, since, until, "")
+	}
+	if err != nil {
+		return spend{}, err
+	}
+	return spend{tokens: stats.TotalTokens, cost: stats.TotalCost}, nil
+}
+
+// CheckBudgets returns the usage of each budget in the day, week or month
+// containing now
+func (t *SQLiteTracker) CheckBudgets(ctx context.Context, budgets []Budget, now time.Time, cal Calendar) ([]BudgetStatus, error) {
+	type key struct {
+		scope  BudgetScope
+		match  string
+		period BudgetPeriod
+	}
+	spent := make(map[key]spend)
+
+	statuses := make([]BudgetStatus, 0, len(budgets))
+	for _, b := range budgets {
+		r, err := ParsePeriod(b.Period.calendarPeriod(), now, cal)
+		if err != nil {
+			return nil, err
+		}
+		since, until := r.bounds()
+
+		k := key{b.Scope, b.Match, b.Period}
+		s, ok := spent[k]
+		if !ok {
+			if s, err = t.db.getSpend(ctx, b, since, until); err != nil {
+				return nil, err
+			}
+			spent[k] = s
+		}
+
+		status := BudgetStatus{Budget: b, Start: since, End: until, Used: s.cost}
+		if b.Unit == UnitTokens {
+			status.Used = float64(s.tokens)
+		}
+		status.Fraction = status.Used / b.Limit
+		status.Exceeded = status.Used > b.Limit
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// BudgetsExceeded reports whether any budget is over its cap
+func BudgetsExceeded(statuses []BudgetStatus) bool {
+	for _, s := range statuses {
+		if s.Exceeded {
+			return true
+		}
+	}
+	return false
+}
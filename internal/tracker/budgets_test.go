@@ -0,0 +1,114 @@
+package tracker
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckBudgets(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	cal := Calendar{Location: time.UTC, FirstWeekday: time.Monday}
+	now := time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC) // a Thursday
+	at := func(day int) int64 { return time.Date(2026, 3, day, 9, 0, 0, 0, time.UTC).Unix() }
+
+	// Today, earlier this week and earlier this month
+	sessions := []SessionRow{
+		{ExternalID: "today", Source: "claude", Model: "claude-opus-4-5", ProjectPath: "/src/api", StartedAt: at(5), TotalTokens: 1000, Cost: 6},
+		{ExternalID: "week", Source: "codex", Model: "gpt-5", ProjectPath: "/src/web", StartedAt: at(3), TotalTokens: 4000, Cost: 2},
+		{ExternalID: "month", Source: "claude", Model: "claude-opus-4-5", ProjectPath: "/src/api", StartedAt: at(1), TotalTokens: 5000, Cost: 10},
+	}
+	for _, s := range sessions {
+		id, err := tr.db.InsertSession(ctx, &s)
+		if err != nil {
+			t.Fatalf("InsertSession() error = %v", err)
+		}
+		models := []SessionModelRow{{Model: s.Model, TotalTokens: s.TotalTokens, Cost: s.Cost}}
+		if err := tr.db.ReplaceSessionModels(ctx, id, models); err != nil {
+			t.Fatalf("ReplaceSessionModels() error = %v", err)
+		}
+	}
+
+	budgets, err := BudgetConfig{
+		BudgetLimits: BudgetLimits{Daily: 5, WeeklyTokens: 10000},
+		Agents:       map[string]BudgetLimits{"codex": {Monthly: 20}},
+		Projects:     map[string]ProjectBudget{"api": {Path: "/src/api", BudgetLimits: BudgetLimits{Monthly: 15}}},
+		Models:       map[string]BudgetLimits{"claude-*": {WeeklyTokens: 800}},
+	}.Budgets()
+	if err != nil {
+		t.Fatalf("Budgets() error = %v", err)
+	}
+
+	statuses, err := tr.CheckBudgets(ctx, budgets, now, cal)
+	if err != nil {
+		t.Fatalf("CheckBudgets() error = %v", err)
+	}
+
+	want := []struct {
+		budget   string
+		used     float64
+		exceeded bool
+	}{
+		{"daily cost", 6, true},
+		{"weekly tokens", 5000, false},
+		{"monthly cost of agent codex", 2, false},
+		{"monthly cost of project api", 16, true},
+		{"weekly tokens of model claude-*", 1000, true},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("CheckBudgets() = %+v; want %d budgets", statuses, len(want))
+	}
+	for i, w := range want {
+		s := statuses[i]
+		if s.String() != w.budget || s.Used != w.used || s.Exceeded != w.exceeded {
+			t.Errorf("budget %d = %s used %v exceeded %v; want %s used %v exceeded %v",
+				i, s, s.Used, s.Exceeded, w.budget, w.used, w.exceeded)
+		}
+	}
+	if weekStart := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC).Unix(); statuses[1].Start != weekStart {
+		t.Errorf("weekly budget starts at %d; want %d", statuses[1].Start, weekStart)
+	}
+	if statuses[0].Fraction != 1.2 {
+		t.Errorf("daily fraction = %v; want 1.2", statuses[0].Fraction)
+	}
+	if !BudgetsExceeded(statuses) || BudgetsExceeded(statuses[1:3]) {
+		t.Error("BudgetsExceeded() did not match the exceeded budgets")
+	}
+}
+
+func TestBudgetsNormalizeProjectPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	budgets, err := BudgetConfig{Projects: map[string]ProjectBudget{
+		"api": {Path: "~/src/api/", BudgetLimits: BudgetLimits{Daily: 1}},
+		"web": {Path: "~/src/web-*", BudgetLimits: BudgetLimits{Daily: 1}},
+	}}.Budgets()
+	if err != nil {
+		t.Fatalf("Budgets() error = %v", err)
+	}
+	want := []string{filepath.Join(home, "src", "api"), "~/src/web-*"}
+	if len(budgets) != len(want) {
+		t.Fatalf("Budgets() = %+v; want %d budgets", budgets, len(want))
+	}
+	for i, w := range want {
+		if budgets[i].Match != w {
+			t.Errorf("budget %s matches %q; want %q", budgets[i].Name, budgets[i].Match, w)
+		}
+	}
+}
+
+func TestBudgetsInvalid(t *testing.T) {
+	tests := []BudgetConfig{
+		{Agents: map[string]BudgetLimits{"gemini": {Daily: 1}}},
+		{Projects: map[string]ProjectBudget{"api": {BudgetLimits: BudgetLimits{Daily: 1}}}},
+		{BudgetLimits: BudgetLimits{Weekly: -1}},
+	}
+	for _, c := range tests {
+		if _, err := c.Budgets(); err == nil {
+			t.Errorf("Budgets() of %+v succeeded", c)
+		}
+	}
+}
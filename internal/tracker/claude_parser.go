@@ -5,31 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 )
 
-// ClaudeSession represents a parsed Claude session
-type ClaudeSession struct {
-	ID          string
-	ProjectPath string
-	Model       string
-	Provider    string // "anthropic"
-	StartedAt   time.Time
-	EndedAt     *time.Time
-	Tokens      TokenUsage
-	Cost        float64
-	Messages    []ClaudeMessage
-}
-
-// ClaudeMessage represents a message in a Claude session
-type ClaudeMessage struct {
-	Role      string
-	Content   string
-	Timestamp time.Time
-}
-
 // claudeEntry represents a JSONL entry in a Claude session file
 type claudeEntry struct {
 	Type       string          `json:"type"`
@@ -44,16 +23,33 @@ type claudeEntry struct {
 	Input      string          `json:"input"`
 	Role       string          `json:"role"`
 	Content    json.RawMessage `json:"content"`
+	Version    string          `json:"version"`
+	GitBranch  string          `json:"gitBranch"`
+	RequestID  string          `json:"requestId"`
 }
 
 // claudeMessage represents a message in a Claude session
 type claudeMessage struct {
+	ID      string          `json:"id"`
 	Model   string          `json:"model"`
 	Role    string          `json:"role"`
 	Usage   *claudeUsage    `json:"usage"`
 	Content json.RawMessage `json:"content"`
 }
 
+// claudeContentBlock is one block of the content of a Claude message
+type claudeContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	Thinking  string          `json:"thinking"`
+	ID        string          `json:"id"`          // tool_use
+	Name      string          `json:"name"`        // tool_use
+	Input     json.RawMessage `json:"input"`       // tool_use
+	ToolUseID string          `json:"tool_use_id"` // tool_result
+	Content   json.RawMessage `json:"content"`     // tool_result, a string or text blocks
+	IsError   bool            `json:"is_error"`    // tool_result
+}
+
 // claudeUsage represents token usage for a Claude message
 type claudeUsage struct {
 	InputTokens              int `json:"input_tokens"`
@@ -62,166 +58,248 @@ type claudeUsage struct {
 	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 }
 
+// tokenUsage converts the usage into TokenUsage
+func (u *claudeUsage) tokenUsage() TokenUsage {
+	tokens := TokenUsage{
+		Input:         u.InputTokens,
+		Output:        u.OutputTokens,
+		CacheCreation: u.CacheCreationInputTokens,
+		CacheRead:     u.CacheReadInputTokens,
+	}
+	tokens.Total = tokens.Input + tokens.Output + tokens.CacheCreation + tokens.CacheRead
+	return tokens
+}
+
 // claudeSystem represents a system entry in a Claude session
 type claudeSystem struct {
 	Type string `json:"type"`
 }
 
 // ParseClaudeSession parses a Claude session JSONL file
-func ParseClaudeSession(path string) (*ClaudeSession, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+func ParseClaudeSession(path string) (*NormalizedSession, error) {
+	return ParseSessionFile(claudeProvider{}, path)
+}
+
+// claudeParser incrementally parses a Claude Code session file
+type claudeParser struct {
+	baseParser
+	state claudeState
+}
+
+// claudeState is the Claude-specific part of the parser state
+type claudeState struct {
+	// Usage keys already counted; streamed content blocks of one response
+	// repeat the same usage on several lines
+	SeenUsage map[string]bool `json:"seen_usage,omitempty"`
+}
+
+// newClaudeParser creates a Claude parser, resuming from state if given
+func newClaudeParser(state []byte) (*claudeParser, error) {
+	p := &claudeParser{baseParser: newBaseParser(AgentClaudeCode)}
+	p.session.Provider = "anthropic"
+	if err := p.loadState(state, &p.state); err != nil {
+		return nil, err
+	}
+	if p.state.SeenUsage == nil {
+		p.state.SeenUsage = make(map[string]bool)
 	}
+	// Every usage is a turn, so totals can be rebuilt from turns left after de-duplication
+	p.session.TokensFromTurns = true
+	return p, nil
+}
 
-	session := &ClaudeSession{
-		Provider: "anthropic",
-		Messages: make([]ClaudeMessage, 0),
+// claudeUsageKey identifies the response a usage belongs to, or is empty if the entry has no IDs
+func claudeUsageKey(messageID, requestID string) string {
+	if messageID == "" && requestID == "" {
+		return ""
 	}
+	return "claude:" + messageID + ":" + requestID
+}
 
-	lines := splitLines(string(data))
-	var firstTimestamp, lastTimestamp time.Time
+// claimUsage reports whether a usage with the given key has not been counted yet
+func (p *claudeParser) claimUsage(key string) bool {
+	if key == "" {
+		return true
+	}
+	if p.state.SeenUsage[key] {
+		return false
+	}
+	p.state.SeenUsage[key] = true
+	return true
+}
 
-	for _, line := range lines {
-		line = trim(line)
-		if line == "" {
-			continue
-		}
+// ParseLine consumes one line of a Claude session file
+func (p *claudeParser) ParseLine(raw []byte) {
+	line := trim(string(raw))
+	if line == "" {
+		return
+	}
 
-		var entry claudeEntry
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue
-		}
+	var entry claudeEntry
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return
+	}
 
-		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
-		if err != nil {
-			ts, _ = time.Parse(time.RFC3339, entry.Timestamp)
-		}
+	ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if err != nil {
+		ts, _ = time.Parse(time.RFC3339, entry.Timestamp)
+	}
+	p.observe(ts)
 
-		if firstTimestamp.IsZero() {
-			firstTimestamp = ts
-		}
-		if !ts.IsZero() {
-			lastTimestamp = ts
-		}
+	session := p.session
+	entrySessionID := entry.SessionID
+	if entrySessionID == "" {
+		entrySessionID = entry.SessionID2
+	}
+	entryProject := entry.Cwd
+	if entryProject == "" {
+		entryProject = entry.Project
+	}
+	if session.ID == "" && entrySessionID != "" {
+		session.ID = entrySessionID
+	}
+	if session.ProjectPath == "" && entryProject != "" {
+		session.ProjectPath = entryProject
+	}
+	if session.Model == "" && entry.Model != "" {
+		session.Model = entry.Model
+	}
+	if entry.Version != "" {
+		session.Metadata["version"] = entry.Version
+	}
+	if entry.GitBranch != "" {
+		session.Metadata["git_branch"] = entry.GitBranch
+	}
 
-		entrySessionID := entry.SessionID
-		if entrySessionID == "" {
-			entrySessionID = entry.SessionID2
+	var messageContent string
+	messageRole := ""
+	if entry.Message != nil {
+		messageContent = extractClaudeMessageContent(entry.Message.Content)
+		if entry.Message.Role != "" {
+			messageRole = entry.Message.Role
 		}
-		entryProject := entry.Cwd
-		if entryProject == "" {
-			entryProject = entry.Project
+		if entry.Message.Model != "" {
+			session.Model = entry.Message.Model
 		}
-		if session.ID == "" && entrySessionID != "" {
-			session.ID = entrySessionID
+		p.addClaudeToolCalls(ts, entry.Message.Content)
+		key := claudeUsageKey(entry.Message.ID, entry.RequestID)
+		if entry.Message.Usage != nil && p.claimUsage(key) {
+			session.Tokens.Input += entry.Message.Usage.InputTokens
+			session.Tokens.Output += entry.Message.Usage.OutputTokens
+			session.Tokens.CacheCreation += entry.Message.Usage.CacheCreationInputTokens
+			session.Tokens.CacheRead += entry.Message.Usage.CacheReadInputTokens
+			p.addTurn(ts, session.Model, entry.Message.Usage.tokenUsage()).UsageKey = key
 		}
-		if session.ProjectPath == "" && entryProject != "" {
-			session.ProjectPath = entryProject
+	}
+	if entry.Message == nil {
+		if entry.Content != nil {
+			messageContent = extractClaudeMessageContent(entry.Content)
 		}
-		if session.Model == "" && entry.Model != "" {
-			session.Model = entry.Model
+		if entry.Role != "" {
+			messageRole = entry.Role
 		}
+	}
 
-		var messageContent string
-		messageRole := ""
-		if entry.Message != nil {
-			messageContent = extractClaudeMessageContent(entry.Message.Content)
-			if entry.Message.Role != "" {
-				messageRole = entry.Message.Role
-			}
-			if entry.Message.Model != "" {
-				session.Model = entry.Message.Model
-			}
-			if entry.Message.Usage != nil {
-				session.Tokens.Input += entry.Message.Usage.InputTokens
-				session.Tokens.Output += entry.Message.Usage.OutputTokens
-				session.Tokens.CacheCreation += entry.Message.Usage.CacheCreationInputTokens
-				session.Tokens.CacheRead += entry.Message.Usage.CacheReadInputTokens
-			}
+	// Handle different entry types
+	switch entry.Type {
+	case "user":
+		p.observeUser(ts)
+		if entry.Input != "" {
+			session.Messages = append(session.Messages, Message{
+				Role:      "user",
+				Content:   entry.Input,
+				Timestamp: ts,
+			})
+			break
 		}
-		if entry.Message == nil {
-			if entry.Content != nil {
-				messageContent = extractClaudeMessageContent(entry.Content)
-			}
-			if entry.Role != "" {
-				messageRole = entry.Role
+		if messageContent != "" {
+			role := messageRole
+			if role == "" {
+				role = "user"
 			}
+			session.Messages = append(session.Messages, Message{
+				Role:      role,
+				Content:   messageContent,
+				Timestamp: ts,
+			})
 		}
 
-		// Handle different entry types
-		switch entry.Type {
-		case "user":
-			if entry.Input != "" {
-				session.Messages = append(session.Messages, ClaudeMessage{
-					Role:      "user",
-					Content:   entry.Input,
-					Timestamp: ts,
-				})
-				break
-			}
-			if messageContent != "" {
-				role := messageRole
-				if role == "" {
-					role = "user"
-				}
-				session.Messages = append(session.Messages, ClaudeMessage{
-					Role:      role,
-					Content:   messageContent,
-					Timestamp: ts,
-				})
+	case "assistant":
+		if messageContent != "" {
+			role := messageRole
+			if role == "" {
+				role = "assistant"
 			}
+			session.Messages = append(session.Messages, Message{
+				Role:      role,
+				Content:   messageContent,
+				Timestamp: ts,
+			})
+		}
 
-		case "assistant":
-			if messageContent != "" {
-				role := messageRole
-				if role == "" {
-					role = "assistant"
-				}
-				session.Messages = append(session.Messages, ClaudeMessage{
-					Role:      role,
-					Content:   messageContent,
-					Timestamp: ts,
-				})
-			}
+	case "system":
+		// System entries can contain turn_duration and other metadata
+		// For now, we just track them but don't extract additional data
+
+	default:
+		if messageContent != "" && messageRole != "" {
+			session.Messages = append(session.Messages, Message{
+				Role:      messageRole,
+				Content:   messageContent,
+				Timestamp: ts,
+			})
+		}
+	}
+}
 
-		case "system":
-			// System entries can contain turn_duration and other metadata
-			// For now, we just track them but don't extract additional data
-
-		default:
-			if messageContent != "" && messageRole != "" {
-				session.Messages = append(session.Messages, ClaudeMessage{
-					Role:      messageRole,
-					Content:   messageContent,
-					Timestamp: ts,
-				})
+// addClaudeToolCalls records the tool_use blocks of a message as tool calls and
+// pairs tool_result blocks with them by tool_use_id
+func (p *claudeParser) addClaudeToolCalls(ts time.Time, raw json.RawMessage) {
+	var blocks []claudeContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return
+	}
+	for _, block := range blocks {
+		switch block.Type {
+		case "tool_use":
+			if _, seen := p.callIndex[block.ID]; seen && block.ID != "" {
+				continue
+			}
+			p.addToolCall(ToolCall{
+				ToolName:  block.Name,
+				Arguments: string(block.Input),
+				Timestamp: ts,
+				CallID:    block.ID,
+			})
+		case "tool_result":
+			if block.ToolUseID == "" {
+				continue
 			}
+			p.addToolResult(ts, block.ToolUseID, ToolCall{
+				Result:  claudeBlockText(block.Content),
+				IsError: block.IsError,
+			})
 		}
 	}
+}
+
+// Session returns the Claude session parsed so far
+func (p *claudeParser) Session() *NormalizedSession {
+	p.finish()
+	session := p.session
 
-	session.StartedAt = firstTimestamp
-	session.EndedAt = &lastTimestamp
 	// Total = Input + Output + CacheCreation + CacheRead
 	session.Tokens.Total = session.Tokens.Input + session.Tokens.Output + session.Tokens.CacheCreation + session.Tokens.CacheRead
 
-	// Calculate cost using Anthropic pricing
-	// Current pricing (as of 2025): $3/million input, $15/million output
-	// Cache pricing: $3.75/million for cache creation, $0.30/million for cache read
-	session.Cost = calculateClaudeCost(session.Tokens)
+	// Calculate cost using the model's Anthropic pricing
+	session.Price(defaultPricing)
 
-	return session, nil
+	return session
 }
 
-// calculateClaudeCost calculates the cost for a Claude session
-func calculateClaudeCost(tokens TokenUsage) float64 {
-	// Anthropic pricing (approximate, can be updated)
-	inputCost := float64(tokens.Input) * 3.0 / 1_000_000
-	cacheCreationCost := float64(tokens.CacheCreation) * 3.75 / 1_000_000
-	cacheReadCost := float64(tokens.CacheRead) * 0.30 / 1_000_000
-	outputCost := float64(tokens.Output) * 15.0 / 1_000_000
-
-	return inputCost + cacheCreationCost + cacheReadCost + outputCost
+// State returns the state needed to resume parsing
+func (p *claudeParser) State() ([]byte, error) {
+	return p.saveState(&p.state)
 }
 
 func extractClaudeMessageContent(raw json.RawMessage) string {
@@ -234,13 +312,7 @@ func extractClaudeMessageContent(raw json.RawMessage) string {
 		return contentStr
 	}
 
-	var items []struct {
-		Type     string `json:"type"`
-		Text     string `json:"text"`
-		Thinking string `json:"thinking"`
-		Name     string `json:"name"`
-		Content  string `json:"content"`
-	}
+	var items []claudeContentBlock
 	if err := json.Unmarshal(raw, &items); err == nil {
 		parts := make([]string, 0, len(items))
 		for _, item := range items {
@@ -251,7 +323,7 @@ func extractClaudeMessageContent(raw json.RawMessage) string {
 				}
 			case "thinking":
 				if item.Thinking != "" {
-					parts = append(parts, item.Thinking)
+					parts = append(parts, ThinkingStart+item.Thinking+ThinkingEnd)
 				}
 			case "tool_use":
 				if item.Name != "" {
@@ -260,8 +332,8 @@ func extractClaudeMessageContent(raw json.RawMessage) string {
 					parts = append(parts, "[tool_use]")
 				}
 			case "tool_result":
-				if item.Content != "" {
-					parts = append(parts, item.Content)
+				if content := claudeBlockText(item.Content); content != "" {
+					parts = append(parts, content)
 				} else {
 					parts = append(parts, "[tool_result]")
 				}
@@ -269,8 +341,8 @@ func extractClaudeMessageContent(raw json.RawMessage) string {
 				if item.Text != "" {
 					parts = append(parts, item.Text)
 				}
-				if item.Content != "" {
-					parts = append(parts, item.Content)
+				if content := claudeBlockText(item.Content); content != "" {
+					parts = append(parts, content)
 				}
 			}
 		}
@@ -290,6 +362,28 @@ func extractClaudeMessageContent(raw json.RawMessage) string {
 	return ""
 }
 
+// claudeBlockText returns the text of block content given as a string or as a list of text blocks
+func claudeBlockText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var blocks []claudeContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Text != "" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
 // GetClaudeSessionsDir returns the default Claude sessions directory
 func GetClaudeSessionsDir() string {
 	home, _ := os.UserHomeDir()
@@ -298,28 +392,28 @@ func GetClaudeSessionsDir() string {
 
 // FindAllClaudeSessions finds all Claude session files recursively
 func FindAllClaudeSessions(sessionsDir string) ([]string, error) {
-	var matches []string
-
-	err := filepath.Walk(sessionsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !info.IsDir() && filepath.Ext(path) == ".jsonl" {
-			matches = append(matches, path)
-		}
-		return nil
-	})
-
+	matches, err := findJSONLFiles(sessionsDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to walk sessions directory: %w", err)
 	}
+	return matches, nil
+}
 
-	// Sort by modification time, newest first
-	sort.Slice(matches, func(i, j int) bool {
-		iInfo, _ := os.Stat(matches[i])
-		jInfo, _ := os.Stat(matches[j])
-		return iInfo.ModTime().After(jInfo.ModTime())
-	})
+// claudeProvider syncs Claude Code sessions from ~/.claude/projects
+type claudeProvider struct{}
 
-	return matches, nil
+func (claudeProvider) Name() Agent                { return AgentClaudeCode }
+func (claudeProvider) DisplayName() string        { return "Claude" }
+func (claudeProvider) DefaultSessionsDir() string { return GetClaudeSessionsDir() }
+
+func (claudeProvider) FindSessions(dir string) ([]string, error) {
+	return FindAllClaudeSessions(dir)
+}
+
+func (claudeProvider) NewParser(state []byte) (SessionParser, error) {
+	return newClaudeParser(state)
+}
+
+func init() {
+	RegisterProvider(claudeProvider{})
 }
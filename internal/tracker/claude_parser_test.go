@@ -1,9 +1,11 @@
 package tracker
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestParseClaudeSession_Accurate(t *testing.T) {
@@ -77,3 +79,109 @@ func TestParseClaudeSession_Messages(t *testing.T) {
 		t.Errorf("Second message incorrect: %+v", session.Messages[1])
 	}
 }
+
+func TestParseClaudeSession_Turns(t *testing.T) {
+	content := `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-123","version":"2.0.1","gitBranch":"main","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-123","message":{"model":"claude-3","role":"assistant","usage":{"input_tokens":10,"output_tokens":20}}}
+{"type":"assistant","timestamp":"2026-02-26T10:00:09Z","sessionId":"sess-123","message":{"model":"claude-3","role":"assistant","usage":{"input_tokens":30,"output_tokens":40,"cache_read_input_tokens":5}}}`
+
+	tmpFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ParseClaudeSession(tmpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if session.Source != AgentClaudeCode {
+		t.Errorf("Source = %s; want claude", session.Source)
+	}
+	if len(session.Turns) != 2 {
+		t.Fatalf("len(Turns) = %d; want 2", len(session.Turns))
+	}
+	if session.Turns[1].Index != 1 || session.Turns[1].Tokens.Total != 75 {
+		t.Errorf("second turn = %+v; want index 1 with 75 tokens", session.Turns[1])
+	}
+	if session.Metadata["version"] != "2.0.1" || session.Metadata["git_branch"] != "main" {
+		t.Errorf("Metadata = %v; want version and git_branch", session.Metadata)
+	}
+}
+
+func TestExtractClaudeMessageContentThinking(t *testing.T) {
+	raw := json.RawMessage(`[{"type":"thinking","thinking":"Check the tests first"},{"type":"text","text":"Running them now"}]`)
+	want := ThinkingStart + "Check the tests first" + ThinkingEnd + "\nRunning them now"
+	if got := extractClaudeMessageContent(raw); got != want {
+		t.Errorf("extractClaudeMessageContent() = %q; want %q", got, want)
+	}
+}
+
+func TestParseClaudeSession_DuplicateUsage(t *testing.T) {
+	// Streamed content blocks of one response repeat its usage on every line
+	content := `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-dup","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:02Z","sessionId":"sess-dup","requestId":"req_1","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":[{"type":"thinking","thinking":"Hmm"}],"usage":{"input_tokens":100,"output_tokens":50,"cache_read_input_tokens":1000}}}
+{"type":"assistant","timestamp":"2026-02-26T10:00:03Z","sessionId":"sess-dup","requestId":"req_1","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":100,"output_tokens":50,"cache_read_input_tokens":1000}}}
+{"type":"assistant","timestamp":"2026-02-26T10:00:04Z","sessionId":"sess-dup","requestId":"req_1","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":[{"type":"tool_use","id":"tool_1","name":"Read","input":{}}],"usage":{"input_tokens":100,"output_tokens":50,"cache_read_input_tokens":1000}}}
+{"type":"assistant","timestamp":"2026-02-26T10:00:09Z","sessionId":"sess-dup","requestId":"req_2","message":{"id":"msg_2","model":"claude-3","role":"assistant","content":[{"type":"text","text":"Done"}],"usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":1200}}}`
+
+	tmpFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ParseClaudeSession(tmpFile)
+	if err != nil {
+		t.Fatalf("ParseClaudeSession failed: %v", err)
+	}
+
+	want := TokenUsage{Input: 110, Output: 55, CacheRead: 2200, Total: 2365}
+	if session.Tokens != want {
+		t.Errorf("Tokens = %+v; want %+v", session.Tokens, want)
+	}
+	if len(session.Turns) != 2 {
+		t.Fatalf("Expected 2 turns, got %d", len(session.Turns))
+	}
+	if session.Turns[0].UsageKey != "claude:msg_1:req_1" || session.Turns[1].UsageKey != "claude:msg_2:req_2" {
+		t.Errorf("UsageKeys = %q, %q", session.Turns[0].UsageKey, session.Turns[1].UsageKey)
+	}
+	if len(session.Messages) != 5 {
+		t.Errorf("Expected every content block to be kept as a message, got %d messages", len(session.Messages))
+	}
+}
+
+func TestParseClaudeSession_ToolCalls(t *testing.T) {
+	content := `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-tools","message":{"role":"user","content":"Check main.go"}}
+{"type":"assistant","timestamp":"2026-02-26T10:00:02Z","sessionId":"sess-tools","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"/p/main.go"}}]}}
+{"type":"assistant","timestamp":"2026-02-26T10:00:02Z","sessionId":"sess-tools","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":[{"type":"tool_use","id":"toolu_2","name":"Bash","input":{"command":"go vet ./..."}}]}}
+{"type":"user","timestamp":"2026-02-26T10:00:03.5Z","sessionId":"sess-tools","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"package main"}]}}
+{"type":"user","timestamp":"2026-02-26T10:00:09Z","sessionId":"sess-tools","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":[{"type":"text","text":"vet: main.go:3: unused import"}],"is_error":true}]}}`
+
+	tmpFile := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ParseClaudeSession(tmpFile)
+	if err != nil {
+		t.Fatalf("ParseClaudeSession failed: %v", err)
+	}
+	if len(session.ToolCalls) != 2 {
+		t.Fatalf("Expected 2 tool calls, got %d", len(session.ToolCalls))
+	}
+
+	read, bash := session.ToolCalls[0], session.ToolCalls[1]
+	if read.ToolName != "Read" || read.CallID != "toolu_1" || read.Arguments != `{"file_path":"/p/main.go"}` ||
+		read.Result != "package main" || read.IsError || read.Duration != 1500*time.Millisecond {
+		t.Errorf("Read tool call = %+v", read)
+	}
+	if bash.ToolName != "Bash" || bash.Result != "vet: main.go:3: unused import" || !bash.IsError || bash.Duration != 7*time.Second {
+		t.Errorf("Bash tool call = %+v", bash)
+	}
+
+	// Tool results given as text blocks are kept in the message content
+	last := session.Messages[len(session.Messages)-1]
+	if last.Content != "vet: main.go:3: unused import" {
+		t.Errorf("Last message content = %q", last.Content)
+	}
+}
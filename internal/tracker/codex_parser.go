@@ -5,49 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"time"
 )
 
-// CodexSession represents a parsed Codex session
-type CodexSession struct {
-	ID          string
-	ProjectPath string
-	Model       string
-	Provider    string
-	StartedAt   time.Time
-	EndedAt     *time.Time
-	Tokens      TokenUsage
-	Cost        float64
-	Messages    []CodexMessage
-	ToolCalls   []CodexToolCall
-}
-
-// TokenUsage represents token usage for a session
-type TokenUsage struct {
-	Input         int
-	Output        int
-	CacheCreation int
-	CacheRead     int
-	Reasoning     int
-	Total         int
-}
-
-// CodexMessage represents a message in a Codex session
-type CodexMessage struct {
-	Role      string
-	Content   string
-	Timestamp time.Time
-}
-
-// CodexToolCall represents a tool call in a Codex session
-type CodexToolCall struct {
-	ToolName  string
-	Arguments string
-	Result    string
-	Timestamp time.Time
-}
-
 // jsonlEntry represents a generic JSONL entry
 type jsonlEntry struct {
 	Payload    json.RawMessage `json:"payload"`
@@ -65,148 +28,324 @@ type responseMessage struct {
 	} `json:"content"`
 }
 
+// codexToolCall is a response_item recording a tool call or its output
+type codexToolCall struct {
+	Type      string          `json:"type"`
+	Name      string          `json:"name"`
+	CallID    string          `json:"call_id"`
+	Status    string          `json:"status"`
+	Arguments string          `json:"arguments"` // function_call
+	Input     string          `json:"input"`     // custom_tool_call
+	Action    json.RawMessage `json:"action"`    // local_shell_call
+	Output    json.RawMessage `json:"output"`    // function_call_output and custom_tool_call_output
+}
+
+// codexToolOutput is the structured output of a shell or patch tool call
+type codexToolOutput struct {
+	Output   string `json:"output"`
+	Metadata struct {
+		ExitCode        *int     `json:"exit_code"`
+		DurationSeconds *float64 `json:"duration_seconds"`
+	} `json:"metadata"`
+}
+
+// codexOutputHeader matches the header of plain-text command output
+var codexOutputHeader = regexp.MustCompile(`^Exit code: (-?\d+)\nWall time: ([\d.]+) seconds\n(?:.*\n)*?Output:\n`)
+
 // ParseCodexSession parses a Codex session JSONL file
-func ParseCodexSession(path string) (*CodexSession, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
+func ParseCodexSession(path string) (*NormalizedSession, error) {
+	return ParseSessionFile(codexProvider{}, path)
+}
+
+// codexParser incrementally parses a Codex rollout file
+type codexParser struct {
+	baseParser
+	state codexState
+}
+
+// codexState is the Codex-specific part of the parser state
+type codexState struct {
+	// Message sizes used to estimate tokens when no token_count event exists
+	InputChars  int `json:"input_chars"`
+	OutputChars int `json:"output_chars"`
+	// Cumulative usage of the latest token_count event, to count only the
+	// usage added by each event
+	LastTotal TokenUsage `json:"last_total"`
+	// Model of the latest turn_context, which token_count events are counted
+	// toward. Unlike session.Model it is never the originator.
+	Model string `json:"model,omitempty"`
+}
 
-	session := &CodexSession{
-		Messages:  make([]CodexMessage, 0),
-		ToolCalls: make([]CodexToolCall, 0),
+// newCodexParser creates a Codex parser, resuming from state if given
+func newCodexParser(state []byte) (*codexParser, error) {
+	p := &codexParser{baseParser: newBaseParser(AgentCodex)}
+	if err := p.loadState(state, &p.state); err != nil {
+		return nil, err
 	}
+	// State saved before the model was tracked separately
+	if p.state.Model == "" && p.session.Model != p.session.Metadata["originator"] {
+		p.state.Model = p.session.Model
+	}
+	return p, nil
+}
 
-	lines := splitLines(string(data))
-	var firstTimestamp, lastTimestamp time.Time
+// ParseLine consumes one line of a Codex rollout file
+func (p *codexParser) ParseLine(raw []byte) {
+	line := trim(string(raw))
+	if line == "" {
+		return
+	}
 
-	for _, line := range lines {
-		line = trim(line)
-		if line == "" {
-			continue
-		}
+	var entry jsonlEntry
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return
+	}
 
-		var entry jsonlEntry
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue
+	ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if err != nil {
+		ts, _ = time.Parse(time.RFC3339, entry.Timestamp)
+	}
+	p.observe(ts)
+
+	session := p.session
+	switch entry.Type {
+	case "session_meta":
+		// Parse payload directly as a map to extract fields
+		var payload map[string]interface{}
+		if err := json.Unmarshal(entry.Payload, &payload); err == nil {
+			if id, ok := payload["id"].(string); ok {
+				session.ID = id
+			}
+			if cwd, ok := payload["cwd"].(string); ok {
+				session.ProjectPath = cwd
+			}
+			if modelProvider, ok := payload["model_provider"].(string); ok {
+				session.Provider = modelProvider
+			}
+			if originator, ok := payload["originator"].(string); ok {
+				session.Model = originator
+				session.Metadata["originator"] = originator
+			}
+			if cliVersion, ok := payload["cli_version"].(string); ok {
+				session.Metadata["cli_version"] = cliVersion
+			}
 		}
 
-		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
-		if err != nil {
-			ts, _ = time.Parse(time.RFC3339, entry.Timestamp)
+	case "turn_context":
+		// Parse turn_context to get the actual model name (overrides session_meta.originator)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(entry.Payload, &payload); err == nil {
+			if model, ok := payload["model"].(string); ok {
+				session.Model = model
+				p.setModel(model)
+			}
 		}
 
-		if firstTimestamp.IsZero() {
-			firstTimestamp = ts
-		}
-		lastTimestamp = ts
-
-		switch entry.Type {
-		case "session_meta":
-			// Parse payload directly as a map to extract fields
-			var payload map[string]interface{}
-			if err := json.Unmarshal(entry.Payload, &payload); err == nil {
-				if id, ok := payload["id"].(string); ok {
-					session.ID = id
-				}
-				if cwd, ok := payload["cwd"].(string); ok {
-					session.ProjectPath = cwd
-				}
-				if modelProvider, ok := payload["model_provider"].(string); ok {
-					session.Provider = modelProvider
-				}
-				if originator, ok := payload["originator"].(string); ok {
-					session.Model = originator
-				}
-				if ts, ok := payload["timestamp"].(string); ok {
-					startedAt, _ := time.Parse(time.RFC3339Nano, ts)
-					if startedAt.IsZero() {
-						startedAt, _ = time.Parse(time.RFC3339, ts)
+	case "response_item":
+		var msg responseMessage
+		if err := json.Unmarshal(entry.Payload, &msg); err == nil {
+			switch msg.Type {
+			case "function_call", "custom_tool_call", "local_shell_call":
+				p.addCodexToolCall(ts, entry.Payload)
+			case "function_call_output", "custom_tool_call_output":
+				p.addCodexToolOutput(ts, entry.Payload)
+			}
+			if msg.Type == "message" {
+				content := extractMessageContent(msg.Content)
+				if content != "" {
+					session.Messages = append(session.Messages, Message{
+						Role:      msg.Role,
+						Content:   content,
+						Timestamp: ts,
+					})
+					if msg.Role == "user" {
+						p.observeUser(ts)
+					}
+					if msg.Role == "developer" || msg.Role == "user" {
+						p.state.InputChars += len(content)
+					} else {
+						p.state.OutputChars += len(content)
 					}
-					session.StartedAt = startedAt
 				}
 			}
+		}
 
-		case "turn_context":
-			// Parse turn_context to get the actual model name (overrides session_meta.originator)
-			var payload map[string]interface{}
-			if err := json.Unmarshal(entry.Payload, &payload); err == nil {
-				if model, ok := payload["model"].(string); ok {
-					session.Model = model
+	case "event_msg":
+		var event map[string]interface{}
+		if err := json.Unmarshal(entry.Payload, &event); err == nil {
+			// Check for tool_use events
+			if eventType, ok := event["type"].(string); ok && eventType == "tool_use" {
+				toolName, _ := event["name"].(string)
+				args, _ := json.Marshal(event["input"])
+				toolCall := ToolCall{
+					ToolName:  toolName,
+					Arguments: string(args),
+					Timestamp: ts,
 				}
+
+				// Look for result in following entries (simplified - just store the call)
+				session.ToolCalls = append(session.ToolCalls, toolCall)
 			}
 
-		case "response_item":
-			var msg responseMessage
-			if err := json.Unmarshal(entry.Payload, &msg); err == nil {
-				if msg.Type == "message" {
-					content := extractMessageContent(msg.Content)
-					if content != "" {
-						session.Messages = append(session.Messages, CodexMessage{
-							Role:      msg.Role,
-							Content:   content,
-							Timestamp: ts,
-						})
-					}
+			// Check for token_count events
+			if eventType, ok := event["type"].(string); ok && eventType == "token_count" {
+				if info, ok := event["info"].(map[string]interface{}); ok {
+					p.countTokens(ts, info)
 				}
 			}
+		}
+	}
+}
 
-		case "event_msg":
-			var event map[string]interface{}
-			if err := json.Unmarshal(entry.Payload, &event); err == nil {
-				// Check for tool_use events
-				if eventType, ok := event["type"].(string); ok && eventType == "tool_use" {
-					toolName, _ := event["name"].(string)
-					args, _ := json.Marshal(event["input"])
-					toolCall := CodexToolCall{
-						ToolName:  toolName,
-						Arguments: string(args),
-						Timestamp: ts,
-					}
+// addCodexToolCall records a function_call, custom_tool_call or local_shell_call item
+func (p *codexParser) addCodexToolCall(ts time.Time, payload json.RawMessage) {
+	var item codexToolCall
+	if err := json.Unmarshal(payload, &item); err != nil {
+		return
+	}
 
-					// Look for result in following entries (simplified - just store the call)
-					session.ToolCalls = append(session.ToolCalls, toolCall)
-				}
+	call := ToolCall{ToolName: item.Name, Timestamp: ts, CallID: item.CallID}
+	switch item.Type {
+	case "function_call":
+		call.Arguments = item.Arguments
+	case "custom_tool_call":
+		call.Arguments = item.Input
+	case "local_shell_call":
+		call.ToolName = "local_shell"
+		call.Arguments = string(item.Action)
+	}
 
-				// Check for token_count events
-				if eventType, ok := event["type"].(string); ok && eventType == "token_count" {
-					if info, ok := event["info"].(map[string]interface{}); ok {
-						if usage, ok := info["total_token_usage"].(map[string]interface{}); ok {
-							if v, ok := usage["input_tokens"].(float64); ok {
-								session.Tokens.Input = int(v)
-							}
-							if v, ok := usage["cached_input_tokens"].(float64); ok {
-								session.Tokens.CacheCreation = int(v)
-							}
-							if v, ok := usage["output_tokens"].(float64); ok {
-								session.Tokens.Output = int(v)
-							}
-							if v, ok := usage["reasoning_output_tokens"].(float64); ok {
-								session.Tokens.Reasoning = int(v)
-							}
-							if v, ok := usage["total_tokens"].(float64); ok {
-								session.Tokens.Total = int(v)
-							}
-						}
-					}
-				}
+	p.addToolCall(call)
+}
+
+// addCodexToolOutput pairs a function_call_output or custom_tool_call_output item with its call
+func (p *codexParser) addCodexToolOutput(ts time.Time, payload json.RawMessage) {
+	var item codexToolCall
+	if err := json.Unmarshal(payload, &item); err != nil || item.CallID == "" {
+		return
+	}
+	p.addToolResult(ts, item.CallID, codexToolResult(item.Output))
+}
+
+// codexToolResult decodes the output of a tool call. The output is either a
+// JSON string holding the command output with its exit code and duration, plain
+// text that may start with an exit code and wall time header, or an object with
+// the content and a success flag.
+func codexToolResult(raw json.RawMessage) ToolCall {
+	var result ToolCall
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		var obj struct {
+			Content string `json:"content"`
+			Success *bool  `json:"success"`
+		}
+		if err := json.Unmarshal(raw, &obj); err == nil {
+			result.Result = obj.Content
+			result.IsError = obj.Success != nil && !*obj.Success
+		}
+		return result
+	}
+
+	var structured codexToolOutput
+	if err := json.Unmarshal([]byte(text), &structured); err == nil && structured.Metadata.ExitCode != nil {
+		result.Result = structured.Output
+		result.ExitCode = structured.Metadata.ExitCode
+		if structured.Metadata.DurationSeconds != nil {
+			result.Duration = time.Duration(*structured.Metadata.DurationSeconds * float64(time.Second))
+		}
+	} else if m := codexOutputHeader.FindStringSubmatch(text); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		seconds, _ := strconv.ParseFloat(m[2], 64)
+		result.Result = text[len(m[0]):]
+		result.ExitCode = &code
+		result.Duration = time.Duration(seconds * float64(time.Second))
+	} else {
+		result.Result = text
+	}
+	result.IsError = result.ExitCode != nil && *result.ExitCode != 0
+	return result
+}
+
+// countTokens adds the usage reported by a token_count event to the model of the
+// current turn context. The cumulative total_token_usage is authoritative for
+// session totals; last_token_usage is the usage of the turn. Codex repeats the
+// event without new usage, for example after rate limit updates, so events
+// whose total did not change are skipped.
+func (p *codexParser) countTokens(ts time.Time, info map[string]interface{}) {
+	total, hasTotal := info["total_token_usage"].(map[string]interface{})
+	last, hasLast := info["last_token_usage"].(map[string]interface{})
+
+	var added TokenUsage
+	switch {
+	case hasTotal:
+		cumulative := codexTokenUsage(total)
+		if cumulative == p.state.LastTotal {
+			return
+		}
+		added = cumulative.sub(p.state.LastTotal)
+		if cumulative.Total < p.state.LastTotal.Total {
+			// The counter restarted, so everything it reports is new
+			added = cumulative
+		}
+		p.state.LastTotal = cumulative
+	case hasLast:
+		added = codexTokenUsage(last)
+	default:
+		return
+	}
+
+	session := p.session
+	session.Tokens = session.Tokens.add(added)
+	if session.ModelTokens == nil {
+		session.ModelTokens = make(map[string]TokenUsage)
+	}
+	session.ModelTokens[p.state.Model] = session.ModelTokens[p.state.Model].add(added)
+
+	turn := added
+	if hasLast {
+		turn = codexTokenUsage(last)
+	}
+	p.addTurn(ts, p.state.Model, turn)
+}
+
+// setModel switches the model that usage is counted toward. Usage reported
+// before the first turn_context has no model yet and is moved to the first one;
+// turns stored by an earlier sync keep an empty model.
+func (p *codexParser) setModel(model string) {
+	if p.state.Model == "" && model != "" {
+		session := p.session
+		if unknown, ok := session.ModelTokens[""]; ok {
+			delete(session.ModelTokens, "")
+			session.ModelTokens[model] = session.ModelTokens[model].add(unknown)
+		}
+		for i := range session.Turns {
+			if session.Turns[i].Model == "" {
+				session.Turns[i].Model = model
 			}
 		}
 	}
+	p.state.Model = model
+}
 
-	session.StartedAt = firstTimestamp
-	session.EndedAt = &lastTimestamp
+// Session returns the Codex session parsed so far
+func (p *codexParser) Session() *NormalizedSession {
+	p.finish()
+	session := p.session
 
 	// Estimate tokens only if not already set from token_count event
 	if session.Tokens.Total == 0 {
-		estimateTokens(session)
+		estimateTokensFromChars(session, p.state.InputChars, p.state.OutputChars)
 	} else {
-		// Calculate cost using actual token counts
-		// $3/million input, $15/million output
-		session.Cost = float64(session.Tokens.Input)*3/1_000_000 + float64(session.Tokens.Output)*15/1_000_000
+		session.Price(defaultPricing)
 	}
 
-	return session, nil
+	return session
+}
+
+// State returns the state needed to resume parsing
+func (p *codexParser) State() ([]byte, error) {
+	return p.saveState(&p.state)
 }
 
 // FindLatestSession finds the most recent session file
@@ -270,6 +409,29 @@ func FindSessionByID(sessionsDir, sessionID string) (string, error) {
 	return found, nil
 }
 
+// codexProvider syncs Codex sessions from ~/.codex/sessions
+type codexProvider struct{}
+
+func (codexProvider) Name() Agent                { return AgentCodex }
+func (codexProvider) DisplayName() string        { return "Codex" }
+func (codexProvider) DefaultSessionsDir() string { return GetDefaultSessionsDir() }
+
+func (codexProvider) FindSessions(dir string) ([]string, error) {
+	matches, err := findJSONLFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk sessions directory: %w", err)
+	}
+	return matches, nil
+}
+
+func (codexProvider) NewParser(state []byte) (SessionParser, error) {
+	return newCodexParser(state)
+}
+
+func init() {
+	RegisterProvider(codexProvider{})
+}
+
 // GetDefaultSessionsDir returns the default Codex sessions directory
 func GetDefaultSessionsDir() string {
 	home, _ := os.UserHomeDir()
@@ -308,7 +470,34 @@ func extractMessageContent(content []struct {
 	return result
 }
 
-func estimateTokens(session *CodexSession) {
+// codexTokenUsage converts a token_count usage object into TokenUsage.
+// OpenAI counts cached_input_tokens as part of input_tokens and bills them as
+// cache reads, so Input is net of the cached tokens. Reasoning tokens are part
+// of output_tokens.
+func codexTokenUsage(usage map[string]interface{}) TokenUsage {
+	var tokens TokenUsage
+	if v, ok := usage["input_tokens"].(float64); ok {
+		tokens.Input = int(v)
+	}
+	if v, ok := usage["cached_input_tokens"].(float64); ok {
+		tokens.CacheRead = int(v)
+		tokens.Input -= tokens.CacheRead
+	}
+	if v, ok := usage["output_tokens"].(float64); ok {
+		tokens.Output = int(v)
+	}
+	if v, ok := usage["reasoning_output_tokens"].(float64); ok {
+		tokens.Reasoning = int(v)
+	}
+	if v, ok := usage["total_tokens"].(float64); ok {
+		tokens.Total = int(v)
+	} else {
+		tokens.Total = tokens.Input + tokens.CacheRead + tokens.Output
+	}
+	return tokens
+}
+
+func estimateTokens(session *NormalizedSession) {
 	var inputChars, outputChars int
 
 	for _, msg := range session.Messages {
@@ -319,6 +508,11 @@ func estimateTokens(session *CodexSession) {
 		}
 	}
 
+	estimateTokensFromChars(session, inputChars, outputChars)
+}
+
+// estimateTokensFromChars estimates token usage and cost from message sizes
+func estimateTokensFromChars(session *NormalizedSession, inputChars, outputChars int) {
 	// Rough estimate: 4 chars per token
 	session.Tokens.Input = inputChars / 4
 	session.Tokens.Output = outputChars / 4
@@ -326,7 +520,5 @@ func estimateTokens(session *CodexSession) {
 	session.Tokens.CacheRead = 0
 	session.Tokens.Total = session.Tokens.Input + session.Tokens.Output
 
-	// Cost estimation (approximate)
-	// $3/input million tokens, $15/output million tokens
-	session.Cost = float64(session.Tokens.Input)*3/1_000_000 + float64(session.Tokens.Output)*15/1_000_000
+	session.Price(defaultPricing)
 }
@@ -1,8 +1,10 @@
 package tracker
 
 import (
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -97,8 +99,8 @@ func TestExtractMessageContent(t *testing.T) {
 }
 
 func TestEstimateTokens(t *testing.T) {
-	session := &CodexSession{
-		Messages: []CodexMessage{
+	session := &NormalizedSession{
+		Messages: []Message{
 			{Role: "developer", Content: "Hello"},           // 5 chars
 			{Role: "user", Content: "World"},                 // 5 chars
 			{Role: "assistant", Content: "Response text"},    // 13 chars
@@ -199,3 +201,153 @@ func TestParseCodexSessionEmptyFile(t *testing.T) {
 		t.Errorf("EndedAt should be zero or nil for empty file")
 	}
 }
+
+// codexModelSwitchRollout is a rollout trimmed from a real Codex session: the
+// first token_count has no info, one event is repeated after a rate limit
+// update, and the model changes between turns
+const codexModelSwitchRollout = `{"timestamp":"2026-03-02T09:14:03.112Z","type":"session_meta","payload":{"id":"0199a1c2-7d4e-7a10-9b3f-5c2e8d1f4a60","timestamp":"2026-03-02T09:14:03.101Z","cwd":"/home/dev/app","originator":"codex_cli_rs","cli_version":"0.46.0","instructions":null,"source":"cli","model_provider":"openai"}}
+{"timestamp":"2026-03-02T09:14:05.530Z","type":"turn_context","payload":{"cwd":"/home/dev/app","approval_policy":"on-request","sandbox_policy":{"mode":"workspace-write"},"model":"gpt-5","effort":"medium","summary":"auto"}}
+{"timestamp":"2026-03-02T09:14:05.531Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Fix the failing test"}]}}
+{"timestamp":"2026-03-02T09:14:06.002Z","type":"event_msg","payload":{"type":"token_count","info":null,"rate_limits":{"primary":{"used_percent":4.0,"window_minutes":300}}}}
+{"timestamp":"2026-03-02T09:14:19.874Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":12000,"cached_input_tokens":8000,"output_tokens":500,"reasoning_output_tokens":300,"total_tokens":12500},"last_token_usage":{"input_tokens":12000,"cached_input_tokens":8000,"output_tokens":500,"reasoning_output_tokens":300,"total_tokens":12500},"model_context_window":272000},"rate_limits":{"primary":{"used_percent":4.0,"window_minutes":300}}}}
+{"timestamp":"2026-03-02T09:14:20.101Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":12000,"cached_input_tokens":8000,"output_tokens":500,"reasoning_output_tokens":300,"total_tokens":12500},"last_token_usage":{"input_tokens":12000,"cached_input_tokens":8000,"output_tokens":500,"reasoning_output_tokens":300,"total_tokens":12500},"model_context_window":272000},"rate_limits":{"primary":{"used_percent":5.0,"window_minutes":300}}}}
+{"timestamp":"2026-03-02T09:15:40.220Z","type":"turn_context","payload":{"cwd":"/home/dev/app","approval_policy":"on-request","sandbox_policy":{"mode":"workspace-write"},"model":"gpt-5-mini","effort":"low","summary":"auto"}}
+{"timestamp":"2026-03-02T09:15:40.221Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Now update the changelog"}]}}
+{"timestamp":"2026-03-02T09:15:52.640Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":30000,"cached_input_tokens":24000,"output_tokens":1500,"reasoning_output_tokens":800,"total_tokens":31500},"last_token_usage":{"input_tokens":18000,"cached_input_tokens":16000,"output_tokens":1000,"reasoning_output_tokens":500,"total_tokens":19000},"model_context_window":272000},"rate_limits":{"primary":{"used_percent":6.0,"window_minutes":300}}}}
+`
+
+func TestParseCodexSessionTokenAccounting(t *testing.T) {
+	sessionFile := filepath.Join(t.TempDir(), "rollout.jsonl")
+	if err := os.WriteFile(sessionFile, []byte(codexModelSwitchRollout), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseCodexSession(sessionFile)
+	if err != nil {
+		t.Fatalf("ParseCodexSession() error = %v", err)
+	}
+
+	// Cached input is a cache read, and input is net of it
+	want := TokenUsage{Input: 6000, Output: 1500, CacheRead: 24000, Reasoning: 800, Total: 31500}
+	if parsed.Tokens != want {
+		t.Errorf("Tokens = %+v; want %+v", parsed.Tokens, want)
+	}
+
+	wantModels := map[string]TokenUsage{
+		"gpt-5":      {Input: 4000, Output: 500, CacheRead: 8000, Reasoning: 300, Total: 12500},
+		"gpt-5-mini": {Input: 2000, Output: 1000, CacheRead: 16000, Reasoning: 500, Total: 19000},
+	}
+	if len(parsed.ModelTokens) != len(wantModels) {
+		t.Errorf("ModelTokens = %+v; want %+v", parsed.ModelTokens, wantModels)
+	}
+	for model, tokens := range wantModels {
+		if parsed.ModelTokens[model] != tokens {
+			t.Errorf("ModelTokens[%s] = %+v; want %+v", model, parsed.ModelTokens[model], tokens)
+		}
+	}
+
+	// The repeated token_count event is not another turn
+	if len(parsed.Turns) != 2 {
+		t.Fatalf("len(Turns) = %d; want 2", len(parsed.Turns))
+	}
+	if parsed.Turns[0].Model != "gpt-5" || parsed.Turns[1].Model != "gpt-5-mini" || parsed.Turns[1].Tokens != wantModels["gpt-5-mini"] {
+		t.Errorf("Turns = %+v", parsed.Turns)
+	}
+
+	// gpt-5:      4000 * 1.25 + 8000 * 0.125 + 500 * 10 = 0.011
+	// gpt-5-mini: 2000 * 0.25 + 16000 * 0.025 + 1000 * 2 = 0.0029
+	if math.Abs(parsed.Cost-0.0139) > 1e-9 {
+		t.Errorf("Cost = %v; want 0.0139", parsed.Cost)
+	}
+}
+
+func TestParseCodexTokensBeforeTurnContext(t *testing.T) {
+	meta := `{"timestamp":"2026-03-02T11:00:00.000Z","type":"session_meta","payload":{"id":"early-1","cwd":"/home/dev/app","originator":"codex_cli_rs","model_provider":"openai"}}
+{"timestamp":"2026-03-02T11:00:02.000Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":1000,"output_tokens":100,"total_tokens":1100},"last_token_usage":{"input_tokens":1000,"output_tokens":100,"total_tokens":1100}}}}
+`
+	later := `{"timestamp":"2026-03-02T11:00:05.000Z","type":"turn_context","payload":{"cwd":"/home/dev/app","model":"gpt-5"}}
+{"timestamp":"2026-03-02T11:00:09.000Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":3000,"output_tokens":300,"total_tokens":3300},"last_token_usage":{"input_tokens":2000,"output_tokens":200,"total_tokens":2200}}}}
+`
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]TokenUsage
+	}{
+		{"first model", meta + later, map[string]TokenUsage{"gpt-5": {Input: 3000, Output: 300, Total: 3300}}},
+		{"no turn context", meta, map[string]TokenUsage{"": {Input: 1000, Output: 100, Total: 1100}}},
+	}
+	for _, tt := range tests {
+		sessionFile := filepath.Join(t.TempDir(), "rollout.jsonl")
+		if err := os.WriteFile(sessionFile, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseCodexSession(sessionFile)
+		if err != nil {
+			t.Fatalf("%s: ParseCodexSession() error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(parsed.ModelTokens, tt.want) {
+			t.Errorf("%s: ModelTokens = %+v; want %+v", tt.name, parsed.ModelTokens, tt.want)
+		}
+		for _, turn := range parsed.Turns {
+			if _, ok := tt.want[turn.Model]; !ok {
+				t.Errorf("%s: turn model = %q; want one of %v", tt.name, turn.Model, tt.want)
+			}
+		}
+	}
+}
+
+func TestParseCodexToolCalls(t *testing.T) {
+	content := `{"timestamp":"2026-03-02T10:00:00.000Z","type":"session_meta","payload":{"id":"tools-1","cwd":"/home/dev/app","originator":"codex_cli_rs","model_provider":"openai"}}
+{"timestamp":"2026-03-02T10:00:01.000Z","type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{\"command\":[\"bash\",\"-lc\",\"go test ./...\"],\"workdir\":\"/home/dev/app\"}","call_id":"call_shell"}}
+{"timestamp":"2026-03-02T10:00:03.000Z","type":"response_item","payload":{"type":"function_call_output","call_id":"call_shell","output":"{\"output\":\"ok  \\tapp\\t0.2s\\n\",\"metadata\":{\"exit_code\":0,\"duration_seconds\":1.5}}"}}
+{"timestamp":"2026-03-02T10:00:04.000Z","type":"response_item","payload":{"type":"custom_tool_call","status":"completed","call_id":"call_patch","name":"apply_patch","input":"*** Begin Patch\n*** Update File: main.go\n*** End Patch"}}
+{"timestamp":"2026-03-02T10:00:04.250Z","type":"response_item","payload":{"type":"custom_tool_call_output","call_id":"call_patch","output":"{\"output\":\"Success. Updated the following files:\\nM main.go\\n\",\"metadata\":{\"exit_code\":0,\"duration_seconds\":0.0}}"}}
+{"timestamp":"2026-03-02T10:00:05.000Z","type":"response_item","payload":{"type":"local_shell_call","id":"lsh_1","call_id":"call_local","status":"completed","action":{"type":"exec","command":["make","lint"]}}}
+{"timestamp":"2026-03-02T10:00:08.000Z","type":"response_item","payload":{"type":"function_call_output","call_id":"call_local","output":"Exit code: 2\nWall time: 2.8 seconds\nTotal output lines: 1\nOutput:\nlint failed\n"}}
+{"timestamp":"2026-03-02T10:00:09.000Z","type":"response_item","payload":{"type":"function_call","name":"view_image","arguments":"{\"path\":\"missing.png\"}","call_id":"call_image"}}
+{"timestamp":"2026-03-02T10:00:09.500Z","type":"response_item","payload":{"type":"function_call_output","call_id":"call_image","output":{"content":"file not found","success":false}}}
+`
+	sessionFile := filepath.Join(t.TempDir(), "rollout.jsonl")
+	if err := os.WriteFile(sessionFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseCodexSession(sessionFile)
+	if err != nil {
+		t.Fatalf("ParseCodexSession() error = %v", err)
+	}
+	if len(parsed.ToolCalls) != 4 {
+		t.Fatalf("len(ToolCalls) = %d; want 4", len(parsed.ToolCalls))
+	}
+
+	exitCode := func(tc ToolCall) int {
+		if tc.ExitCode == nil {
+			return -1
+		}
+		return *tc.ExitCode
+	}
+	tests := []struct {
+		name     string
+		callID   string
+		result   string
+		exitCode int
+		isError  bool
+		duration time.Duration
+	}{
+		{"shell", "call_shell", "ok  \tapp\t0.2s\n", 0, false, 1500 * time.Millisecond},
+		// A zero reported duration falls back to the time between call and output
+		{"apply_patch", "call_patch", "Success. Updated the following files:\nM main.go\n", 0, false, 250 * time.Millisecond},
+		{"local_shell", "call_local", "lint failed\n", 2, true, 2800 * time.Millisecond},
+		{"view_image", "call_image", "file not found", -1, true, 500 * time.Millisecond},
+	}
+	for i, tt := range tests {
+		tc := parsed.ToolCalls[i]
+		if tc.ToolName != tt.name || tc.CallID != tt.callID || tc.Result != tt.result || exitCode(tc) != tt.exitCode ||
+			tc.IsError != tt.isError || tc.Duration != tt.duration || tc.ResultOnly {
+			t.Errorf("ToolCalls[%d] = %+v; want %+v", i, tc, tt)
+		}
+	}
+	if parsed.ToolCalls[2].Arguments != `{"type":"exec","command":["make","lint"]}` {
+		t.Errorf("local_shell arguments = %s", parsed.ToolCalls[2].Arguments)
+	}
+}
@@ -4,143 +4,170 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
 // DB represents the database connection
 type DB struct {
-	db *sql.DB
+	db   dbtx
+	conn *sql.DB // nil when DB is bound to a transaction
+}
+
+// dbtx is the subset of *sql.DB and *sql.Tx used by queries
+type dbtx interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
 const messageCountSubquery = "COALESCE((SELECT COUNT(*) FROM messages m WHERE m.session_id = s.id), 0) as message_count"
 
-// Open opens the database at the given path
+// Open opens the database at the given path and applies pending migrations
 func Open(path string) (*DB, error) {
-	db, err := sql.Open("sqlite", path)
+	d, err := OpenUnmigrated(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	d := &DB{db: db}
-	if err := d.migrate(); err != nil {
-		db.Close()
+	if _, err := d.Migrate(context.Background()); err != nil {
+		d.Close()
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	return d, nil
 }
 
+// OpenUnmigrated opens the database at the given path without applying migrations
+func OpenUnmigrated(path string) (*DB, error) {
+	// The watch daemon writes while other commands read, so readers use WAL
+	// instead of blocking on writers and writers wait for each other
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return &DB{db: db, conn: db}, nil
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
-	return db.db.Close()
-}
-
-// migrate creates the database tables if they don't exist
-func (db *DB) migrate() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		external_id TEXT UNIQUE,
-		source TEXT NOT NULL,
-		project_path TEXT,
-		model TEXT,
-		provider TEXT,
-		started_at INTEGER NOT NULL,
-		ended_at INTEGER,
-		input_tokens INTEGER DEFAULT 0,
-		output_tokens INTEGER DEFAULT 0,
-		cache_creation_tokens INTEGER DEFAULT 0,
-		cache_read_tokens INTEGER DEFAULT 0,
-		total_tokens INTEGER DEFAULT 0,
-		cost REAL DEFAULT 0,
-		reasoning_tokens INTEGER DEFAULT 0
-	);
-
-	CREATE TABLE IF NOT EXISTS messages (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id INTEGER NOT NULL,
-		role TEXT NOT NULL,
-		content TEXT,
-		timestamp INTEGER NOT NULL,
-		FOREIGN KEY (session_id) REFERENCES sessions(id)
-	);
-
-	CREATE TABLE IF NOT EXISTS tool_calls (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id INTEGER NOT NULL,
-		tool_name TEXT NOT NULL,
-		arguments TEXT,
-		result TEXT,
-		timestamp INTEGER NOT NULL,
-		FOREIGN KEY (session_id) REFERENCES sessions(id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_sessions_external_id ON sessions(external_id);
-	CREATE INDEX IF NOT EXISTS idx_messages_session_id ON messages(session_id);
-	CREATE INDEX IF NOT EXISTS idx_tool_calls_session_id ON tool_calls(session_id);
-
-	CREATE TABLE IF NOT EXISTS metadata (
-		key TEXT PRIMARY KEY,
-		value TEXT,
-		updated_at INTEGER
-	);
-	`
+	if db.conn == nil {
+		return fmt.Errorf("cannot close a transaction-bound database")
+	}
+	return db.conn.Close()
+}
+
+// WithTx runs fn inside a transaction, committing if fn returns nil.
+// Calls on a DB that is already bound to a transaction reuse it.
+func (db *DB) WithTx(ctx context.Context, fn func(tx *DB) error) error {
+	if db.conn == nil {
+		return fn(db)
+	}
 
-	_, err := db.db.Exec(schema)
+	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(&DB{db: tx}); err != nil {
+		tx.Rollback()
 		return err
 	}
-
-	// Migrate existing database if columns are missing
-	// SQLite doesn't support IF NOT EXISTS in ALTER TABLE, so we try and ignore errors
-	db.db.Exec("ALTER TABLE sessions ADD COLUMN cache_creation_tokens INTEGER DEFAULT 0")
-	db.db.Exec("ALTER TABLE sessions ADD COLUMN cache_read_tokens INTEGER DEFAULT 0")
-	db.db.Exec("ALTER TABLE sessions ADD COLUMN reasoning_tokens INTEGER DEFAULT 0")
-
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
 // SessionRow represents a session database row
 type SessionRow struct {
-	ID                  int64
-	ExternalID          string
-	Source              string
-	ProjectPath         string
-	Model               string
-	Provider            string
-	StartedAt           int64
-	EndedAt             *int64
-	InputTokens         int64
-	OutputTokens        int64
-	CacheCreationTokens int64
-	CacheReadTokens     int64
-	ReasoningTokens     int64
-	TotalTokens         int64
-	Cost                float64
-	MessageCount        int64
+	ID                  int64   `json:"-"`
+	ExternalID          string  `json:"external_id"`
+	Source              string  `json:"source"`
+	ProjectPath         string  `json:"project_path"`
+	Model               string  `json:"model"`
+	Provider            string  `json:"provider"`
+	StartedAt           int64   `json:"started_at"`
+	EndedAt             *int64  `json:"ended_at"`
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	ReasoningTokens     int64   `json:"reasoning_tokens"`
+	TotalTokens         int64   `json:"total_tokens"`
+	Cost                float64 `json:"cost"`
+	MessageCount        int64   `json:"message_count"`
+	Metadata            string  `json:"-"` // JSON-encoded agent-specific metadata
+	Fingerprint         string  `json:"-"` // content hash of the source file at last sync
 }
 
 // InsertSession inserts a new session and returns its ID
 func (db *DB) InsertSession(ctx context.Context, s *SessionRow) (int64, error) {
 	query := `
 	INSERT INTO sessions (external_id, source, project_path, model, provider, started_at, ended_at,
-		input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, reasoning_tokens, total_tokens, cost)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, reasoning_tokens, total_tokens, cost, metadata, fingerprint)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	result, err := db.db.ExecContext(ctx, query,
 		s.ExternalID, s.Source, s.ProjectPath, s.Model, s.Provider, s.StartedAt, s.EndedAt,
-		s.InputTokens, s.OutputTokens, s.CacheCreationTokens, s.CacheReadTokens, s.ReasoningTokens, s.TotalTokens, s.Cost)
+		s.InputTokens, s.OutputTokens, s.CacheCreationTokens, s.CacheReadTokens, s.ReasoningTokens, s.TotalTokens, s.Cost, s.Metadata, s.Fingerprint)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert session: %w", err)
 	}
 	return result.LastInsertId()
 }
 
+// UpdateSession overwrites the stored fields of the session with the given ID
+func (db *DB) UpdateSession(ctx context.Context, id int64, s *SessionRow) error {
+	query := `
+	UPDATE sessions SET project_path = ?, model = ?, provider = ?, started_at = ?, ended_at = ?,
+		input_tokens = ?, output_tokens = ?, cache_creation_tokens = ?, cache_read_tokens = ?, reasoning_tokens = ?,
+		total_tokens = ?, cost = ?, metadata = ?, fingerprint = ?
+	WHERE id = ?
+	`
+	_, err := db.db.ExecContext(ctx, query,
+		s.ProjectPath, s.Model, s.Provider, s.StartedAt, s.EndedAt,
+		s.InputTokens, s.OutputTokens, s.CacheCreationTokens, s.CacheReadTokens, s.ReasoningTokens,
+		s.TotalTokens, s.Cost, s.Metadata, s.Fingerprint, id)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// UpdateSessionCost sets the stored cost of the session with the given ID
+func (db *DB) UpdateSessionCost(ctx context.Context, id int64, cost float64) error {
+	if _, err := db.db.ExecContext(ctx, `UPDATE sessions SET cost = ? WHERE id = ?`, cost, id); err != nil {
+		return fmt.Errorf("failed to update session cost: %w", err)
+	}
+	return nil
+}
+
+// DeleteSessionContent removes all messages, tool calls and turns of a session
+func (db *DB) DeleteSessionContent(ctx context.Context, sessionID int64) error {
+	// The external-content index needs the old content to remove its entries
+	if _, err := db.db.ExecContext(ctx, `INSERT INTO messages_fts(messages_fts, rowid, content)
+		SELECT 'delete', id, content FROM messages WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete message index: %w", err)
+	}
+	if _, err := db.db.ExecContext(ctx, `DELETE FROM messages WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete messages: %w", err)
+	}
+	if _, err := db.db.ExecContext(ctx, `DELETE FROM tool_calls WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete tool calls: %w", err)
+	}
+	if _, err := db.db.ExecContext(ctx, `DELETE FROM turns WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete turns: %w", err)
+	}
+	return nil
+}
+
 // GetSessionByExternalID retrieves a session by its external ID
 func (db *DB) GetSessionByExternalID(ctx context.Context, externalID string) (*SessionRow, error) {
 	query := `SELECT id, external_id, source, project_path, model, provider, started_at, ended_at,
-		input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, reasoning_tokens, total_tokens, cost
+		input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, reasoning_tokens, total_tokens, cost,
+		COALESCE(metadata, ''), COALESCE(fingerprint, '')
 		FROM sessions WHERE external_id = ?`
 
 	row := db.db.QueryRowContext(ctx, query, externalID)
@@ -148,7 +175,7 @@ func (db *DB) GetSessionByExternalID(ctx context.Context, externalID string) (*S
 	err := row.Scan(
 		&s.ID, &s.ExternalID, &s.Source, &s.ProjectPath, &s.Model, &s.Provider,
 		&s.StartedAt, &s.EndedAt, &s.InputTokens, &s.OutputTokens, &s.CacheCreationTokens,
-		&s.CacheReadTokens, &s.ReasoningTokens, &s.TotalTokens, &s.Cost,
+		&s.CacheReadTokens, &s.ReasoningTokens, &s.TotalTokens, &s.Cost, &s.Metadata, &s.Fingerprint,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -161,43 +188,285 @@ func (db *DB) GetSessionByExternalID(ctx context.Context, externalID string) (*S
 
 // MessageRow represents a message database row
 type MessageRow struct {
-	ID        int64
-	SessionID int64
-	Role      string
-	Content   string
-	Timestamp int64
+	ID        int64  `json:"-"`
+	SessionID int64  `json:"-"`
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	Timestamp int64  `json:"timestamp"`
 }
 
-// InsertMessage inserts a new message
+// InsertMessage inserts a new message and adds it to the full-text index
 func (db *DB) InsertMessage(ctx context.Context, m *MessageRow) (int64, error) {
 	query := `INSERT INTO messages (session_id, role, content, timestamp) VALUES (?, ?, ?, ?)`
 	result, err := db.db.ExecContext(ctx, query, m.SessionID, m.Role, m.Content, m.Timestamp)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert message: %w", err)
 	}
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert message: %w", err)
+	}
+	if _, err := db.db.ExecContext(ctx, `INSERT INTO messages_fts (rowid, content) VALUES (?, ?)`, id, m.Content); err != nil {
+		return 0, fmt.Errorf("failed to index message: %w", err)
+	}
+	return id, nil
 }
 
 // ToolCallRow represents a tool call database row
 type ToolCallRow struct {
-	ID        int64
-	SessionID int64
-	ToolName  string
-	Arguments string
-	Result    string
-	Timestamp int64
+	ID         int64  `json:"-"`
+	SessionID  int64  `json:"-"`
+	ToolName   string `json:"tool_name"`
+	Arguments  string `json:"arguments"`
+	Result     string `json:"result"`
+	Timestamp  int64  `json:"timestamp"`
+	CallID     string `json:"call_id"`
+	ExitCode   *int64 `json:"exit_code"` // nil if unknown
+	IsError    bool   `json:"is_error"`
+	DurationMs int64  `json:"duration_ms"` // 0 if unknown
 }
 
 // InsertToolCall inserts a new tool call
 func (db *DB) InsertToolCall(ctx context.Context, t *ToolCallRow) (int64, error) {
-	query := `INSERT INTO tool_calls (session_id, tool_name, arguments, result, timestamp) VALUES (?, ?, ?, ?, ?)`
-	result, err := db.db.ExecContext(ctx, query, t.SessionID, t.ToolName, t.Arguments, t.Result, t.Timestamp)
+	query := `INSERT INTO tool_calls (session_id, tool_name, arguments, result, timestamp, call_id, exit_code, is_error, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := db.db.ExecContext(ctx, query, t.SessionID, t.ToolName, t.Arguments, t.Result, t.Timestamp,
+		t.CallID, t.ExitCode, t.IsError, t.DurationMs)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert tool call: %w", err)
 	}
 	return result.LastInsertId()
 }
 
+// UpdateToolCallResult sets the result of the stored tool call of a session with the call ID of t
+func (db *DB) UpdateToolCallResult(ctx context.Context, t *ToolCallRow) error {
+	query := `UPDATE tool_calls SET result = ?, exit_code = ?, is_error = ?, duration_ms = ?
+		WHERE session_id = ? AND call_id = ?`
+	if _, err := db.db.ExecContext(ctx, query, t.Result, t.ExitCode, t.IsError, t.DurationMs, t.SessionID, t.CallID); err != nil {
+		return fmt.Errorf("failed to update tool call result: %w", err)
+	}
+	return nil
+}
+
+// TurnRow represents a turns database row
+type TurnRow struct {
+	ID                  int64  `json:"-"`
+	SessionID           int64  `json:"-"`
+	TurnIndex           int    `json:"turn_index"`
+	Timestamp           int64  `json:"timestamp"`
+	Model               string `json:"model"`
+	InputTokens         int64  `json:"input_tokens"`
+	OutputTokens        int64  `json:"output_tokens"`
+	CacheCreationTokens int64  `json:"cache_creation_tokens"`
+	CacheReadTokens     int64  `json:"cache_read_tokens"`
+	ReasoningTokens     int64  `json:"reasoning_tokens"`
+	TotalTokens         int64  `json:"total_tokens"`
+	LatencyMs           int64  `json:"latency_ms"` // since the prior user message, 0 if unknown
+	UsageKey            string `json:"-"`          // agent-specific ID of the response, empty if unknown
+}
+
+// ContextTokens returns the size of the prompt sent to the model in the turn
+func (t TurnRow) ContextTokens() int64 {
+	return t.InputTokens + t.CacheCreationTokens + t.CacheReadTokens
+}
+
+// InsertTurn inserts a new turn
+func (db *DB) InsertTurn(ctx context.Context, t *TurnRow) (int64, error) {
+	query := `INSERT INTO turns (session_id, turn_index, timestamp, model, input_tokens, output_tokens,
+		cache_creation_tokens, cache_read_tokens, reasoning_tokens, total_tokens, latency_ms, usage_key)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := db.db.ExecContext(ctx, query, t.SessionID, t.TurnIndex, t.Timestamp, t.Model, t.InputTokens, t.OutputTokens,
+		t.CacheCreationTokens, t.CacheReadTokens, t.ReasoningTokens, t.TotalTokens, t.LatencyMs, t.UsageKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert turn: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// SumTurnTokens returns the token totals of the stored turns of a session by model
+func (db *DB) SumTurnTokens(ctx context.Context, sessionID int64) (map[string]TokenUsage, error) {
+	query := `SELECT COALESCE(model, ''), SUM(input_tokens), SUM(output_tokens), SUM(cache_creation_tokens),
+		SUM(cache_read_tokens), SUM(reasoning_tokens), SUM(total_tokens)
+		FROM turns WHERE session_id = ? GROUP BY COALESCE(model, '')`
+
+	rows, err := db.db.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum turn tokens: %w", err)
+	}
+	defer rows.Close()
+
+	models := make(map[string]TokenUsage)
+	for rows.Next() {
+		var model string
+		var u TokenUsage
+		if err := rows.Scan(&model, &u.Input, &u.Output, &u.CacheCreation, &u.CacheRead, &u.Reasoning, &u.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan turn tokens: %w", err)
+		}
+		models[model] = u
+	}
+	return models, rows.Err()
+}
+
+// SessionModelRow represents a session_models database row, the usage of one
+// model within a session
+type SessionModelRow struct {
+	SessionID           int64   `json:"-"`
+	Model               string  `json:"model"`
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	ReasoningTokens     int64   `json:"reasoning_tokens"`
+	TotalTokens         int64   `json:"total_tokens"`
+	Cost                float64 `json:"cost"`
+}
+
+// ReplaceSessionModels replaces the per-model usage of a session
+func (db *DB) ReplaceSessionModels(ctx context.Context, sessionID int64, models []SessionModelRow) error {
+	if _, err := db.db.ExecContext(ctx, `DELETE FROM session_models WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete session models: %w", err)
+	}
+	query := `INSERT INTO session_models (session_id, model, input_tokens, output_tokens, cache_creation_tokens,
+		cache_read_tokens, reasoning_tokens, total_tokens, cost) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	for _, m := range models {
+		if _, err := db.db.ExecContext(ctx, query, sessionID, m.Model, m.InputTokens, m.OutputTokens, m.CacheCreationTokens,
+			m.CacheReadTokens, m.ReasoningTokens, m.TotalTokens, m.Cost); err != nil {
+			return fmt.Errorf("failed to insert session model: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetSessionModels returns the per-model usage of a session ordered by model
+func (db *DB) GetSessionModels(ctx context.Context, sessionID int64) ([]SessionModelRow, error) {
+	query := `SELECT session_id, model, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens,
+		reasoning_tokens, total_tokens, cost
+		FROM session_models WHERE session_id = ? ORDER BY model`
+
+	rows, err := db.db.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session models: %w", err)
+	}
+	defer rows.Close()
+
+	var models []SessionModelRow
+	for rows.Next() {
+		var m SessionModelRow
+		if err := rows.Scan(&m.SessionID, &m.Model, &m.InputTokens, &m.OutputTokens, &m.CacheCreationTokens,
+			&m.CacheReadTokens, &m.ReasoningTokens, &m.TotalTokens, &m.Cost); err != nil {
+			return nil, fmt.Errorf("failed to scan session model: %w", err)
+		}
+		models = append(models, m)
+	}
+	return models, rows.Err()
+}
+
+// UpdateSessionModelCost sets the stored cost of one model of a session
+func (db *DB) UpdateSessionModelCost(ctx context.Context, sessionID int64, model string, cost float64) error {
+	if _, err := db.db.ExecContext(ctx, `UPDATE session_models SET cost = ? WHERE session_id = ? AND model = ?`,
+		cost, sessionID, model); err != nil {
+		return fmt.Errorf("failed to update session model cost: %w", err)
+	}
+	return nil
+}
+
+// ClaimUsageKey records that the usage with the given key, reported at
+// timestamp, belongs to a session, and returns the external ID of its owner.
+// A key claimed by another session goes to the session that reported it
+// first; copies of a response in resumed sessions keep its timestamp, so ties
+// go to the session that ended first, then to the lower external ID. The owner
+// therefore does not depend on the order files are synced in. When the claim
+// moves, the turns of the previous owner with the key are deleted and its
+// external ID is returned as previous.
+func (db *DB) ClaimUsageKey(ctx context.Context, key, externalID string, timestamp int64) (owner, previous string, err error) {
+	var ownerTimestamp int64
+	err = db.db.QueryRowContext(ctx, `SELECT external_id, COALESCE(timestamp, 0) FROM usage_keys WHERE key = ?`, key).
+		Scan(&owner, &ownerTimestamp)
+	if err == sql.ErrNoRows {
+		if _, err := db.db.ExecContext(ctx, `INSERT INTO usage_keys (key, external_id, timestamp) VALUES (?, ?, ?)`,
+			key, externalID, timestamp); err != nil {
+			return "", "", fmt.Errorf("failed to claim usage key: %w", err)
+		}
+		return externalID, "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read usage key: %w", err)
+	}
+	if owner == externalID {
+		// Keys claimed before timestamps were stored have none
+		if ownerTimestamp == 0 {
+			if _, err := db.db.ExecContext(ctx, `UPDATE usage_keys SET timestamp = ? WHERE key = ?`, timestamp, key); err != nil {
+				return "", "", fmt.Errorf("failed to update usage key: %w", err)
+			}
+		}
+		return owner, "", nil
+	}
+
+	wins := timestamp < ownerTimestamp
+	if ownerTimestamp == 0 || timestamp == ownerTimestamp {
+		if wins, err = db.endsFirst(ctx, externalID, owner); err != nil {
+			return "", "", err
+		}
+	}
+	if !wins {
+		return owner, "", nil
+	}
+
+	if _, err := db.db.ExecContext(ctx, `UPDATE usage_keys SET external_id = ?, timestamp = ? WHERE key = ?`,
+		externalID, timestamp, key); err != nil {
+		return "", "", fmt.Errorf("failed to claim usage key: %w", err)
+	}
+	if _, err := db.db.ExecContext(ctx, `DELETE FROM turns WHERE usage_key = ?
+		AND session_id IN (SELECT id FROM sessions WHERE external_id = ?)`, key, owner); err != nil {
+		return "", "", fmt.Errorf("failed to delete moved turns: %w", err)
+	}
+	return externalID, owner, nil
+}
+
+// endsFirst reports whether session a ended before session b, or at the same
+// time and has the lower external ID. Sessions that are not stored end last.
+func (db *DB) endsFirst(ctx context.Context, a, b string) (bool, error) {
+	end := func(externalID string) (int64, bool, error) {
+		var ts int64
+		err := db.db.QueryRowContext(ctx, `SELECT COALESCE(ended_at, started_at) FROM sessions WHERE external_id = ?`,
+			externalID).Scan(&ts)
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get session end: %w", err)
+		}
+		return ts, true, nil
+	}
+	endA, okA, err := end(a)
+	if err != nil {
+		return false, err
+	}
+	endB, okB, err := end(b)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case okA != okB:
+		return okA, nil
+	case endA != endB:
+		return endA < endB, nil
+	default:
+		return a < b, nil
+	}
+}
+
+// UpdateSessionTotals sets the token totals and cost of a session
+func (db *DB) UpdateSessionTotals(ctx context.Context, id int64, u TokenUsage, cost float64) error {
+	query := `UPDATE sessions SET input_tokens = ?, output_tokens = ?, cache_creation_tokens = ?, cache_read_tokens = ?,
+		reasoning_tokens = ?, total_tokens = ?, cost = ? WHERE id = ?`
+	if _, err := db.db.ExecContext(ctx, query, u.Input, u.Output, u.CacheCreation, u.CacheRead, u.Reasoning, u.Total,
+		cost, id); err != nil {
+		return fmt.Errorf("failed to update session totals: %w", err)
+	}
+	return nil
+}
+
 // GetAllSessions returns all sessions ordered by started_at descending
 func (db *DB) GetAllSessions(ctx context.Context) ([]SessionRow, error) {
 	query := `SELECT s.id, s.external_id, s.source, s.project_path, s.model, s.provider, s.started_at, s.ended_at,
@@ -250,7 +519,9 @@ func (db *DB) GetMessagesBySessionID(ctx context.Context, sessionID int64) ([]Me
 
 // GetToolCallsBySessionID returns all tool calls for a session
 func (db *DB) GetToolCallsBySessionID(ctx context.Context, sessionID int64) ([]ToolCallRow, error) {
-	query := `SELECT id, session_id, tool_name, arguments, result, timestamp FROM tool_calls WHERE session_id = ? ORDER BY timestamp`
+	query := `SELECT id, session_id, tool_name, COALESCE(arguments, ''), COALESCE(result, ''), timestamp,
+		COALESCE(call_id, ''), exit_code, COALESCE(is_error, 0), COALESCE(duration_ms, 0)
+		FROM tool_calls WHERE session_id = ? ORDER BY timestamp, id`
 
 	rows, err := db.db.QueryContext(ctx, query, sessionID)
 	if err != nil {
@@ -261,7 +532,8 @@ func (db *DB) GetToolCallsBySessionID(ctx context.Context, sessionID int64) ([]T
 	var toolCalls []ToolCallRow
 	for rows.Next() {
 		var t ToolCallRow
-		err := rows.Scan(&t.ID, &t.SessionID, &t.ToolName, &t.Arguments, &t.Result, &t.Timestamp)
+		err := rows.Scan(&t.ID, &t.SessionID, &t.ToolName, &t.Arguments, &t.Result, &t.Timestamp,
+			&t.CallID, &t.ExitCode, &t.IsError, &t.DurationMs)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan tool call: %w", err)
 		}
@@ -283,12 +555,14 @@ type AggregatedStats struct {
 }
 
 // GetLastSession returns the most recent session within the time period
-func (db *DB) GetLastSession(ctx context.Context, source string, since int64) (*SessionRow, error) {
+func (db *DB) GetLastSession(ctx context.Context, source string, since, until int64, project string) (*SessionRow, error) {
 	query := `SELECT s.id, s.external_id, s.source, s.project_path, s.model, s.provider, s.started_at, s.ended_at,
 		s.input_tokens, s.output_tokens, s.cache_creation_tokens, s.cache_read_tokens, s.reasoning_tokens, s.total_tokens, ` + messageCountSubquery + `, s.cost
-		FROM sessions s WHERE s.source = ? AND s.started_at >= ? ORDER BY s.started_at DESC LIMIT 1`
+		FROM sessions s WHERE s.source = ? AND s.started_at >= ? AND s.started_at < ?`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter + ` ORDER BY s.started_at DESC LIMIT 1`
 
-	row := db.db.QueryRowContext(ctx, query, source, since)
+	row := db.db.QueryRowContext(ctx, query, append([]any{source, since, until}, args...)...)
 	var s SessionRow
 	var endedAt sql.NullInt64
 	err := row.Scan(
@@ -308,22 +582,45 @@ func (db *DB) GetLastSession(ctx context.Context, source string, since int64) (*
 	return &s, nil
 }
 
-// GetTopModels returns the top N models by session count
-func (db *DB) GetTopModels(ctx context.Context, source string, since int64, limit int) ([]ModelUsage, error) {
-	query := `SELECT model, COUNT(*) as session_count
-		FROM sessions WHERE source = ? AND started_at >= ? AND model IS NOT NULL AND model != ''
-		GROUP BY model ORDER BY session_count DESC LIMIT ?`
-
-	rows, err := db.db.QueryContext(ctx, query, source, since, limit)
+// GetTopModels returns the top N models ranked by session count or cost. A
+// session that switched models counts toward each model with its usage of it.
+func (db *DB) GetTopModels(ctx context.Context, source string, since, until int64, project string, limit int, sort SortOrder) ([]ModelUsage, error) {
+	query := `SELECT m.model, COUNT(*) as session_count, COALESCE(SUM(m.total_tokens), 0), COALESCE(SUM(m.cost), 0) as total_cost
+		FROM session_models m JOIN sessions s ON s.id = m.session_id
+		WHERE s.source = ? AND s.started_at >= ? AND s.started_at < ? AND m.model != ''`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter + ` GROUP BY m.model ORDER BY ` + sort.orderBy() + ` LIMIT ?`
+
+	args = append([]any{source, since, until}, args...)
+	rows, err := db.db.QueryContext(ctx, query, append(args, limit)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query top models: %w", err)
 	}
 	defer rows.Close()
+	return scanModelUsage(rows)
+}
+
+// GetTopProjects returns the top N projects ranked by session count or cost
+func (db *DB) GetTopProjects(ctx context.Context, source string, since, until int64, project string, limit int, sort SortOrder) ([]ProjectUsage, error) {
+	query := `SELECT project_path, COUNT(*) as session_count, COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost), 0) as total_cost
+		FROM sessions WHERE source = ? AND started_at >= ? AND started_at < ? AND project_path IS NOT NULL AND project_path != ''`
+	filter, args := projectFilter("project_path", project)
+	query += filter + ` GROUP BY project_path ORDER BY ` + sort.orderBy() + ` LIMIT ?`
 
+	args = append([]any{source, since, until}, args...)
+	rows, err := db.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top projects: %w", err)
+	}
+	defer rows.Close()
+	return scanProjectUsage(rows)
+}
+
+func scanModelUsage(rows *sql.Rows) ([]ModelUsage, error) {
 	var models []ModelUsage
 	for rows.Next() {
 		var m ModelUsage
-		if err := rows.Scan(&m.Model, &m.SessionCount); err != nil {
+		if err := rows.Scan(&m.Model, &m.SessionCount, &m.TotalTokens, &m.TotalCost); err != nil {
 			return nil, fmt.Errorf("failed to scan model: %w", err)
 		}
 		models = append(models, m)
@@ -331,8 +628,20 @@ func (db *DB) GetTopModels(ctx context.Context, source string, since int64, limi
 	return models, rows.Err()
 }
 
+func scanProjectUsage(rows *sql.Rows) ([]ProjectUsage, error) {
+	var projects []ProjectUsage
+	for rows.Next() {
+		var p ProjectUsage
+		if err := rows.Scan(&p.ProjectPath, &p.SessionCount, &p.TotalTokens, &p.TotalCost); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
 // GetAggregatedStats returns aggregated statistics for the period
-func (db *DB) GetAggregatedStats(ctx context.Context, source string, since int64) (*AggregatedStats, error) {
+func (db *DB) GetAggregatedStats(ctx context.Context, source string, since, until int64, project string) (*AggregatedStats, error) {
 	query := `SELECT
 		COALESCE(SUM(CASE WHEN ended_at IS NOT NULL AND ended_at > started_at THEN ended_at - started_at ELSE 0 END), 0) as total_time,
 		COALESCE(SUM(input_tokens), 0) as total_input,
@@ -342,10 +651,12 @@ func (db *DB) GetAggregatedStats(ctx context.Context, source string, since int64
 		COALESCE(SUM(total_tokens), 0) as total_tokens,
 		COALESCE(SUM(cost), 0) as total_cost,
 		COUNT(*) as session_count
-		FROM sessions WHERE source = ? AND started_at >= ?`
+		FROM sessions WHERE source = ? AND started_at >= ? AND started_at < ?`
+	filter, args := projectFilter("project_path", project)
+	query += filter
 
 	var stats AggregatedStats
-	err := db.db.QueryRowContext(ctx, query, source, since).Scan(
+	err := db.db.QueryRowContext(ctx, query, append([]any{source, since, until}, args...)...).Scan(
 		&stats.TotalSessionTime,
 		&stats.TotalInputTokens,
 		&stats.TotalOutputTokens,
@@ -362,14 +673,16 @@ func (db *DB) GetAggregatedStats(ctx context.Context, source string, since int64
 }
 
 // GetMessageCount returns the total message count for sessions in the period
-func (db *DB) GetMessageCount(ctx context.Context, source string, since int64) (int64, error) {
+func (db *DB) GetMessageCount(ctx context.Context, source string, since, until int64, project string) (int64, error) {
 	query := `SELECT COALESCE(COUNT(m.id), 0)
 		FROM messages m
 		JOIN sessions s ON m.session_id = s.id
-		WHERE s.source = ? AND s.started_at >= ?`
+		WHERE s.source = ? AND s.started_at >= ? AND s.started_at < ?`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter
 
 	var count int64
-	err := db.db.QueryRowContext(ctx, query, source, since).Scan(&count)
+	err := db.db.QueryRowContext(ctx, query, append([]any{source, since, until}, args...)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get message count: %w", err)
 	}
@@ -377,14 +690,16 @@ func (db *DB) GetMessageCount(ctx context.Context, source string, since int64) (
 }
 
 // GetMessageCountAll returns the total message count for all sessions in the period
-func (db *DB) GetMessageCountAll(ctx context.Context, since int64) (int64, error) {
+func (db *DB) GetMessageCountAll(ctx context.Context, since, until int64, project string) (int64, error) {
 	query := `SELECT COALESCE(COUNT(m.id), 0)
 		FROM messages m
 		JOIN sessions s ON m.session_id = s.id
-		WHERE s.started_at >= ?`
+		WHERE s.started_at >= ? AND s.started_at < ?`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter
 
 	var count int64
-	err := db.db.QueryRowContext(ctx, query, since).Scan(&count)
+	err := db.db.QueryRowContext(ctx, query, append([]any{since, until}, args...)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get message count: %w", err)
 	}
@@ -404,14 +719,16 @@ func (db *DB) GetMessageCountBySessionID(ctx context.Context, sessionID int64) (
 }
 
 // GetToolCallCount returns the total tool call count for sessions in the period
-func (db *DB) GetToolCallCount(ctx context.Context, source string, since int64) (int64, error) {
+func (db *DB) GetToolCallCount(ctx context.Context, source string, since, until int64, project string) (int64, error) {
 	query := `SELECT COALESCE(COUNT(t.id), 0)
 		FROM tool_calls t
 		JOIN sessions s ON t.session_id = s.id
-		WHERE s.source = ? AND s.started_at >= ?`
+		WHERE s.source = ? AND s.started_at >= ? AND s.started_at < ?`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter
 
 	var count int64
-	err := db.db.QueryRowContext(ctx, query, source, since).Scan(&count)
+	err := db.db.QueryRowContext(ctx, query, append([]any{source, since, until}, args...)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get tool call count: %w", err)
 	}
@@ -419,14 +736,16 @@ func (db *DB) GetToolCallCount(ctx context.Context, source string, since int64)
 }
 
 // GetToolCallCountAll returns the total tool call count for all sessions in the period
-func (db *DB) GetToolCallCountAll(ctx context.Context, since int64) (int64, error) {
+func (db *DB) GetToolCallCountAll(ctx context.Context, since, until int64, project string) (int64, error) {
 	query := `SELECT COALESCE(COUNT(t.id), 0)
 		FROM tool_calls t
 		JOIN sessions s ON t.session_id = s.id
-		WHERE s.started_at >= ?`
+		WHERE s.started_at >= ? AND s.started_at < ?`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter
 
 	var count int64
-	err := db.db.QueryRowContext(ctx, query, since).Scan(&count)
+	err := db.db.QueryRowContext(ctx, query, append([]any{since, until}, args...)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get tool call count: %w", err)
 	}
@@ -434,12 +753,14 @@ func (db *DB) GetToolCallCountAll(ctx context.Context, since int64) (int64, erro
 }
 
 // GetUniqueProjects returns the count of unique projects in the period
-func (db *DB) GetUniqueProjects(ctx context.Context, source string, since int64) (int64, error) {
+func (db *DB) GetUniqueProjects(ctx context.Context, source string, since, until int64, project string) (int64, error) {
 	query := `SELECT COALESCE(COUNT(DISTINCT project_path), 0)
-		FROM sessions WHERE source = ? AND started_at >= ? AND project_path IS NOT NULL`
+		FROM sessions WHERE source = ? AND started_at >= ? AND started_at < ? AND project_path IS NOT NULL`
+	filter, args := projectFilter("project_path", project)
+	query += filter
 
 	var count int64
-	err := db.db.QueryRowContext(ctx, query, source, since).Scan(&count)
+	err := db.db.QueryRowContext(ctx, query, append([]any{source, since, until}, args...)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get unique projects: %w", err)
 	}
@@ -447,12 +768,14 @@ func (db *DB) GetUniqueProjects(ctx context.Context, source string, since int64)
 }
 
 // GetSessionsInPeriod returns all sessions within a time period for debug
-func (db *DB) GetSessionsInPeriod(ctx context.Context, source string, since int64) ([]SessionRow, error) {
+func (db *DB) GetSessionsInPeriod(ctx context.Context, source string, since, until int64, project string) ([]SessionRow, error) {
 	query := `SELECT s.id, s.external_id, s.source, s.project_path, s.model, s.provider, s.started_at, s.ended_at,
 		s.input_tokens, s.output_tokens, s.cache_creation_tokens, s.cache_read_tokens, s.reasoning_tokens, s.total_tokens, ` + messageCountSubquery + `, s.cost
-		FROM sessions s WHERE s.source = ? AND s.started_at >= ? ORDER BY s.started_at DESC`
+		FROM sessions s WHERE s.source = ? AND s.started_at >= ? AND s.started_at < ?`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter + ` ORDER BY s.started_at DESC`
 
-	rows, err := db.db.QueryContext(ctx, query, source, since)
+	rows, err := db.db.QueryContext(ctx, query, append([]any{source, since, until}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query sessions: %w", err)
 	}
@@ -480,92 +803,124 @@ func (db *DB) GetSessionsInPeriod(ctx context.Context, source string, since int6
 
 // DailySummary represents daily aggregated statistics
 type DailySummary struct {
-	Date         string
-	SessionCount int64
-	TotalTime    int64
-	TotalTokens  int64
+	Date         string  `json:"date"`
+	SessionCount int64   `json:"session_count"`
+	TotalTime    int64   `json:"total_time"`
+	TotalTokens  int64   `json:"total_tokens"`
+	TotalCost    float64 `json:"total_cost"`
 }
 
-// GetDailySummaries returns daily summaries for a time period (used for weekly period)
-func (db *DB) GetDailySummaries(ctx context.Context, source string, since int64) ([]DailySummary, error) {
-	query := `SELECT date(started_at, 'unixepoch') as day,
-		COUNT(*) as sessions,
-		COALESCE(SUM(CASE WHEN ended_at IS NOT NULL AND ended_at > started_at THEN ended_at - started_at ELSE 0 END), 0) as total_time,
-		COALESCE(SUM(total_tokens), 0) as total_tokens
-		FROM sessions
-		WHERE source = ? AND started_at >= ?
-		GROUP BY day
-		ORDER BY day DESC`
-
-	rows, err := db.db.QueryContext(ctx, query, source, since)
+// GetDailySummaries returns per-day summaries, with days taken in the calendar's time zone
+func (db *DB) GetDailySummaries(ctx context.Context, source string, since, until int64, project string, cal Calendar) ([]DailySummary, error) {
+	totals, err := db.getSessionTotals(ctx, source, since, until, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query daily summaries: %w", err)
 	}
-	defer rows.Close()
 
 	var summaries []DailySummary
-	for rows.Next() {
-		var s DailySummary
-		if err := rows.Scan(&s.Date, &s.SessionCount, &s.TotalTime, &s.TotalTokens); err != nil {
-			return nil, fmt.Errorf("failed to scan daily summary: %w", err)
+	index := make(map[string]int)
+	for _, t := range totals {
+		day := cal.DayStart(time.Unix(t.StartedAt, 0)).Format("2006-01-02")
+		i, ok := index[day]
+		if !ok {
+			i = len(summaries)
+			index[day] = i
+			summaries = append(summaries, DailySummary{Date: day})
 		}
-		summaries = append(summaries, s)
+		summaries[i].SessionCount++
+		summaries[i].TotalTime += t.Duration
+		summaries[i].TotalTokens += t.TotalTokens
+		summaries[i].TotalCost += t.Cost
 	}
-	return summaries, rows.Err()
+	return summaries, nil
 }
 
 // WeeklySummary represents weekly aggregated statistics
 type WeeklySummary struct {
-	WeekStart    string
-	SessionCount int64
-	TotalTime    int64
-	TotalTokens  int64
+	WeekStart    string  `json:"week_start"`
+	SessionCount int64   `json:"session_count"`
+	TotalTime    int64   `json:"total_time"`
+	TotalTokens  int64   `json:"total_tokens"`
+	TotalCost    float64 `json:"total_cost"`
 }
 
 // PerAgentStats represents per-agent statistics
 type PerAgentStats struct {
-	Source             string
-	SessionCount       int64
-	TotalInputTokens   int64
-	TotalOutputTokens  int64
-	TotalCacheCreation int64
-	TotalCacheRead     int64
-	TotalTokens        int64
-	TotalCost          float64
-	TotalTime          int64
-	TotalMessages      int64
+	Source             string  `json:"source"`
+	SessionCount       int64   `json:"session_count"`
+	TotalInputTokens   int64   `json:"total_input_tokens"`
+	TotalOutputTokens  int64   `json:"total_output_tokens"`
+	TotalCacheCreation int64   `json:"total_cache_creation"`
+	TotalCacheRead     int64   `json:"total_cache_read"`
+	TotalTokens        int64   `json:"total_tokens"`
+	TotalCost          float64 `json:"total_cost"`
+	TotalTime          int64   `json:"total_time"`
+	TotalMessages      int64   `json:"total_messages"`
 }
 
-// GetWeeklySummaries returns weekly summaries for a time period (used for monthly period)
-func (db *DB) GetWeeklySummaries(ctx context.Context, source string, since int64) ([]WeeklySummary, error) {
-	query := `SELECT strftime('%Y/%m/%d', datetime(min(started_at), 'unixepoch')) as week_start,
-		COUNT(*) as sessions,
-		COALESCE(SUM(CASE WHEN ended_at IS NOT NULL AND ended_at > started_at THEN ended_at - started_at ELSE 0 END), 0) as total_time,
-		COALESCE(SUM(total_tokens), 0) as total_tokens
+// GetWeeklySummaries returns per-week summaries, with weeks starting on the
+// calendar's week start day in its time zone
+func (db *DB) GetWeeklySummaries(ctx context.Context, source string, since, until int64, project string, cal Calendar) ([]WeeklySummary, error) {
+	totals, err := db.getSessionTotals(ctx, source, since, until, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weekly summaries: %w", err)
+	}
+
+	var summaries []WeeklySummary
+	index := make(map[string]int)
+	for _, t := range totals {
+		week := cal.WeekStart(time.Unix(t.StartedAt, 0)).Format("2006/01/02")
+		i, ok := index[week]
+		if !ok {
+			i = len(summaries)
+			index[week] = i
+			summaries = append(summaries, WeeklySummary{WeekStart: week})
+		}
+		summaries[i].SessionCount++
+		summaries[i].TotalTime += t.Duration
+		summaries[i].TotalTokens += t.TotalTokens
+		summaries[i].TotalCost += t.Cost
+	}
+	return summaries, nil
+}
+
+// sessionTotals holds the per-session values summed into daily and weekly summaries
+type sessionTotals struct {
+	StartedAt   int64
+	Duration    int64
+	TotalTokens int64
+	Cost        float64
+}
+
+// getSessionTotals returns the totals of each session in the range, newest first
+func (db *DB) getSessionTotals(ctx context.Context, source string, since, until int64, project string) ([]sessionTotals, error) {
+	query := `SELECT started_at,
+		CASE WHEN ended_at IS NOT NULL AND ended_at > started_at THEN ended_at - started_at ELSE 0 END,
+		COALESCE(total_tokens, 0), COALESCE(cost, 0)
 		FROM sessions
-		WHERE source = ? AND started_at >= ?
-		GROUP BY strftime('%Y-W%W', started_at, 'unixepoch')
-		ORDER BY week_start DESC`
+		WHERE source = ? AND started_at >= ? AND started_at < ?`
+	filter, args := projectFilter("project_path", project)
+	query += filter + ` ORDER BY started_at DESC`
 
-	rows, err := db.db.QueryContext(ctx, query, source, since)
+	rows, err := db.db.QueryContext(ctx, query, append([]any{source, since, until}, args...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query weekly summaries: %w", err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	var summaries []WeeklySummary
+	var totals []sessionTotals
 	for rows.Next() {
-		var s WeeklySummary
-		if err := rows.Scan(&s.WeekStart, &s.SessionCount, &s.TotalTime, &s.TotalTokens); err != nil {
-			return nil, fmt.Errorf("failed to scan weekly summary: %w", err)
+		var t sessionTotals
+		if err := rows.Scan(&t.StartedAt, &t.Duration, &t.TotalTokens, &t.Cost); err != nil {
+			return nil, err
 		}
-		summaries = append(summaries, s)
+		totals = append(totals, t)
 	}
-	return summaries, rows.Err()
+	return totals, rows.Err()
 }
 
 // GetAggregatedStatsAll returns aggregated stats for all sources
-func (db *DB) GetAggregatedStatsAll(ctx context.Context, since int64) (*AggregatedStats, error) {
+func (db *DB) GetAggregatedStatsAll(ctx context.Context, since, until int64, project string) (*AggregatedStats, error) {
 	query := `SELECT
 		COALESCE(SUM(CASE WHEN ended_at IS NOT NULL AND ended_at > started_at THEN ended_at - started_at ELSE 0 END), 0) as total_time,
 		COALESCE(SUM(input_tokens), 0) as total_input,
@@ -575,10 +930,12 @@ func (db *DB) GetAggregatedStatsAll(ctx context.Context, since int64) (*Aggregat
 		COALESCE(SUM(total_tokens), 0) as total_tokens,
 		COALESCE(SUM(cost), 0) as total_cost,
 		COUNT(*) as session_count
-		FROM sessions WHERE started_at >= ?`
+		FROM sessions WHERE started_at >= ? AND started_at < ?`
+	filter, args := projectFilter("project_path", project)
+	query += filter
 
 	var stats AggregatedStats
-	err := db.db.QueryRowContext(ctx, query, since).Scan(
+	err := db.db.QueryRowContext(ctx, query, append([]any{since, until}, args...)...).Scan(
 		&stats.TotalSessionTime,
 		&stats.TotalInputTokens,
 		&stats.TotalOutputTokens,
@@ -595,7 +952,7 @@ func (db *DB) GetAggregatedStatsAll(ctx context.Context, since int64) (*Aggregat
 }
 
 // GetPerAgentStats returns stats grouped by source
-func (db *DB) GetPerAgentStats(ctx context.Context, since int64) ([]PerAgentStats, error) {
+func (db *DB) GetPerAgentStats(ctx context.Context, since, until int64, project string) ([]PerAgentStats, error) {
 	query := `SELECT s.source,
 		COUNT(*) as session_count,
 		COALESCE(SUM(s.input_tokens), 0) as total_input,
@@ -610,10 +967,11 @@ func (db *DB) GetPerAgentStats(ctx context.Context, since int64) ([]PerAgentStat
 		LEFT JOIN (
 			SELECT session_id, COUNT(*) as message_count FROM messages GROUP BY session_id
 		) m ON m.session_id = s.id
-		WHERE s.started_at >= ?
-		GROUP BY s.source ORDER BY session_count DESC`
+		WHERE s.started_at >= ? AND s.started_at < ?`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter + ` GROUP BY s.source ORDER BY session_count DESC`
 
-	rows, err := db.db.QueryContext(ctx, query, since)
+	rows, err := db.db.QueryContext(ctx, query, append([]any{since, until}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query per-agent stats: %w", err)
 	}
@@ -630,49 +988,63 @@ func (db *DB) GetPerAgentStats(ctx context.Context, since int64) ([]PerAgentStat
 	return stats, rows.Err()
 }
 
-// GetTopModelsAll returns top models across all sources
-func (db *DB) GetTopModelsAll(ctx context.Context, since int64, limit int) ([]ModelUsage, error) {
-	query := `SELECT model, COUNT(*) as session_count
-		FROM sessions WHERE started_at >= ? AND model IS NOT NULL AND model != ''
-		GROUP BY model ORDER BY session_count DESC LIMIT ?`
+// GetTopModelsAll returns top models across all sources, split like GetTopModels
+func (db *DB) GetTopModelsAll(ctx context.Context, since, until int64, project string, limit int, sort SortOrder) ([]ModelUsage, error) {
+	query := `SELECT m.model, COUNT(*) as session_count, COALESCE(SUM(m.total_tokens), 0), COALESCE(SUM(m.cost), 0) as total_cost
+		FROM session_models m JOIN sessions s ON s.id = m.session_id
+		WHERE s.started_at >= ? AND s.started_at < ? AND m.model != ''`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter + ` GROUP BY m.model ORDER BY ` + sort.orderBy() + ` LIMIT ?`
 
-	rows, err := db.db.QueryContext(ctx, query, since, limit)
+	args = append([]any{since, until}, args...)
+	rows, err := db.db.QueryContext(ctx, query, append(args, limit)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query top models: %w", err)
 	}
 	defer rows.Close()
+	return scanModelUsage(rows)
+}
 
-	var models []ModelUsage
-	for rows.Next() {
-		var m ModelUsage
-		if err := rows.Scan(&m.Model, &m.SessionCount); err != nil {
-			return nil, fmt.Errorf("failed to scan model: %w", err)
-		}
-		models = append(models, m)
+// GetTopProjectsAll returns top projects across all sources
+func (db *DB) GetTopProjectsAll(ctx context.Context, since, until int64, project string, limit int, sort SortOrder) ([]ProjectUsage, error) {
+	query := `SELECT project_path, COUNT(*) as session_count, COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost), 0) as total_cost
+		FROM sessions WHERE started_at >= ? AND started_at < ? AND project_path IS NOT NULL AND project_path != ''`
+	filter, args := projectFilter("project_path", project)
+	query += filter + ` GROUP BY project_path ORDER BY ` + sort.orderBy() + ` LIMIT ?`
+
+	args = append([]any{since, until}, args...)
+	rows, err := db.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top projects: %w", err)
 	}
-	return models, rows.Err()
+	defer rows.Close()
+	return scanProjectUsage(rows)
 }
 
 // GetUniqueProjectsAll returns unique projects across all agents
-func (db *DB) GetUniqueProjectsAll(ctx context.Context, since int64) (int64, error) {
+func (db *DB) GetUniqueProjectsAll(ctx context.Context, since, until int64, project string) (int64, error) {
 	query := `SELECT COALESCE(COUNT(DISTINCT project_path), 0)
-		FROM sessions WHERE started_at >= ? AND project_path IS NOT NULL`
+		FROM sessions WHERE started_at >= ? AND started_at < ? AND project_path IS NOT NULL`
+	filter, args := projectFilter("project_path", project)
+	query += filter
 
 	var count int64
-	err := db.db.QueryRowContext(ctx, query, since).Scan(&count)
+	err := db.db.QueryRowContext(ctx, query, append([]any{since, until}, args...)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get unique projects: %w", err)
 	}
 	return count, nil
 }
 
-// GetRecentSessions returns the most recent N sessions ordered by start time descending
-func (db *DB) GetRecentSessions(ctx context.Context, limit int) ([]SessionRow, error) {
+// GetRecentSessions returns the most recent N sessions of matching projects ordered by start time descending
+func (db *DB) GetRecentSessions(ctx context.Context, project string, limit int) ([]SessionRow, error) {
 	query := `SELECT s.id, s.external_id, s.source, s.project_path, s.model, s.provider, s.started_at, s.ended_at,
 		s.input_tokens, s.output_tokens, s.cache_creation_tokens, s.cache_read_tokens, s.reasoning_tokens, s.total_tokens, ` + messageCountSubquery + `, s.cost
-		FROM sessions s ORDER BY s.started_at DESC LIMIT ?`
+		FROM sessions s WHERE 1 = 1`
+	filter, args := projectFilter("s.project_path", project)
+	query += filter + ` ORDER BY s.started_at DESC LIMIT ?`
 
-	rows, err := db.db.QueryContext(ctx, query, limit)
+	rows, err := db.db.QueryContext(ctx, query, append(args, limit)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query recent sessions: %w", err)
 	}
@@ -721,3 +1093,197 @@ func (db *DB) GetLastSyncTime(ctx context.Context, agent string) (int64, error)
 	}
 	return timestamp, nil
 }
+
+// AddSyncError counts a session file of an agent that failed to sync
+func (db *DB) AddSyncError(ctx context.Context, agent string) error {
+	query := `INSERT INTO metadata (key, value, updated_at) VALUES (?, '1', ?)
+		ON CONFLICT(key) DO UPDATE SET value = CAST(value AS INTEGER) + 1, updated_at = excluded.updated_at`
+	if _, err := db.db.ExecContext(ctx, query, "sync_errors_"+agent, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to record sync error: %w", err)
+	}
+	return nil
+}
+
+// GetSyncErrors returns the number of session files of an agent that failed to sync
+func (db *DB) GetSyncErrors(ctx context.Context, agent string) (int64, error) {
+	var count int64
+	err := db.db.QueryRowContext(ctx, `SELECT CAST(value AS INTEGER) FROM metadata WHERE key = ?`, "sync_errors_"+agent).Scan(&count)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get sync errors: %w", err)
+	}
+	return count, nil
+}
+
+// ClaimMetadata stores value under key unless the key is already set, and
+// reports whether it was stored. Concurrent claims of a key succeed once.
+func (db *DB) ClaimMetadata(ctx context.Context, key, value string) (bool, error) {
+	query := `INSERT OR IGNORE INTO metadata (key, value, updated_at) VALUES (?, ?, ?)`
+	res, err := db.db.ExecContext(ctx, query, key, value, time.Now().Unix())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim metadata key: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim metadata key: %w", err)
+	}
+	return n == 1, nil
+}
+
+// GetMetadata returns the value stored under key, or "" if it is not set
+func (db *DB) GetMetadata(ctx context.Context, key string) (string, error) {
+	var value string
+	err := db.db.QueryRowContext(ctx, `SELECT value FROM metadata WHERE key = ?`, key).Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get metadata: %w", err)
+	}
+	return value, nil
+}
+
+// DeleteMetadata removes key
+func (db *DB) DeleteMetadata(ctx context.Context, key string) error {
+	if _, err := db.db.ExecContext(ctx, `DELETE FROM metadata WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("failed to delete metadata: %w", err)
+	}
+	return nil
+}
+
+// ModelTotals is the all-time token usage and cost of one agent and model
+type ModelTotals struct {
+	Source              string
+	Model               string
+	ProjectPath         string // empty unless grouped by project
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+	ReasoningTokens     int64
+	Cost                float64
+}
+
+// GetModelTotals returns the token usage and cost of every agent and model
+// across all sessions, split by project if byProject is set
+func (db *DB) GetModelTotals(ctx context.Context, byProject bool) ([]ModelTotals, error) {
+	project := `''`
+	if byProject {
+		project = `COALESCE(s.project_path, '')`
+	}
+	query := `SELECT s.source, m.model, ` + project + ` as project,
+		COALESCE(SUM(m.input_tokens), 0), COALESCE(SUM(m.output_tokens), 0),
+		COALESCE(SUM(m.cache_creation_tokens), 0), COALESCE(SUM(m.cache_read_tokens), 0),
+		COALESCE(SUM(m.reasoning_tokens), 0), COALESCE(SUM(m.cost), 0)
+		FROM session_models m
+		JOIN sessions s ON s.id = m.session_id
+		GROUP BY s.source, m.model, project
+		ORDER BY s.source, m.model, project`
+
+	rows, err := db.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query model totals: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []ModelTotals
+	for rows.Next() {
+		var t ModelTotals
+		if err := rows.Scan(&t.Source, &t.Model, &t.ProjectPath, &t.InputTokens, &t.OutputTokens,
+			&t.CacheCreationTokens, &t.CacheReadTokens, &t.ReasoningTokens, &t.Cost); err != nil {
+			return nil, fmt.Errorf("failed to scan model totals: %w", err)
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// AgentTotals is the all-time number of sessions, messages and tool calls of one agent
+type AgentTotals struct {
+	Source         string
+	ProjectPath    string // empty unless grouped by project
+	Sessions       int64
+	Messages       int64
+	ToolCalls      int64
+	ToolCallErrors int64
+}
+
+// GetAgentTotals returns the session, message and tool call counts of every
+// agent across all sessions, split by project if byProject is set
+func (db *DB) GetAgentTotals(ctx context.Context, byProject bool) ([]AgentTotals, error) {
+	project := `''`
+	if byProject {
+		project = `COALESCE(s.project_path, '')`
+	}
+	query := `SELECT s.source, ` + project + ` as project, COUNT(*),
+		COALESCE(SUM(m.message_count), 0), COALESCE(SUM(t.call_count), 0), COALESCE(SUM(t.error_count), 0)
+		FROM sessions s
+		LEFT JOIN (
+			SELECT session_id, COUNT(*) as message_count FROM messages GROUP BY session_id
+		) m ON m.session_id = s.id
+		LEFT JOIN (
+			SELECT session_id, COUNT(*) as call_count, SUM(COALESCE(is_error, 0)) as error_count
+			FROM tool_calls GROUP BY session_id
+		) t ON t.session_id = s.id
+		GROUP BY s.source, project
+		ORDER BY s.source, project`
+
+	rows, err := db.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent totals: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []AgentTotals
+	for rows.Next() {
+		var t AgentTotals
+		if err := rows.Scan(&t.Source, &t.ProjectPath, &t.Sessions, &t.Messages, &t.ToolCalls, &t.ToolCallErrors); err != nil {
+			return nil, fmt.Errorf("failed to scan agent totals: %w", err)
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// SyncFileRow records how far a session file has been synced
+type SyncFileRow struct {
+	Path        string
+	Source      string
+	Inode       uint64
+	Size        int64
+	ModTime     int64 // Unix nanoseconds
+	Offset      int64 // bytes consumed by the parser
+	ParserState string
+	ResumeHash  string // hash of the head of the file and the bytes before Offset
+	SessionID   string // external ID of the session parsed from the file
+	SyncedAt    int64
+}
+
+// GetSyncFile returns the sync state of a file, or nil if it was never synced
+func (db *DB) GetSyncFile(ctx context.Context, path string) (*SyncFileRow, error) {
+	query := `SELECT path, source, inode, size, mtime, byte_offset, COALESCE(parser_state, ''), COALESCE(resume_hash, ''),
+		COALESCE(session_id, ''), COALESCE(synced_at, 0)
+		FROM sync_files WHERE path = ?`
+
+	var f SyncFileRow
+	var inode int64
+	err := db.db.QueryRowContext(ctx, query, path).Scan(
+		&f.Path, &f.Source, &inode, &f.Size, &f.ModTime, &f.Offset, &f.ParserState, &f.ResumeHash, &f.SessionID, &f.SyncedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get sync file: %w", err)
+	}
+	f.Inode = uint64(inode)
+	return &f, nil
+}
+
+// UpsertSyncFile stores the sync state of a file
+func (db *DB) UpsertSyncFile(ctx context.Context, f *SyncFileRow) error {
+	query := `INSERT OR REPLACE INTO sync_files (path, source, inode, size, mtime, byte_offset, parser_state, resume_hash, session_id, synced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.db.ExecContext(ctx, query,
+		f.Path, f.Source, int64(f.Inode), f.Size, f.ModTime, f.Offset, f.ParserState, f.ResumeHash, f.SessionID, f.SyncedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert sync file: %w", err)
+	}
+	return nil
+}
@@ -67,7 +67,7 @@ func TestGetRecentSessions(t *testing.T) {
 	}
 
 	// Test GetRecentSessions with limit 2
-	recent, err := db.GetRecentSessions(ctx, 2)
+	recent, err := db.GetRecentSessions(ctx, "", 2)
 	if err != nil {
 		t.Fatalf("GetRecentSessions() error = %v", err)
 	}
@@ -97,7 +97,7 @@ func TestGetRecentSessionsEmpty(t *testing.T) {
 
 	ctx := context.Background()
 
-	recent, err := db.GetRecentSessions(ctx, 5)
+	recent, err := db.GetRecentSessions(ctx, "", 5)
 	if err != nil {
 		t.Fatalf("GetRecentSessions() error = %v", err)
 	}
@@ -107,6 +107,24 @@ func TestGetRecentSessionsEmpty(t *testing.T) {
 	}
 }
 
+// insertSingleModelSession stores a session and its usage as one model, as
+// sync does for sessions that never switch models
+func insertSingleModelSession(t *testing.T, db *DB, s SessionRow) {
+	t.Helper()
+	ctx := context.Background()
+	id, err := db.InsertSession(ctx, &s)
+	if err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+	if s.Model == "" {
+		return
+	}
+	model := SessionModelRow{Model: s.Model, TotalTokens: s.TotalTokens, Cost: s.Cost}
+	if err := db.ReplaceSessionModels(ctx, id, []SessionModelRow{model}); err != nil {
+		t.Fatalf("Failed to insert session models: %v", err)
+	}
+}
+
 func TestGetTopModelsExcludesEmpty(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -132,14 +150,11 @@ func TestGetTopModelsExcludesEmpty(t *testing.T) {
 	}
 
 	for _, s := range sessions {
-		_, err := db.InsertSession(ctx, &s)
-		if err != nil {
-			t.Fatalf("Failed to insert session: %v", err)
-		}
+		insertSingleModelSession(t, db, s)
 	}
 
 	// Get top models - should exclude empty models
-	topModels, err := db.GetTopModels(ctx, "claude", now-86400, 10)
+	topModels, err := db.GetTopModels(ctx, "claude", now-86400, now+1, "", 10, SortSessions)
 	if err != nil {
 		t.Fatalf("GetTopModels() error = %v", err)
 	}
@@ -193,14 +208,11 @@ func TestGetTopModelsAllExcludesEmpty(t *testing.T) {
 	}
 
 	for _, s := range sessions {
-		_, err := db.InsertSession(ctx, &s)
-		if err != nil {
-			t.Fatalf("Failed to insert session: %v", err)
-		}
+		insertSingleModelSession(t, db, s)
 	}
 
 	// Get top models across all sources
-	topModels, err := db.GetTopModelsAll(ctx, now-86400, 10)
+	topModels, err := db.GetTopModelsAll(ctx, now-86400, now+1, "", 10, SortSessions)
 	if err != nil {
 		t.Fatalf("GetTopModelsAll() error = %v", err)
 	}
@@ -213,6 +225,64 @@ func TestGetTopModelsAllExcludesEmpty(t *testing.T) {
 	}
 }
 
+func TestGetTopModelsAndProjectsSortByCost(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	now := time.Now().Unix()
+
+	// The cheap model and project have more sessions, the expensive ones more spend
+	sessions := []SessionRow{
+		{ExternalID: "s1", Source: "claude", Model: "claude-haiku-4-5", ProjectPath: "/test/cheap", StartedAt: now - 3600, Cost: 0.10},
+		{ExternalID: "s2", Source: "claude", Model: "claude-haiku-4-5", ProjectPath: "/test/cheap", StartedAt: now - 3600, Cost: 0.10},
+		{ExternalID: "s3", Source: "codex", Model: "claude-opus-4-5", ProjectPath: "/test/pricey", StartedAt: now - 3600, Cost: 5.00},
+	}
+
+	for _, s := range sessions {
+		insertSingleModelSession(t, db, s)
+	}
+
+	topModels, err := db.GetTopModelsAll(ctx, now-86400, now+1, "", 10, SortSessions)
+	if err != nil {
+		t.Fatalf("GetTopModelsAll() error = %v", err)
+	}
+	if len(topModels) != 2 || topModels[0].Model != "claude-haiku-4-5" {
+		t.Errorf("Expected haiku first by sessions, got %+v", topModels)
+	}
+
+	topModels, err = db.GetTopModelsAll(ctx, now-86400, now+1, "", 10, SortCost)
+	if err != nil {
+		t.Fatalf("GetTopModelsAll() error = %v", err)
+	}
+	if len(topModels) != 2 || topModels[0].Model != "claude-opus-4-5" || topModels[0].TotalCost != 5.00 {
+		t.Errorf("Expected opus first by cost, got %+v", topModels)
+	}
+
+	topProjects, err := db.GetTopProjects(ctx, "claude", now-86400, now+1, "", 10, SortCost)
+	if err != nil {
+		t.Fatalf("GetTopProjects() error = %v", err)
+	}
+	if len(topProjects) != 1 || topProjects[0].ProjectPath != "/test/cheap" || topProjects[0].SessionCount != 2 {
+		t.Errorf("Expected only the claude project, got %+v", topProjects)
+	}
+
+	topProjects, err = db.GetTopProjectsAll(ctx, now-86400, now+1, "", 10, SortCost)
+	if err != nil {
+		t.Fatalf("GetTopProjectsAll() error = %v", err)
+	}
+	if len(topProjects) != 2 || topProjects[0].ProjectPath != "/test/pricey" {
+		t.Errorf("Expected pricey project first by cost, got %+v", topProjects)
+	}
+}
+
 func TestParseClaudeSessionTokenTotal(t *testing.T) {
 	// Create a temporary session file with tokens
 	tmpDir := t.TempDir()
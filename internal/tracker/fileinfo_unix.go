@@ -0,0 +1,16 @@
+//go:build !windows
+
+package tracker
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns the inode number of a file, used to detect replaced files
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
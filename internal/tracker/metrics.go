@@ -0,0 +1,34 @@
+package tracker
+
+import "context"
+
+// Metrics is the all-time usage published by the metrics exporter
+type Metrics struct {
+	Models     []ModelTotals
+	Agents     []AgentTotals
+	LastSync   map[string]int64 // Unix timestamp of the last sync of each registered agent, 0 if never
+	SyncErrors map[string]int64 // session files of each registered agent that failed to sync
+}
+
+// GetMetrics returns the totals of every agent and model, split by project if byProject is set
+func (t *SQLiteTracker) GetMetrics(ctx context.Context, byProject bool) (*Metrics, error) {
+	models, err := t.db.GetModelTotals(ctx, byProject)
+	if err != nil {
+		return nil, err
+	}
+	agents, err := t.db.GetAgentTotals(ctx, byProject)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Metrics{Models: models, Agents: agents, LastSync: make(map[string]int64), SyncErrors: make(map[string]int64)}
+	for _, name := range ProviderNames() {
+		if m.LastSync[name], err = t.db.GetLastSyncTime(ctx, name); err != nil {
+			return nil, err
+		}
+		if m.SyncErrors[name], err = t.db.GetSyncErrors(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,403 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer version of agent-usage
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of agent-usage supports")
+
+// migration is a numbered schema change, applied once in its own transaction.
+// Databases created before schema_migrations existed already have some of the
+// changes, so migrations must tolerate objects that exist.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *DB) error
+}
+
+// migrations is the ordered list of schema changes; versions start at 1 and
+// have no gaps. Never edit or reorder an applied migration, append a new one.
+var migrations = []migration{
+	{1, "create sessions, messages, tool_calls and metadata", migrateInitialSchema},
+	{2, "add session metadata and fingerprint", migrateSessionFingerprint},
+	{3, "create sync_files", migrateSyncFiles},
+	{4, "create messages_fts full-text index", migrateMessagesFTS},
+	{5, "create turns", migrateTurns},
+	{6, "create usage_keys for de-duplicating usage", migrateUsageKeys},
+	{7, "create session_models", migrateSessionModels},
+	{8, "add tool call IDs, exit codes, errors and durations", migrateToolCallResults},
+	{9, "resync sessions to store Claude tool calls", resetSync},
+	{10, "add sync_files.resume_hash", migrateResumeHash},
+	{11, "add usage key timestamps and turn usage keys", migrateUsageKeyOwners},
+}
+
+// MigrationStatus describes a migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	AppliedAt int64  `json:"applied_at"` // Unix timestamp, 0 if pending
+}
+
+// Applied reports whether the migration has been applied
+func (m MigrationStatus) Applied() bool {
+	return m.AppliedAt != 0
+}
+
+// LatestSchemaVersion returns the schema version this binary migrates to
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// ensureMigrationsTable creates the schema_migrations table if it doesn't exist
+func (db *DB) ensureMigrationsTable(ctx context.Context) error {
+	_, err := db.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations returns the applied_at time of every applied migration by version
+func (db *DB) appliedMigrations(ctx context.Context) (map[int]int64, error) {
+	rows, err := db.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]int64)
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// checkSchemaVersion fails with ErrSchemaTooNew if a migration unknown to this binary was applied
+func checkSchemaVersion(applied map[int]int64) error {
+	latest := LatestSchemaVersion()
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: database is at version %d, this binary supports up to %d; upgrade agent-usage",
+				ErrSchemaTooNew, version, latest)
+		}
+	}
+	return nil
+}
+
+// MigrationStatus returns every known migration, in order, with its applied time
+func (db *DB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(applied); err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		status[i] = MigrationStatus{Version: m.version, Name: m.name, AppliedAt: applied[m.version]}
+	}
+	return status, nil
+}
+
+// Migrate applies all pending migrations in order, each in its own transaction,
+// and returns the ones it applied
+func (db *DB) Migrate(ctx context.Context) ([]MigrationStatus, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(applied); err != nil {
+		return nil, err
+	}
+
+	var done []MigrationStatus
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		now := time.Now().Unix()
+		err := db.WithTx(ctx, func(tx *DB) error {
+			if err := m.up(ctx, tx); err != nil {
+				return err
+			}
+			_, err := tx.db.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				m.version, m.name, now)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		done = append(done, MigrationStatus{Version: m.version, Name: m.name, AppliedAt: now})
+	}
+	return done, nil
+}
+
+// addColumn adds a column to a table unless a database created before
+// versioned migrations already has it
+func (db *DB) addColumn(ctx context.Context, table, column, definition string) error {
+	var exists int
+	err := db.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", table, err)
+	}
+	if exists > 0 {
+		return nil
+	}
+	if _, err := db.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+func migrateInitialSchema(ctx context.Context, db *DB) error {
+	_, err := db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		external_id TEXT UNIQUE,
+		source TEXT NOT NULL,
+		project_path TEXT,
+		model TEXT,
+		provider TEXT,
+		started_at INTEGER NOT NULL,
+		ended_at INTEGER,
+		input_tokens INTEGER DEFAULT 0,
+		output_tokens INTEGER DEFAULT 0,
+		cache_creation_tokens INTEGER DEFAULT 0,
+		cache_read_tokens INTEGER DEFAULT 0,
+		total_tokens INTEGER DEFAULT 0,
+		cost REAL DEFAULT 0,
+		reasoning_tokens INTEGER DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL,
+		role TEXT NOT NULL,
+		content TEXT,
+		timestamp INTEGER NOT NULL,
+		FOREIGN KEY (session_id) REFERENCES sessions(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS tool_calls (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL,
+		tool_name TEXT NOT NULL,
+		arguments TEXT,
+		result TEXT,
+		timestamp INTEGER NOT NULL,
+		FOREIGN KEY (session_id) REFERENCES sessions(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sessions_external_id ON sessions(external_id);
+	CREATE INDEX IF NOT EXISTS idx_messages_session_id ON messages(session_id);
+	CREATE INDEX IF NOT EXISTS idx_tool_calls_session_id ON tool_calls(session_id);
+
+	CREATE TABLE IF NOT EXISTS metadata (
+		key TEXT PRIMARY KEY,
+		value TEXT,
+		updated_at INTEGER
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// The token columns were added to sessions after its first release
+	for _, column := range []string{"cache_creation_tokens", "cache_read_tokens", "reasoning_tokens"} {
+		if err := db.addColumn(ctx, "sessions", column, "INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateSessionFingerprint(ctx context.Context, db *DB) error {
+	if err := db.addColumn(ctx, "sessions", "metadata", "TEXT"); err != nil {
+		return err
+	}
+	return db.addColumn(ctx, "sessions", "fingerprint", "TEXT")
+}
+
+func migrateSyncFiles(ctx context.Context, db *DB) error {
+	_, err := db.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS sync_files (
+		path TEXT PRIMARY KEY,
+		source TEXT NOT NULL,
+		inode INTEGER DEFAULT 0,
+		size INTEGER DEFAULT 0,
+		mtime INTEGER DEFAULT 0,
+		byte_offset INTEGER DEFAULT 0,
+		parser_state TEXT,
+		session_id TEXT,
+		synced_at INTEGER
+	)`)
+	return err
+}
+
+// migrateMessagesFTS creates the full-text index over message content and fills it from existing messages
+func migrateMessagesFTS(ctx context.Context, db *DB) error {
+	var exists int
+	if err := db.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'messages_fts'`).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return nil
+	}
+	if _, err := db.db.ExecContext(ctx, `CREATE VIRTUAL TABLE messages_fts USING fts5(
+		content, content='messages', content_rowid='id', tokenize='porter unicode61')`); err != nil {
+		return fmt.Errorf("failed to create messages_fts: %w", err)
+	}
+	if _, err := db.db.ExecContext(ctx, `INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')`); err != nil {
+		return fmt.Errorf("failed to rebuild messages_fts: %w", err)
+	}
+	return nil
+}
+
+// migrateTurns creates the per-turn usage table. Sync state and fingerprints are
+// cleared so that the next sync re-parses existing session files and stores their turns.
+func migrateTurns(ctx context.Context, db *DB) error {
+	_, err := db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS turns (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL,
+		turn_index INTEGER NOT NULL,
+		timestamp INTEGER NOT NULL,
+		model TEXT,
+		input_tokens INTEGER DEFAULT 0,
+		output_tokens INTEGER DEFAULT 0,
+		cache_creation_tokens INTEGER DEFAULT 0,
+		cache_read_tokens INTEGER DEFAULT 0,
+		reasoning_tokens INTEGER DEFAULT 0,
+		total_tokens INTEGER DEFAULT 0,
+		latency_ms INTEGER DEFAULT 0,
+		FOREIGN KEY (session_id) REFERENCES sessions(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_turns_session_id ON turns(session_id, turn_index);
+
+	DELETE FROM sync_files;
+	UPDATE sessions SET fingerprint = NULL;
+	`)
+	return err
+}
+
+// migrateUsageKeys creates the table recording which session counted each
+// response. Sync state and fingerprints are cleared so that the next sync
+// recomputes the totals of existing sessions without duplicated usage.
+func migrateUsageKeys(ctx context.Context, db *DB) error {
+	_, err := db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS usage_keys (
+		key TEXT PRIMARY KEY,
+		external_id TEXT NOT NULL
+	);
+
+	DELETE FROM sync_files;
+	UPDATE sessions SET fingerprint = NULL;
+	`)
+	return err
+}
+
+// migrateSessionModels creates the per-model usage of sessions. Each session
+// starts with its totals under its main model, so sessions whose files are gone
+// keep counting. Sync state and fingerprints are cleared so that the next sync
+// splits existing sessions by model and stores Codex cached input as cache reads.
+func migrateSessionModels(ctx context.Context, db *DB) error {
+	_, err := db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS session_models (
+		session_id INTEGER NOT NULL,
+		model TEXT NOT NULL,
+		input_tokens INTEGER DEFAULT 0,
+		output_tokens INTEGER DEFAULT 0,
+		cache_creation_tokens INTEGER DEFAULT 0,
+		cache_read_tokens INTEGER DEFAULT 0,
+		reasoning_tokens INTEGER DEFAULT 0,
+		total_tokens INTEGER DEFAULT 0,
+		cost REAL DEFAULT 0,
+		PRIMARY KEY (session_id, model),
+		FOREIGN KEY (session_id) REFERENCES sessions(id)
+	);
+
+	INSERT OR IGNORE INTO session_models (session_id, model, input_tokens, output_tokens,
+		cache_creation_tokens, cache_read_tokens, reasoning_tokens, total_tokens, cost)
+	SELECT id, model, COALESCE(input_tokens, 0), COALESCE(output_tokens, 0), COALESCE(cache_creation_tokens, 0),
+		COALESCE(cache_read_tokens, 0), COALESCE(reasoning_tokens, 0), COALESCE(total_tokens, 0), COALESCE(cost, 0)
+	FROM sessions WHERE model IS NOT NULL AND model != '';
+
+	DELETE FROM sync_files;
+	UPDATE sessions SET fingerprint = NULL;
+	`)
+	return err
+}
+
+// migrateToolCallResults adds the columns pairing tool calls with their results.
+// Sync state and fingerprints are cleared so that the next sync stores the tool
+// calls of existing sessions.
+func migrateToolCallResults(ctx context.Context, db *DB) error {
+	for _, c := range []struct{ column, definition string }{
+		{"call_id", "TEXT"},
+		{"exit_code", "INTEGER"},
+		{"is_error", "INTEGER DEFAULT 0"},
+		{"duration_ms", "INTEGER DEFAULT 0"},
+	} {
+		if err := db.addColumn(ctx, "tool_calls", c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	_, err := db.db.ExecContext(ctx, `
+	CREATE INDEX IF NOT EXISTS idx_tool_calls_call_id ON tool_calls(session_id, call_id);
+
+	DELETE FROM sync_files;
+	UPDATE sessions SET fingerprint = NULL;
+	`)
+	return err
+}
+
+// resetSync clears sync state and fingerprints so that the next sync parses
+// every session file again, for parser changes that existing sessions need
+func resetSync(ctx context.Context, db *DB) error {
+	_, err := db.db.ExecContext(ctx, `
+	DELETE FROM sync_files;
+	UPDATE sessions SET fingerprint = NULL;
+	`)
+	return err
+}
+
+// migrateResumeHash adds the hash checked before resuming a file from its
+// stored offset. Files synced before have none and are parsed again once.
+func migrateResumeHash(ctx context.Context, db *DB) error {
+	return db.addColumn(ctx, "sync_files", "resume_hash", "TEXT")
+}
+
+// migrateUsageKeyOwners stores the time each usage key was reported and the
+// key of each turn, so that a key can move to the session that reported it
+// first. Sync state and fingerprints are cleared so that the next sync stores
+// the keys of existing turns.
+func migrateUsageKeyOwners(ctx context.Context, db *DB) error {
+	if err := db.addColumn(ctx, "usage_keys", "timestamp", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := db.addColumn(ctx, "turns", "usage_key", "TEXT"); err != nil {
+		return err
+	}
+	return resetSync(ctx, db)
+}
@@ -0,0 +1,108 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+
+	db, err := OpenUnmigrated(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	status, err := db.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus() error = %v", err)
+	}
+	if len(status) != len(migrations) || status[0].Applied() {
+		t.Fatalf("MigrationStatus() = %+v; want all pending", status)
+	}
+
+	applied, err := db.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("Migrate() applied %d migrations; want %d", len(applied), len(migrations))
+	}
+	if applied, err := db.Migrate(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second Migrate() = %v, %v; want nothing applied", applied, err)
+	}
+
+	status, _ = db.MigrationStatus(ctx)
+	for _, s := range status {
+		if !s.Applied() {
+			t.Errorf("migration %d not applied", s.Version)
+		}
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	// A database created by the first release, before versioned migrations
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE sessions (id INTEGER PRIMARY KEY AUTOINCREMENT, external_id TEXT UNIQUE, source TEXT NOT NULL,
+			project_path TEXT, model TEXT, provider TEXT, started_at INTEGER NOT NULL, ended_at INTEGER,
+			input_tokens INTEGER DEFAULT 0, output_tokens INTEGER DEFAULT 0, total_tokens INTEGER DEFAULT 0, cost REAL DEFAULT 0)`,
+		`CREATE TABLE messages (id INTEGER PRIMARY KEY AUTOINCREMENT, session_id INTEGER NOT NULL, role TEXT NOT NULL,
+			content TEXT, timestamp INTEGER NOT NULL)`,
+		`INSERT INTO sessions (external_id, source, project_path, model, provider, started_at, total_tokens, cost)
+			VALUES ('old', 'claude', '/p', 'm', 'anthropic', 1000000, 42, 1.5)`,
+		`INSERT INTO messages (session_id, role, content, timestamp) VALUES (1, 'user', 'migrate the legacy schema', 1000000)`,
+	} {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatalf("Exec(%q) error = %v", stmt, err)
+		}
+	}
+	conn.Close()
+
+	tr, err := NewSQLiteTracker(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteTracker() error = %v", err)
+	}
+	defer tr.Close()
+
+	ctx := context.Background()
+	row, err := tr.db.GetSessionByExternalID(ctx, "old")
+	if err != nil || row == nil || row.TotalTokens != 42 {
+		t.Fatalf("GetSessionByExternalID() = %+v, %v", row, err)
+	}
+	if results, err := tr.Search(ctx, QuoteSearchTerms("legacy"), SessionFilter{}); err != nil || len(results) != 1 {
+		t.Errorf("Search() = %+v, %v; want the legacy message", results, err)
+	}
+	// Sessions whose files are gone keep their usage per model
+	models, err := tr.db.GetSessionModels(ctx, row.ID)
+	if err != nil || len(models) != 1 || models[0].Model != "m" || models[0].TotalTokens != 42 || models[0].Cost != 1.5 {
+		t.Errorf("GetSessionModels() = %+v, %v; want the session totals under model m", models, err)
+	}
+}
+
+func TestOpenNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'from the future', 1)`,
+		LatestSchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if _, err := Open(path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Open() error = %v; want ErrSchemaTooNew", err)
+	}
+}
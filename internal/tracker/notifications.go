@@ -0,0 +1,111 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// notifySinceKey is the metadata key of the time notifications were first checked
+const notifySinceKey = "notify_since"
+
+// NotificationsSince returns the Unix time notifications were first checked,
+// and whether that is now because this is the first check. Events of the
+// sessions stored before then are not notified.
+func (t *SQLiteTracker) NotificationsSince(ctx context.Context, now time.Time) (int64, bool, error) {
+	claimed, err := t.db.ClaimMetadata(ctx, notifySinceKey, strconv.FormatInt(now.Unix(), 10))
+	if err != nil || claimed {
+		return now.Unix(), claimed, err
+	}
+	value, err := t.db.GetMetadata(ctx, notifySinceKey)
+	if err != nil {
+		return 0, false, err
+	}
+	since, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse %s: %w", notifySinceKey, err)
+	}
+	return since, false, nil
+}
+
+// ClaimNotification records that the event with the given key was notified,
+// and reports false if it already was, so each event is sent once
+func (t *SQLiteTracker) ClaimNotification(ctx context.Context, key string) (bool, error) {
+	return t.db.ClaimMetadata(ctx, "notified_"+key, strconv.FormatInt(time.Now().Unix(), 10))
+}
+
+// ReleaseNotification forgets a claimed event so it is sent again, e.g.
+// after it could not be delivered
+func (t *SQLiteTracker) ReleaseNotification(ctx context.Context, key string) error {
+	return t.db.DeleteMetadata(ctx, "notified_"+key)
+}
+
+// ModelSeen is a model and the first session that used it
+type ModelSeen struct {
+	Model      string `json:"model"`
+	Source     string `json:"source"`
+	ExternalID string `json:"external_id"`
+	FirstSeen  int64  `json:"first_seen"` // Unix start time of the first session
+}
+
+// GetSessionsOverCost returns the sessions that cost more than minCost and
+// were active at or after activeSince, most expensive first
+func (t *SQLiteTracker) GetSessionsOverCost(ctx context.Context, minCost float64, activeSince int64) ([]SessionRow, error) {
+	return t.db.GetSessionsOverCost(ctx, minCost, activeSince)
+}
+
+// GetModelsSeen returns every model used in a session, in the order they were first used
+func (t *SQLiteTracker) GetModelsSeen(ctx context.Context) ([]ModelSeen, error) {
+	return t.db.GetModelsSeen(ctx)
+}
+
+// GetSessionsOverCost returns the sessions that cost more than minCost and
+// were active at or after activeSince, most expensive first
+func (db *DB) GetSessionsOverCost(ctx context.Context, minCost float64, activeSince int64) ([]SessionRow, error) {
+	query := `SELECT s.id, s.external_id, s.source, s.project_path, s.model, s.provider, s.started_at, s.ended_at,
+		s.input_tokens, s.output_tokens, s.cache_creation_tokens, s.cache_read_tokens, s.reasoning_tokens, s.total_tokens, ` + messageCountSubquery + `, s.cost
+		FROM sessions s WHERE s.cost > ? AND COALESCE(s.ended_at, s.started_at) >= ?
+		ORDER BY s.cost DESC`
+	rows, err := db.db.QueryContext(ctx, query, minCost, activeSince)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions over cost: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionRow
+	for rows.Next() {
+		var s SessionRow
+		if err := rows.Scan(&s.ID, &s.ExternalID, &s.Source, &s.ProjectPath, &s.Model, &s.Provider,
+			&s.StartedAt, &s.EndedAt, &s.InputTokens, &s.OutputTokens, &s.CacheCreationTokens,
+			&s.CacheReadTokens, &s.ReasoningTokens, &s.TotalTokens, &s.MessageCount, &s.Cost); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// GetModelsSeen returns every model used in a session, in the order they were first used
+func (db *DB) GetModelsSeen(ctx context.Context) ([]ModelSeen, error) {
+	// SQLite takes the bare columns from the row with the minimum start time
+	query := `SELECT m.model, s.source, s.external_id, MIN(s.started_at)
+		FROM session_models m JOIN sessions s ON s.id = m.session_id
+		WHERE m.model != ''
+		GROUP BY m.model ORDER BY MIN(s.started_at), m.model`
+	rows, err := db.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query models: %w", err)
+	}
+	defer rows.Close()
+
+	var models []ModelSeen
+	for rows.Next() {
+		var m ModelSeen
+		if err := rows.Scan(&m.Model, &m.Source, &m.ExternalID, &m.FirstSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan model: %w", err)
+		}
+		models = append(models, m)
+	}
+	return models, rows.Err()
+}
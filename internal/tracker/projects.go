@@ -0,0 +1,163 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectStats holds the usage of a single project
+type ProjectStats struct {
+	ProjectPath        string  `json:"project_path"`
+	SessionCount       int64   `json:"session_count"`
+	TotalTime          int64   `json:"total_time"` // in seconds
+	TotalInputTokens   int64   `json:"total_input_tokens"`
+	TotalOutputTokens  int64   `json:"total_output_tokens"`
+	TotalCacheCreation int64   `json:"total_cache_creation"`
+	TotalCacheRead     int64   `json:"total_cache_read"`
+	TotalTokens        int64   `json:"total_tokens"`
+	TotalCost          float64 `json:"total_cost"`
+	TotalMessages      int64   `json:"total_messages"`
+	TopModel           string  `json:"top_model"`
+	LastActivity       int64   `json:"last_activity"` // Unix timestamp of the latest session start or end
+}
+
+// ProjectSortOrders lists the sort orders accepted by the projects report
+var ProjectSortOrders = []SortOrder{SortSessions, SortCost, SortTokens, SortTime, SortRecent}
+
+// projectOrderBy returns the SQL ORDER BY expression of the projects report
+func projectOrderBy(sort SortOrder) string {
+	switch sort {
+	case SortCost:
+		return "total_cost DESC, session_count DESC"
+	case SortTokens:
+		return "total_tokens DESC, session_count DESC"
+	case SortTime:
+		return "total_time DESC, session_count DESC"
+	case SortRecent:
+		return "last_activity DESC"
+	default:
+		return "session_count DESC, total_cost DESC"
+	}
+}
+
+// NormalizeProject returns a project path or glob in the form of the stored
+// project paths. A path is made absolute, with ~ expanded to the home
+// directory; a glob is returned as given.
+func NormalizeProject(pattern string) (string, error) {
+	if pattern == "" || strings.ContainsAny(pattern, "*?[") {
+		return pattern, nil
+	}
+	path := pattern
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	return filepath.Abs(path)
+}
+
+// projectFilter returns an SQL condition restricting column to projects matching
+// pattern. A pattern containing *, ? or [ is a glob; any other pattern is a path
+// that also matches its subdirectories. An empty pattern matches everything.
+func projectFilter(column, pattern string) (string, []any) {
+	if pattern == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		return " AND " + column + " GLOB ?", []any{pattern}
+	}
+	path := strings.TrimRight(pattern, "/")
+	return " AND (" + column + " = ? OR " + column + " GLOB ?)", []any{path, path + "/*"}
+}
+
+// GetProjectStats returns per-project usage in the period, optionally restricted to one agent
+func (db *DB) GetProjectStats(ctx context.Context, source string, since, until int64, project string, sort SortOrder) ([]ProjectStats, error) {
+	query := `SELECT s.project_path,
+		COUNT(*) as session_count,
+		COALESCE(SUM(CASE WHEN s.ended_at IS NOT NULL AND s.ended_at > s.started_at THEN s.ended_at - s.started_at ELSE 0 END), 0) as total_time,
+		COALESCE(SUM(s.input_tokens), 0),
+		COALESCE(SUM(s.output_tokens), 0),
+		COALESCE(SUM(s.cache_creation_tokens), 0),
+		COALESCE(SUM(s.cache_read_tokens), 0),
+		COALESCE(SUM(s.total_tokens), 0) as total_tokens,
+		COALESCE(SUM(s.cost), 0) as total_cost,
+		COALESCE(SUM(m.message_count), 0),
+		MAX(MAX(s.started_at, COALESCE(s.ended_at, 0))) as last_activity
+		FROM sessions s
+		LEFT JOIN (
+			SELECT session_id, COUNT(*) as message_count FROM messages GROUP BY session_id
+		) m ON m.session_id = s.id
+		WHERE s.started_at >= ? AND s.started_at < ? AND s.project_path IS NOT NULL AND s.project_path != ''`
+	args := []any{since, until}
+	if source != "" {
+		query += ` AND s.source = ?`
+		args = append(args, source)
+	}
+	filter, filterArgs := projectFilter("s.project_path", project)
+	args = append(args, filterArgs...)
+	query += filter + ` GROUP BY s.project_path ORDER BY ` + projectOrderBy(sort) + `, s.project_path`
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project stats: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []ProjectStats
+	index := make(map[string]int)
+	for rows.Next() {
+		var p ProjectStats
+		if err := rows.Scan(&p.ProjectPath, &p.SessionCount, &p.TotalTime, &p.TotalInputTokens, &p.TotalOutputTokens,
+			&p.TotalCacheCreation, &p.TotalCacheRead, &p.TotalTokens, &p.TotalCost, &p.TotalMessages, &p.LastActivity); err != nil {
+			return nil, fmt.Errorf("failed to scan project stats: %w", err)
+		}
+		index[p.ProjectPath] = len(projects)
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query project stats: %w", err)
+	}
+
+	// The most used model of each project comes first in its group
+	query = `SELECT project_path, model FROM sessions
+		WHERE started_at >= ? AND started_at < ? AND model IS NOT NULL AND model != ''
+		AND project_path IS NOT NULL AND project_path != ''`
+	args = []any{since, until}
+	if source != "" {
+		query += ` AND source = ?`
+		args = append(args, source)
+	}
+	filter, filterArgs = projectFilter("project_path", project)
+	args = append(args, filterArgs...)
+	query += filter + ` GROUP BY project_path, model ORDER BY project_path, COUNT(*) DESC, SUM(cost) DESC`
+
+	modelRows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project models: %w", err)
+	}
+	defer modelRows.Close()
+
+	for modelRows.Next() {
+		var path, model string
+		if err := modelRows.Scan(&path, &model); err != nil {
+			return nil, fmt.Errorf("failed to scan project model: %w", err)
+		}
+		if i, ok := index[path]; ok && projects[i].TopModel == "" {
+			projects[i].TopModel = model
+		}
+	}
+	return projects, modelRows.Err()
+}
+
+// GetProjectStats returns per-project usage within a time range. An empty agent
+// includes all agents and an empty project pattern includes all projects.
+func (t *SQLiteTracker) GetProjectStats(ctx context.Context, agent Agent, r TimeRange, project string, sort SortOrder) ([]ProjectStats, error) {
+	start, end := r.bounds()
+
+	return t.db.GetProjectStats(ctx, string(agent), start, end, project, sort)
+}
@@ -0,0 +1,138 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func insertProjectSessions(t *testing.T, tr *SQLiteTracker, now int64) {
+	t.Helper()
+	ended := func(ts int64) *int64 { return &ts }
+	sessions := []SessionRow{
+		{ExternalID: "a1", Source: "claude", ProjectPath: "/work/api", Model: "claude-sonnet-4-5", StartedAt: now - 7200, EndedAt: ended(now - 6600), InputTokens: 100, OutputTokens: 50, TotalTokens: 150, Cost: 0.50},
+		{ExternalID: "a2", Source: "codex", ProjectPath: "/work/api", Model: "gpt-5.2-codex", StartedAt: now - 3600, EndedAt: ended(now - 3000), InputTokens: 200, OutputTokens: 100, TotalTokens: 300, Cost: 0.25},
+		{ExternalID: "a3", Source: "claude", ProjectPath: "/work/api", Model: "claude-sonnet-4-5", StartedAt: now - 1800, InputTokens: 10, TotalTokens: 10, Cost: 0.05},
+		{ExternalID: "b1", Source: "claude", ProjectPath: "/work/api/tools", Model: "claude-haiku-4-5", StartedAt: now - 600, EndedAt: ended(now - 300), TotalTokens: 5000, Cost: 2.00},
+		{ExternalID: "c1", Source: "codex", ProjectPath: "/home/me/apiary", Model: "gpt-5.2-codex", StartedAt: now - 900, TotalTokens: 20, Cost: 0.01},
+		{ExternalID: "old", Source: "claude", ProjectPath: "/work/api", StartedAt: now - 10*86400, TotalTokens: 999},
+	}
+	for _, s := range sessions {
+		if _, err := tr.db.InsertSession(context.Background(), &s); err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+	}
+}
+
+func TestGetProjectStats(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	now := time.Now()
+	insertProjectSessions(t, tr, now.Unix())
+	r := TimeRange{Start: now.Add(-24 * time.Hour), End: now}
+
+	projects, err := tr.GetProjectStats(ctx, "", r, "", SortSessions)
+	if err != nil {
+		t.Fatalf("GetProjectStats() error = %v", err)
+	}
+	if len(projects) != 3 {
+		t.Fatalf("len(projects) = %d; want 3: %+v", len(projects), projects)
+	}
+
+	api := projects[0]
+	if api.ProjectPath != "/work/api" || api.SessionCount != 3 {
+		t.Fatalf("projects[0] = %+v; want /work/api with 3 sessions", api)
+	}
+	if api.TotalTime != 1200 || api.TotalInputTokens != 310 || api.TotalOutputTokens != 150 || api.TotalTokens != 460 {
+		t.Errorf("Unexpected totals: %+v", api)
+	}
+	if api.TotalCost < 0.7999 || api.TotalCost > 0.8001 {
+		t.Errorf("TotalCost = %v; want 0.80", api.TotalCost)
+	}
+	if api.TopModel != "claude-sonnet-4-5" {
+		t.Errorf("TopModel = %q; want claude-sonnet-4-5", api.TopModel)
+	}
+	if api.LastActivity != now.Unix()-1800 {
+		t.Errorf("LastActivity = %d; want %d", api.LastActivity, now.Unix()-1800)
+	}
+
+	projects, _ = tr.GetProjectStats(ctx, "", r, "", SortCost)
+	if projects[0].ProjectPath != "/work/api/tools" {
+		t.Errorf("First project by cost = %s; want /work/api/tools", projects[0].ProjectPath)
+	}
+	projects, _ = tr.GetProjectStats(ctx, "", r, "", SortRecent)
+	if projects[0].ProjectPath != "/work/api/tools" || projects[2].ProjectPath != "/work/api" {
+		t.Errorf("Unexpected order by last activity: %+v", projects)
+	}
+
+	projects, _ = tr.GetProjectStats(ctx, AgentCodex, r, "", SortSessions)
+	if len(projects) != 2 || projects[0].SessionCount != 1 || projects[0].TopModel != "gpt-5.2-codex" {
+		t.Errorf("Unexpected codex projects: %+v", projects)
+	}
+}
+
+func TestProjectFilter(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	now := time.Now()
+	insertProjectSessions(t, tr, now.Unix())
+	r := TimeRange{Start: now.Add(-24 * time.Hour), End: now}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		// A path matches itself and its subdirectories, but not siblings sharing a prefix
+		{"/work/api", []string{"/work/api", "/work/api/tools"}},
+		{"/work/api/", []string{"/work/api", "/work/api/tools"}},
+		{"/work/api/tools", []string{"/work/api/tools"}},
+		{"*/api*", []string{"/home/me/apiary", "/work/api", "/work/api/tools"}},
+		{"/home/*", []string{"/home/me/apiary"}},
+		{"/nowhere", nil},
+	}
+	for _, tt := range tests {
+		projects, err := tr.GetProjectStats(ctx, "", r, tt.pattern, SortRecent)
+		if err != nil {
+			t.Fatalf("GetProjectStats(%q) error = %v", tt.pattern, err)
+		}
+		got := make(map[string]bool)
+		for _, p := range projects {
+			got[p.ProjectPath] = true
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("GetProjectStats(%q) = %v; want %v", tt.pattern, got, tt.want)
+			continue
+		}
+		for _, w := range tt.want {
+			if !got[w] {
+				t.Errorf("GetProjectStats(%q) = %v; want %v", tt.pattern, got, tt.want)
+			}
+		}
+	}
+
+	stats, err := tr.GetUsageStatsAll(ctx, r, "/work/api", SortSessions)
+	if err != nil {
+		t.Fatalf("GetUsageStatsAll() error = %v", err)
+	}
+	if stats.SessionCount != 4 || stats.UniqueProjects != 2 || stats.Project != "/work/api" {
+		t.Errorf("Unexpected filtered stats: %+v", stats)
+	}
+	for _, s := range stats.RecentSessions {
+		if s.ProjectPath == "/home/me/apiary" {
+			t.Errorf("Recent sessions include a filtered project: %+v", s)
+		}
+	}
+
+	perAgent, _ := tr.GetPerAgentStats(ctx, r, "/home/me/apiary")
+	if len(perAgent) != 1 || perAgent[0].Source != "codex" || perAgent[0].SessionCount != 1 {
+		t.Errorf("Unexpected filtered per-agent stats: %+v", perAgent)
+	}
+
+	claude, err := tr.GetUsageStats(ctx, AgentClaudeCode, r, "/work/api/tools", SortSessions)
+	if err != nil {
+		t.Fatalf("GetUsageStats() error = %v", err)
+	}
+	if claude.SessionCount != 1 || claude.LastSession == nil || claude.LastSession.ExternalID != "b1" {
+		t.Errorf("Unexpected filtered claude stats: %+v", claude)
+	}
+}
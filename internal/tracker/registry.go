@@ -0,0 +1,110 @@
+package tracker
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// AgentProvider describes an AI coding agent whose session files can be synced
+type AgentProvider interface {
+	// Name returns the agent identifier stored in sessions.source
+	Name() Agent
+	// DisplayName returns the human-readable agent name
+	DisplayName() string
+	// DefaultSessionsDir returns the directory the agent writes session files to
+	DefaultSessionsDir() string
+	// FindSessions returns all session files under dir
+	FindSessions(dir string) ([]string, error)
+	// NewParser returns a parser for one session file, resuming from state
+	// previously returned by SessionParser.State (nil starts a new file)
+	NewParser(state []byte) (SessionParser, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   []AgentProvider
+)
+
+// RegisterProvider adds an agent provider to the registry.
+// Registering a provider with an existing name replaces it.
+func RegisterProvider(p AgentProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	for i, existing := range providers {
+		if existing.Name() == p.Name() {
+			providers[i] = p
+			return
+		}
+	}
+	providers = append(providers, p)
+}
+
+// Providers returns all registered agent providers in registration order
+func Providers() []AgentProvider {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	result := make([]AgentProvider, len(providers))
+	copy(result, providers)
+	return result
+}
+
+// LookupProvider returns the provider registered under name
+func LookupProvider(name string) (AgentProvider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	for _, p := range providers {
+		if string(p.Name()) == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// ProviderNames returns the names of all registered providers
+func ProviderNames() []string {
+	var names []string
+	for _, p := range Providers() {
+		names = append(names, string(p.Name()))
+	}
+	return names
+}
+
+// DisplayName returns the display name for a source, falling back to the source itself
+func DisplayName(source string) string {
+	if p, ok := LookupProvider(source); ok {
+		return p.DisplayName()
+	}
+	return source
+}
+
+// findJSONLFiles finds all .jsonl files under dir, newest first
+func findJSONLFiles(dir string) ([]string, error) {
+	var matches []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".jsonl" {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort by modification time, newest first
+	sort.Slice(matches, func(i, j int) bool {
+		iInfo, _ := os.Stat(matches[i])
+		jInfo, _ := os.Stat(matches[j])
+		return iInfo.ModTime().After(jInfo.ModTime())
+	})
+
+	return matches, nil
+}
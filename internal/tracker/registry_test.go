@@ -0,0 +1,58 @@
+package tracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuiltinProvidersRegistered(t *testing.T) {
+	for _, name := range []string{"codex", "claude"} {
+		p, ok := LookupProvider(name)
+		if !ok {
+			t.Fatalf("provider %s not registered", name)
+		}
+		if string(p.Name()) != name {
+			t.Errorf("Name() = %s; want %s", p.Name(), name)
+		}
+	}
+
+	if _, ok := LookupProvider("unknown"); ok {
+		t.Error("LookupProvider(unknown) should not find a provider")
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	if got := DisplayName("codex"); got != "Codex" {
+		t.Errorf("DisplayName(codex) = %s; want Codex", got)
+	}
+	if got := DisplayName("claude"); got != "Claude" {
+		t.Errorf("DisplayName(claude) = %s; want Claude", got)
+	}
+	// Unknown sources fall back to the raw name
+	if got := DisplayName("gemini"); got != "gemini" {
+		t.Errorf("DisplayName(gemini) = %s; want gemini", got)
+	}
+}
+
+func TestProviderFindSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	nested := filepath.Join(tmpDir, "2026", "02")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.jsonl", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(nested, name), []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, _ := LookupProvider("codex")
+	files, err := p.FindSessions(tmpDir)
+	if err != nil {
+		t.Fatalf("FindSessions() error = %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "a.jsonl" {
+		t.Errorf("FindSessions() = %v; want [a.jsonl]", files)
+	}
+}
@@ -0,0 +1,75 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Snippet highlight markers around matched terms in SearchResult.Snippet
+const (
+	SnippetStart = "\x02"
+	SnippetEnd   = "\x03"
+)
+
+// SearchResult is a message matching a full-text search
+type SearchResult struct {
+	ExternalID  string `json:"external_id"`
+	Source      string `json:"source"`
+	ProjectPath string `json:"project_path"`
+	Model       string `json:"model"`
+	Role        string `json:"role"`
+	Timestamp   int64  `json:"timestamp"`
+	Snippet     string `json:"snippet"`
+}
+
+// QuoteSearchTerms turns free text into an FTS5 query matching messages that
+// contain every word, so that punctuation is never parsed as query syntax
+func QuoteSearchTerms(text string) string {
+	terms := strings.Fields(text)
+	for i, t := range terms {
+		terms[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
+
+// SearchMessages returns the messages matching an FTS5 query, best match first,
+// restricted to sessions matching the filter. Its sort order is ignored.
+func (db *DB) SearchMessages(ctx context.Context, query string, f SessionFilter) ([]SearchResult, error) {
+	where, args := f.where()
+	sqlQuery := `SELECT s.external_id, s.source, COALESCE(s.project_path, ''), COALESCE(s.model, ''), m.role, m.timestamp,
+		snippet(messages_fts, 0, ?, ?, '…', 16)
+		FROM messages_fts
+		JOIN messages m ON m.id = messages_fts.rowid
+		JOIN sessions s ON s.id = m.session_id` + where + ` AND messages_fts MATCH ?
+		ORDER BY messages_fts.rank LIMIT ? OFFSET ?`
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append([]any{SnippetStart, SnippetEnd}, args...)
+	rows, err := db.db.QueryContext(ctx, sqlQuery, append(args, query, limit, f.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.ExternalID, &r.Source, &r.ProjectPath, &r.Model, &r.Role, &r.Timestamp, &r.Snippet); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	return results, nil
+}
+
+// Search returns the messages matching an FTS5 query in sessions matching the filter
+func (t *SQLiteTracker) Search(ctx context.Context, query string, f SessionFilter) ([]SearchResult, error) {
+	return t.db.SearchMessages(ctx, query, f)
+}
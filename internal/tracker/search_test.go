@@ -0,0 +1,114 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	insertProjectSessions(t, tr, 1000000)
+
+	messages := map[string][]string{
+		"a1": {"The migration fixed the flaky login test", "Deploy the API"},
+		"c1": {"Fixing the parser crash in codex"},
+		"b1": {"login page redesign"},
+	}
+	for id, contents := range messages {
+		row, _ := tr.db.GetSessionByExternalID(ctx, id)
+		for i, content := range contents {
+			if _, err := tr.db.InsertMessage(ctx, &MessageRow{SessionID: row.ID, Role: "user", Content: content, Timestamp: int64(1000000 + i)}); err != nil {
+				t.Fatalf("InsertMessage() error = %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		filter SessionFilter
+		want   []string
+	}{
+		{"stemmed", QuoteSearchTerms("fix"), SessionFilter{}, []string{"a1", "c1"}},
+		{"all words", QuoteSearchTerms("login test"), SessionFilter{}, []string{"a1"}},
+		{"punctuation", QuoteSearchTerms(`login "page`), SessionFilter{}, []string{"b1"}},
+		{"agent", QuoteSearchTerms("fixes"), SessionFilter{Source: "codex"}, []string{"c1"}},
+		{"project", QuoteSearchTerms("login"), SessionFilter{Project: "/work/api/tools"}, []string{"b1"}},
+		{"raw", "deploy OR crash", SessionFilter{}, []string{"a1", "c1"}},
+	}
+	for _, tt := range tests {
+		results, err := tr.Search(ctx, tt.query, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: Search() error = %v", tt.name, err)
+		}
+		got := map[string]bool{}
+		for _, r := range results {
+			got[r.ExternalID] = true
+		}
+		if len(results) != len(tt.want) {
+			t.Errorf("%s: got %+v; want %v", tt.name, results, tt.want)
+			continue
+		}
+		for _, id := range tt.want {
+			if !got[id] {
+				t.Errorf("%s: missing %s in %+v", tt.name, id, results)
+			}
+		}
+	}
+
+	results, _ := tr.Search(ctx, QuoteSearchTerms("flaky"), SessionFilter{})
+	if len(results) != 1 || !strings.Contains(results[0].Snippet, SnippetStart+"flaky"+SnippetEnd) {
+		t.Errorf("Unexpected snippet: %+v", results)
+	}
+
+	// Replacing a session's content removes its old messages from the index
+	row, _ := tr.db.GetSessionByExternalID(ctx, "a1")
+	if err := tr.db.DeleteSessionContent(ctx, row.ID); err != nil {
+		t.Fatalf("DeleteSessionContent() error = %v", err)
+	}
+	if results, _ := tr.Search(ctx, QuoteSearchTerms("flaky"), SessionFilter{}); len(results) != 0 {
+		t.Errorf("Deleted message still found: %+v", results)
+	}
+}
+
+func TestSearchIndexesExistingMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	tr, err := NewSQLiteTracker(path)
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+	insertProjectSessions(t, tr, 1000000)
+	tr.Close()
+
+	// Simulate a database created before the search index existed
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`DROP TABLE messages_fts`,
+		`DROP TABLE schema_migrations`,
+		`INSERT INTO messages (session_id, role, content, timestamp) VALUES (1, 'assistant', 'renamed the config loader', 1000000)`,
+	} {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatalf("Exec(%q) error = %v", stmt, err)
+		}
+	}
+	conn.Close()
+
+	if tr, err = NewSQLiteTracker(path); err != nil {
+		t.Fatalf("Failed to reopen tracker: %v", err)
+	}
+	defer tr.Close()
+	results, err := tr.Search(context.Background(), QuoteSearchTerms("loader"), SessionFilter{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].ExternalID != "a1" || results[0].Role != "assistant" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,163 @@
+package tracker
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ari/agent-usage/internal/pricing"
+)
+
+// defaultPricing prices sessions when no configured table is available
+var defaultPricing = pricing.Default()
+
+// NormalizedSession is the agent-independent session model emitted by every parser
+type NormalizedSession struct {
+	ID          string
+	Source      Agent
+	ProjectPath string
+	Model       string
+	Provider    string
+	StartedAt   time.Time
+	EndedAt     *time.Time
+	Tokens      TokenUsage
+	Cost        float64
+	Messages    []Message
+	ToolCalls   []ToolCall
+	Turns       []Turn
+	Metadata    map[string]string
+	Fingerprint string // content hash of the parsed file, used to detect changes
+
+	// TokensFromTurns is set when Tokens is the sum of Turns, so that stored
+	// totals can be recomputed after turns copied from other sessions are dropped
+	TokensFromTurns bool
+
+	// ModelTokens splits Tokens by model for sessions that switch models;
+	// empty when all usage belongs to Model
+	ModelTokens map[string]TokenUsage
+}
+
+// TokenUsage represents token usage for a session or turn
+type TokenUsage struct {
+	Input         int
+	Output        int
+	CacheCreation int
+	CacheRead     int
+	Reasoning     int
+	Total         int
+}
+
+// add returns the sum of two usages
+func (u TokenUsage) add(o TokenUsage) TokenUsage {
+	return TokenUsage{
+		Input:         u.Input + o.Input,
+		Output:        u.Output + o.Output,
+		CacheCreation: u.CacheCreation + o.CacheCreation,
+		CacheRead:     u.CacheRead + o.CacheRead,
+		Reasoning:     u.Reasoning + o.Reasoning,
+		Total:         u.Total + o.Total,
+	}
+}
+
+// sub returns the usage of u not already included in o
+func (u TokenUsage) sub(o TokenUsage) TokenUsage {
+	return TokenUsage{
+		Input:         u.Input - o.Input,
+		Output:        u.Output - o.Output,
+		CacheCreation: u.CacheCreation - o.CacheCreation,
+		CacheRead:     u.CacheRead - o.CacheRead,
+		Reasoning:     u.Reasoning - o.Reasoning,
+		Total:         u.Total - o.Total,
+	}
+}
+
+// Message represents a message in a session
+type Message struct {
+	Role      string
+	Content   string
+	Timestamp time.Time
+}
+
+// ThinkingStart and ThinkingEnd enclose the model's thinking within message content
+const (
+	ThinkingStart = "[thinking]\n"
+	ThinkingEnd   = "\n[/thinking]"
+)
+
+// ToolCall represents a tool call in a session
+type ToolCall struct {
+	ToolName  string
+	Arguments string
+	Result    string
+	Timestamp time.Time
+	CallID    string        // agent-specific ID pairing the call with its result, empty if unknown
+	ExitCode  *int          // exit code of commands, nil if unknown
+	IsError   bool          // the call failed
+	Duration  time.Duration // 0 if unknown
+
+	// ResultOnly marks the result of a call stored by an earlier sync; it is
+	// joined to the stored call by CallID instead of stored as a new call
+	ResultOnly bool
+}
+
+// Turn represents the token usage reported for a single model response
+type Turn struct {
+	Index     int
+	Timestamp time.Time
+	Model     string
+	Tokens    TokenUsage
+	Latency   time.Duration // since the prior user message, 0 if unknown
+	UsageKey  string        // agent-specific ID of the response, empty if unknown
+}
+
+// newNormalizedSession returns an empty session for the given agent
+func newNormalizedSession(source Agent) *NormalizedSession {
+	return &NormalizedSession{
+		Source:    source,
+		Messages:  make([]Message, 0),
+		ToolCalls: make([]ToolCall, 0),
+		Turns:     make([]Turn, 0),
+		Metadata:  make(map[string]string),
+	}
+}
+
+// pricingUsage returns the usage in the form priced by a pricing table
+func (u TokenUsage) pricingUsage() pricing.Usage {
+	return pricing.Usage{
+		Input:      int64(u.Input),
+		Output:     int64(u.Output),
+		CacheWrite: int64(u.CacheCreation),
+		CacheRead:  int64(u.CacheRead),
+		Reasoning:  int64(u.Reasoning),
+	}
+}
+
+// modelUsage returns the usage of each model of the session
+func (s *NormalizedSession) modelUsage() map[string]TokenUsage {
+	if len(s.ModelTokens) > 0 {
+		return s.ModelTokens
+	}
+	if s.Tokens == (TokenUsage{}) {
+		return nil
+	}
+	return map[string]TokenUsage{s.Model: s.Tokens}
+}
+
+// Price sets the session cost from the given pricing table, pricing the usage
+// of each model at its own rate
+func (s *NormalizedSession) Price(table *pricing.Table) {
+	models := make([]string, 0, len(s.ModelTokens))
+	for model := range s.ModelTokens {
+		models = append(models, model)
+	}
+	if len(models) == 0 {
+		s.Cost = table.Cost(s.Provider, s.Model, s.Tokens.pricingUsage())
+		return
+	}
+
+	// Sum in a fixed order so the cost does not change between syncs
+	sort.Strings(models)
+	s.Cost = 0
+	for _, model := range models {
+		s.Cost += table.Cost(s.Provider, model, s.ModelTokens[model].pricingUsage())
+	}
+}
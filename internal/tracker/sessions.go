@@ -0,0 +1,225 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ari/agent-usage/internal/pricing"
+)
+
+// SessionFilter selects the sessions returned by ListSessions. Zero fields match
+// every session.
+type SessionFilter struct {
+	Source  string
+	Project string // path or glob, matched like the --project flag
+	Model   string // exact model name, or a glob if it contains *, ? or [
+	Since   int64  // inclusive lower bound of started_at
+	Until   int64  // exclusive upper bound of started_at
+	Sort    SortOrder
+	Limit   int
+	Offset  int
+}
+
+// SortOldest lists sessions oldest first
+const SortOldest SortOrder = "oldest"
+
+// SessionSortOrders lists the sort orders accepted by the sessions list
+var SessionSortOrders = []SortOrder{SortRecent, SortOldest, SortCost, SortTokens, SortTime}
+
+// sessionOrderBy returns the SQL ORDER BY expression of the sessions list
+func sessionOrderBy(sort SortOrder) string {
+	switch sort {
+	case SortOldest:
+		return "s.started_at ASC"
+	case SortCost:
+		return "s.cost DESC, s.started_at DESC"
+	case SortTokens:
+		return "s.total_tokens DESC, s.started_at DESC"
+	case SortTime:
+		return "CASE WHEN s.ended_at > s.started_at THEN s.ended_at - s.started_at ELSE 0 END DESC, s.started_at DESC"
+	default:
+		return "s.started_at DESC"
+	}
+}
+
+// where returns the SQL conditions and arguments of the filter
+func (f SessionFilter) where() (string, []any) {
+	query := ` WHERE 1 = 1`
+	var args []any
+	if f.Source != "" {
+		query += ` AND s.source = ?`
+		args = append(args, f.Source)
+	}
+	if f.Model != "" {
+		if strings.ContainsAny(f.Model, "*?[") {
+			query += ` AND s.model GLOB ?`
+		} else {
+			query += ` AND s.model = ?`
+		}
+		args = append(args, f.Model)
+	}
+	if f.Since != 0 {
+		query += ` AND s.started_at >= ?`
+		args = append(args, f.Since)
+	}
+	if f.Until != 0 {
+		query += ` AND s.started_at < ?`
+		args = append(args, f.Until)
+	}
+	filter, filterArgs := projectFilter("s.project_path", f.Project)
+	return query + filter, append(args, filterArgs...)
+}
+
+// ListSessions returns one page of the sessions matching the filter
+func (db *DB) ListSessions(ctx context.Context, f SessionFilter) ([]SessionRow, error) {
+	where, args := f.where()
+	query := `SELECT s.id, s.external_id, s.source, s.project_path, s.model, s.provider, s.started_at, s.ended_at,
+		s.input_tokens, s.output_tokens, s.cache_creation_tokens, s.cache_read_tokens, s.reasoning_tokens, s.total_tokens, ` + messageCountSubquery + `, s.cost
+		FROM sessions s` + where + ` ORDER BY ` + sessionOrderBy(f.Sort) + ` LIMIT ? OFFSET ?`
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := db.db.QueryContext(ctx, query, append(args, limit, f.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionRow
+	for rows.Next() {
+		var s SessionRow
+		if err := rows.Scan(&s.ID, &s.ExternalID, &s.Source, &s.ProjectPath, &s.Model, &s.Provider,
+			&s.StartedAt, &s.EndedAt, &s.InputTokens, &s.OutputTokens, &s.CacheCreationTokens,
+			&s.CacheReadTokens, &s.ReasoningTokens, &s.TotalTokens, &s.MessageCount, &s.Cost); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// CountSessions returns the number of sessions matching the filter, ignoring its limit and offset
+func (db *DB) CountSessions(ctx context.Context, f SessionFilter) (int64, error) {
+	where, args := f.where()
+
+	var count int64
+	if err := db.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sessions s`+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	return count, nil
+}
+
+// FindExternalIDs returns up to limit external IDs starting with prefix
+func (db *DB) FindExternalIDs(ctx context.Context, prefix string, limit int) ([]string, error) {
+	query := `SELECT external_id FROM sessions WHERE substr(external_id, 1, ?) = ? ORDER BY external_id LIMIT ?`
+
+	rows, err := db.db.QueryContext(ctx, query, len(prefix), prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan session id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SessionDetail is a stored session with its messages, tool calls and cost breakdown
+type SessionDetail struct {
+	Session       SessionRow        `json:"session"`
+	CostBreakdown pricing.Breakdown `json:"cost_breakdown"` // at the current rate of each model
+	Models        []SessionModelRow `json:"models"`         // usage of each model of the session
+	Messages      []MessageRow      `json:"messages"`
+	ToolCalls     []ToolCallRow     `json:"tool_calls"`
+}
+
+// ListSessions returns the sessions matching the filter and the total number of
+// matches across all pages
+func (t *SQLiteTracker) ListSessions(ctx context.Context, f SessionFilter) ([]SessionRow, int64, error) {
+	sessions, err := t.db.ListSessions(ctx, f)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := t.db.CountSessions(ctx, f)
+	if err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
+
+// GetSessionDetail returns the session with the given external ID, or with the
+// only external ID starting with it. It returns ErrSessionNotFound if no session
+// matches and ErrAmbiguousSession if the prefix matches several.
+func (t *SQLiteTracker) GetSessionDetail(ctx context.Context, externalID string) (*SessionDetail, error) {
+	row, err := t.db.GetSessionByExternalID(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil && externalID != "" {
+		ids, err := t.db.FindExternalIDs(ctx, externalID, 2)
+		if err != nil {
+			return nil, err
+		}
+		switch len(ids) {
+		case 0:
+		case 1:
+			if row, err = t.db.GetSessionByExternalID(ctx, ids[0]); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrAmbiguousSession, externalID)
+		}
+	}
+	if row == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, externalID)
+	}
+
+	detail := &SessionDetail{Session: *row}
+	if detail.Messages, err = t.db.GetMessagesBySessionID(ctx, row.ID); err != nil {
+		return nil, err
+	}
+	if detail.ToolCalls, err = t.db.GetToolCallsBySessionID(ctx, row.ID); err != nil {
+		return nil, err
+	}
+	detail.Session.MessageCount = int64(len(detail.Messages))
+
+	if detail.Models, err = t.db.GetSessionModels(ctx, row.ID); err != nil {
+		return nil, err
+	}
+
+	if len(detail.Models) == 0 {
+		rate, _ := t.pricing.Lookup(row.Provider, row.Model)
+		detail.CostBreakdown = rate.Breakdown(pricing.Usage{
+			Input:      row.InputTokens,
+			Output:     row.OutputTokens,
+			CacheWrite: row.CacheCreationTokens,
+			CacheRead:  row.CacheReadTokens,
+			Reasoning:  row.ReasoningTokens,
+		})
+	}
+	for _, m := range detail.Models {
+		rate, _ := t.pricing.Lookup(row.Provider, m.Model)
+		b := rate.Breakdown(pricing.Usage{
+			Input:      m.InputTokens,
+			Output:     m.OutputTokens,
+			CacheWrite: m.CacheCreationTokens,
+			CacheRead:  m.CacheReadTokens,
+			Reasoning:  m.ReasoningTokens,
+		})
+		detail.CostBreakdown.Input += b.Input
+		detail.CostBreakdown.Output += b.Output
+		detail.CostBreakdown.CacheWrite += b.CacheWrite
+		detail.CostBreakdown.CacheRead += b.CacheRead
+		detail.CostBreakdown.Reasoning += b.Reasoning
+	}
+	return detail, nil
+}
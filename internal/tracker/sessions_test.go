@@ -0,0 +1,123 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestListSessions(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	now := time.Now()
+	insertProjectSessions(t, tr, now.Unix())
+
+	sessions, total, err := tr.ListSessions(ctx, SessionFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if total != 6 || len(sessions) != 2 {
+		t.Fatalf("total = %d, len = %d; want 6 and 2", total, len(sessions))
+	}
+	if sessions[0].ExternalID != "b1" || sessions[1].ExternalID != "c1" {
+		t.Errorf("Unexpected first page: %s, %s", sessions[0].ExternalID, sessions[1].ExternalID)
+	}
+
+	sessions, _, _ = tr.ListSessions(ctx, SessionFilter{Limit: 2, Offset: 4})
+	if len(sessions) != 2 || sessions[1].ExternalID != "old" {
+		t.Errorf("Unexpected last page: %+v", sessions)
+	}
+
+	tests := []struct {
+		name   string
+		filter SessionFilter
+		want   []string
+	}{
+		{"agent", SessionFilter{Source: "codex"}, []string{"c1", "a2"}},
+		{"model", SessionFilter{Model: "claude-sonnet-4-5"}, []string{"a3", "a1"}},
+		{"model glob", SessionFilter{Model: "claude-*"}, []string{"b1", "a3", "a1"}},
+		{"project", SessionFilter{Project: "/work/api/tools"}, []string{"b1"}},
+		{"since", SessionFilter{Since: now.Unix() - 1800}, []string{"b1", "c1", "a3"}},
+		{"until", SessionFilter{Until: now.Unix() - 86400}, []string{"old"}},
+		{"oldest", SessionFilter{Source: "codex", Sort: SortOldest}, []string{"a2", "c1"}},
+		{"cost", SessionFilter{Source: "claude", Sort: SortCost, Limit: 2}, []string{"b1", "a1"}},
+		{"tokens", SessionFilter{Sort: SortTokens, Limit: 1}, []string{"b1"}},
+		{"time", SessionFilter{Sort: SortTime, Limit: 2}, []string{"a2", "a1"}},
+	}
+	for _, tt := range tests {
+		sessions, total, err := tr.ListSessions(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: ListSessions() error = %v", tt.name, err)
+		}
+		var got []string
+		for _, s := range sessions {
+			got = append(got, s.ExternalID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v; want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v; want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+		if tt.filter.Limit == 0 && total != int64(len(tt.want)) {
+			t.Errorf("%s: total = %d; want %d", tt.name, total, len(tt.want))
+		}
+	}
+}
+
+func TestGetSessionDetail(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+
+	start := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+	for _, id := range []string{"abc-111", "abc-222"} {
+		session := newNormalizedSession(AgentClaudeCode)
+		session.ID = id
+		session.Model = "claude-sonnet-4-5"
+		session.Provider = "anthropic"
+		session.StartedAt = start
+		session.Tokens = TokenUsage{Input: 1_000_000, Output: 100_000, CacheRead: 2_000_000, Total: 3_100_000}
+		session.Messages = []Message{
+			{Role: "user", Content: "hello", Timestamp: start},
+			{Role: "assistant", Content: "hi", Timestamp: start.Add(time.Second)},
+		}
+		session.ToolCalls = []ToolCall{{ToolName: "Read", Arguments: `{"file_path":"main.go"}`, Timestamp: start.Add(2 * time.Second)}}
+		if err := tr.Track(ctx, session); err != nil {
+			t.Fatalf("Track() error = %v", err)
+		}
+	}
+
+	detail, err := tr.GetSessionDetail(ctx, "abc-111")
+	if err != nil {
+		t.Fatalf("GetSessionDetail() error = %v", err)
+	}
+	if len(detail.Messages) != 2 || detail.Messages[0].Role != "user" || detail.Session.MessageCount != 2 {
+		t.Errorf("Unexpected messages: %+v", detail.Messages)
+	}
+	if len(detail.ToolCalls) != 1 || detail.ToolCalls[0].ToolName != "Read" {
+		t.Errorf("Unexpected tool calls: %+v", detail.ToolCalls)
+	}
+	// claude-sonnet-4-5: $3 input, $15 output, $0.30 cache read per million
+	b := detail.CostBreakdown
+	if b.Input != 3 || b.Output != 1.5 || b.CacheRead != 0.6 || b.CacheWrite != 0 {
+		t.Errorf("Unexpected cost breakdown: %+v", b)
+	}
+	if diff := b.Total() - detail.Session.Cost; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Breakdown total %v != session cost %v", b.Total(), detail.Session.Cost)
+	}
+
+	if detail, err = tr.GetSessionDetail(ctx, "abc-2"); err != nil || detail.Session.ExternalID != "abc-222" {
+		t.Errorf("GetSessionDetail(prefix) = %v, %v; want abc-222", detail, err)
+	}
+	if _, err := tr.GetSessionDetail(ctx, "abc"); !errors.Is(err, ErrAmbiguousSession) {
+		t.Errorf("GetSessionDetail(ambiguous) error = %v; want ErrAmbiguousSession", err)
+	}
+	if _, err := tr.GetSessionDetail(ctx, "xyz"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("GetSessionDetail(missing) error = %v; want ErrSessionNotFound", err)
+	}
+}
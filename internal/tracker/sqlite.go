@@ -2,8 +2,12 @@ package tracker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
+
+	"github.com/ari/agent-usage/internal/pricing"
 )
 
 // Period represents the time period for usage stats
@@ -15,37 +19,76 @@ const (
 	PeriodMonth Period = "month"
 )
 
+// SortOrder selects how top models and projects are ranked
+type SortOrder string
+
+const (
+	SortSessions SortOrder = "sessions"
+	SortCost     SortOrder = "cost"
+	SortTokens   SortOrder = "tokens" // projects report only
+	SortTime     SortOrder = "time"   // projects report only
+	SortRecent   SortOrder = "recent" // projects report only
+)
+
+// orderBy returns the SQL ORDER BY expression for the sort order
+func (s SortOrder) orderBy() string {
+	if s == SortCost {
+		return "total_cost DESC, session_count DESC"
+	}
+	return "session_count DESC, total_cost DESC"
+}
+
 // UsageStatsData holds all usage statistics for display
 type UsageStatsData struct {
-	LastSession        *SessionRow
-	RecentSessions     []SessionRow
-	TopModels          []ModelUsage
-	DailySummaries     []DailySummary  // For weekly period
-	WeeklySummaries    []WeeklySummary // For monthly period
-	TotalSessionTime   int64           // in seconds
-	TotalInputTokens   int64
-	TotalOutputTokens  int64
-	TotalCacheCreation int64
-	TotalCacheRead     int64
-	TotalTokens        int64
-	TotalCost          float64
-	TotalMessages      int64
-	TotalToolCalls     int64
-	UniqueProjects     int64
-	SessionCount       int64
-	LastSyncTime       int64 // Unix timestamp of last sync for the agent
+	LastSession        *SessionRow     `json:"last_session"`
+	RecentSessions     []SessionRow    `json:"recent_sessions"`
+	TopModels          []ModelUsage    `json:"top_models"`
+	TopProjects        []ProjectUsage  `json:"top_projects"`
+	SortBy             SortOrder       `json:"sort_by"`            // ranking of TopModels and TopProjects
+	Project            string          `json:"project,omitempty"`  // project path or glob the stats are restricted to
+	DailySummaries     []DailySummary  `json:"daily_summaries"`    // For ranges longer than a day, up to 14 days
+	WeeklySummaries    []WeeklySummary `json:"weekly_summaries"`   // For ranges longer than 14 days
+	TotalSessionTime   int64           `json:"total_session_time"` // in seconds
+	TotalInputTokens   int64           `json:"total_input_tokens"`
+	TotalOutputTokens  int64           `json:"total_output_tokens"`
+	TotalCacheCreation int64           `json:"total_cache_creation"`
+	TotalCacheRead     int64           `json:"total_cache_read"`
+	TotalTokens        int64           `json:"total_tokens"`
+	TotalCost          float64         `json:"total_cost"`
+	TotalMessages      int64           `json:"total_messages"`
+	TotalToolCalls     int64           `json:"total_tool_calls"`
+	UniqueProjects     int64           `json:"unique_projects"`
+	SessionCount       int64           `json:"session_count"`
+	LastSyncTime       int64           `json:"last_sync_time"`    // Unix timestamp of last sync for the agent
+	Budgets            []BudgetStatus  `json:"budgets,omitempty"` // configured budgets in their current period, regardless of the stats period
 }
 
 // ModelUsage represents model usage count
 type ModelUsage struct {
-	Model        string
-	SessionCount int64
+	Model        string  `json:"model"`
+	SessionCount int64   `json:"session_count"`
+	TotalTokens  int64   `json:"total_tokens"`
+	TotalCost    float64 `json:"total_cost"`
+}
+
+// ProjectUsage represents usage of a single project
+type ProjectUsage struct {
+	ProjectPath  string  `json:"project_path"`
+	SessionCount int64   `json:"session_count"`
+	TotalTokens  int64   `json:"total_tokens"`
+	TotalCost    float64 `json:"total_cost"`
 }
 
 // SQLiteTracker implements the Tracker interface using SQLite
 type SQLiteTracker struct {
-	db    *DB
-	debug bool
+	db      *DB
+	debug   bool
+	pricing *pricing.Table
+}
+
+// SetPricing sets the pricing table used to compute session costs
+func (t *SQLiteTracker) SetPricing(table *pricing.Table) {
+	t.pricing = table
 }
 
 // SetDebug enables or disables debug mode
@@ -64,7 +107,7 @@ func NewSQLiteTracker(dbPath string) (*SQLiteTracker, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &SQLiteTracker{db: db}, nil
+	return &SQLiteTracker{db: db, pricing: defaultPricing}, nil
 }
 
 // Close closes the database connection
@@ -114,96 +157,323 @@ func (t *SQLiteTracker) GetUsage(agent Agent) (*UsageStats, error) {
 	}, nil
 }
 
-// TrackSession stores a parsed Codex session into the database
-func (t *SQLiteTracker) TrackSession(ctx context.Context, session *CodexSession) error {
+// Track stores a parsed session into the database.
+// Sessions that were tracked before are updated in place when the source file
+// changed since the last sync, so growing sessions pick up new tokens and messages.
+func (t *SQLiteTracker) Track(ctx context.Context, session *NormalizedSession) error {
+	session.Price(t.pricing)
+
 	// Check if session already exists
 	existing, err := t.db.GetSessionByExternalID(ctx, session.ID)
 	if err != nil {
 		return fmt.Errorf("failed to check existing session: %w", err)
 	}
-	if existing != nil {
-		if len(session.Messages) > 0 {
-			msgCount, err := t.db.GetMessageCountBySessionID(ctx, existing.ID)
+
+	if existing == nil {
+		return t.db.WithTx(ctx, func(tx *DB) error {
+			sessionID, err := tx.InsertSession(ctx, sessionRowFromNormalized(session))
 			if err != nil {
-				return fmt.Errorf("failed to check message count: %w", err)
+				return fmt.Errorf("failed to insert session: %w", err)
 			}
-			if msgCount == 0 {
-				for _, msg := range session.Messages {
-					msgRow := &MessageRow{
-						SessionID: existing.ID,
-						Role:      msg.Role,
-						Content:   msg.Content,
-						Timestamp: msg.Timestamp.Unix(),
-					}
-					if _, err := t.db.InsertMessage(ctx, msgRow); err != nil {
-						return fmt.Errorf("failed to insert message: %w", err)
+			return t.storeSessionContent(ctx, tx, sessionID, session)
+		})
+	}
+
+	if existing.Fingerprint != session.Fingerprint {
+		err := t.db.WithTx(ctx, func(tx *DB) error {
+			if err := tx.UpdateSession(ctx, existing.ID, sessionRowFromNormalized(session)); err != nil {
+				return err
+			}
+			if err := tx.DeleteSessionContent(ctx, existing.ID); err != nil {
+				return err
+			}
+			return t.storeSessionContent(ctx, tx, existing.ID, session)
+		})
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrSessionUpdated, session.ID)
+	}
+
+	if len(session.Messages) > 0 {
+		msgCount, err := t.db.GetMessageCountBySessionID(ctx, existing.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check message count: %w", err)
+		}
+		if msgCount == 0 {
+			if err := insertMessages(ctx, t.db, existing.ID, session.Messages); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %s", ErrSessionBackfilled, session.ID)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrSessionAlreadyTracked, session.ID)
+}
+
+// Reprice recomputes the cost of every stored session with the current pricing
+// table and returns the number of sessions whose cost changed
+func (t *SQLiteTracker) Reprice(ctx context.Context) (int, error) {
+	sessions, err := t.db.GetAllSessions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	err = t.db.WithTx(ctx, func(tx *DB) error {
+		for _, s := range sessions {
+			cost := t.pricing.Cost(s.Provider, s.Model, pricing.Usage{
+				Input:      s.InputTokens,
+				Output:     s.OutputTokens,
+				CacheWrite: s.CacheCreationTokens,
+				CacheRead:  s.CacheReadTokens,
+				Reasoning:  s.ReasoningTokens,
+			})
+
+			// Sessions split by model are priced at the rate of each model
+			models, err := tx.GetSessionModels(ctx, s.ID)
+			if err != nil {
+				return err
+			}
+			if len(models) > 0 {
+				cost = 0
+			}
+			for _, m := range models {
+				modelCost := t.pricing.Cost(s.Provider, m.Model, pricing.Usage{
+					Input:      m.InputTokens,
+					Output:     m.OutputTokens,
+					CacheWrite: m.CacheCreationTokens,
+					CacheRead:  m.CacheReadTokens,
+					Reasoning:  m.ReasoningTokens,
+				})
+				if modelCost != m.Cost {
+					if err := tx.UpdateSessionModelCost(ctx, s.ID, m.Model, modelCost); err != nil {
+						return err
 					}
 				}
-				return fmt.Errorf("%w: %s", ErrSessionBackfilled, session.ID)
+				cost += modelCost
 			}
+
+			if cost == s.Cost {
+				continue
+			}
+			if err := tx.UpdateSessionCost(ctx, s.ID, cost); err != nil {
+				return err
+			}
+			updated++
 		}
-		return fmt.Errorf("%w: %s", ErrSessionAlreadyTracked, session.ID)
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
+	return updated, nil
+}
 
-	// Convert started_at to unix timestamp
-	startedAt := session.StartedAt.Unix()
+// storeSessionContent stores the content of a session. Turns already counted by
+// another session are dropped, and sessions whose totals are the sum of their
+// turns get their stored totals and cost recomputed from the remaining turns.
+func (t *SQLiteTracker) storeSessionContent(ctx context.Context, tx *DB, sessionID int64, session *NormalizedSession) error {
+	if err := t.dropCopiedTurns(ctx, tx, session); err != nil {
+		return err
+	}
+	if err := insertSessionContent(ctx, tx, sessionID, session); err != nil {
+		return err
+	}
 
-	var endedAt *int64
-	if session.EndedAt != nil {
-		ts := session.EndedAt.Unix()
-		endedAt = &ts
+	if session.TokensFromTurns {
+		models, err := tx.SumTurnTokens(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		session.Tokens = TokenUsage{}
+		for _, tokens := range models {
+			session.Tokens = session.Tokens.add(tokens)
+		}
+		session.ModelTokens = models
+		session.Price(t.pricing)
+		if err := tx.UpdateSession(ctx, sessionID, sessionRowFromNormalized(session)); err != nil {
+			return err
+		}
 	}
+	return tx.ReplaceSessionModels(ctx, sessionID, t.sessionModelRows(session))
+}
 
-	sessionRow := &SessionRow{
-		ExternalID:          session.ID,
-		Source:              "codex",
-		ProjectPath:         session.ProjectPath,
-		Model:               session.Model,
-		Provider:            session.Provider,
-		StartedAt:           startedAt,
-		EndedAt:             endedAt,
-		InputTokens:         int64(session.Tokens.Input),
-		OutputTokens:        int64(session.Tokens.Output),
-		CacheCreationTokens: int64(session.Tokens.CacheCreation),
-		CacheReadTokens:     int64(session.Tokens.CacheRead),
-		ReasoningTokens:     int64(session.Tokens.Reasoning),
-		TotalTokens:         int64(session.Tokens.Total),
-		Cost:                session.Cost,
+// sessionModelRows prices the usage of each model of a session
+func (t *SQLiteTracker) sessionModelRows(session *NormalizedSession) []SessionModelRow {
+	var rows []SessionModelRow
+	for model, tokens := range session.modelUsage() {
+		rows = append(rows, SessionModelRow{
+			Model:               model,
+			InputTokens:         int64(tokens.Input),
+			OutputTokens:        int64(tokens.Output),
+			CacheCreationTokens: int64(tokens.CacheCreation),
+			CacheReadTokens:     int64(tokens.CacheRead),
+			ReasoningTokens:     int64(tokens.Reasoning),
+			TotalTokens:         int64(tokens.Total),
+			Cost:                t.pricing.Cost(session.Provider, model, tokens.pricingUsage()),
+		})
+	}
+	return rows
+}
+
+// dropCopiedTurns removes the turns whose usage key belongs to another
+// session, such as entries copied into a resumed Claude session, and claims the
+// keys of the remaining turns for this session. Sessions the keys were taken
+// from are recounted.
+func (t *SQLiteTracker) dropCopiedTurns(ctx context.Context, tx *DB, session *NormalizedSession) error {
+	kept := session.Turns[:0]
+	var losers []string
+	for _, turn := range session.Turns {
+		if turn.UsageKey != "" {
+			owner, previous, err := tx.ClaimUsageKey(ctx, turn.UsageKey, session.ID, turn.Timestamp.Unix())
+			if err != nil {
+				return err
+			}
+			if previous != "" && !slices.Contains(losers, previous) {
+				losers = append(losers, previous)
+			}
+			if owner != session.ID {
+				continue
+			}
+		}
+		kept = append(kept, turn)
 	}
+	session.Turns = kept
 
-	sessionID, err := t.db.InsertSession(ctx, sessionRow)
+	for _, externalID := range losers {
+		if err := t.recountSession(ctx, tx, externalID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recountSession recomputes the totals, per-model usage and cost of a stored
+// session from its turns after some of them moved to another session
+func (t *SQLiteTracker) recountSession(ctx context.Context, tx *DB, externalID string) error {
+	row, err := tx.GetSessionByExternalID(ctx, externalID)
+	if err != nil || row == nil {
+		return err
+	}
+	models, err := tx.SumTurnTokens(ctx, row.ID)
 	if err != nil {
-		return fmt.Errorf("failed to insert session: %w", err)
+		return err
 	}
+	session := &NormalizedSession{Provider: row.Provider, Model: row.Model, ModelTokens: models}
+	for _, tokens := range models {
+		session.Tokens = session.Tokens.add(tokens)
+	}
+	session.Price(t.pricing)
+	if err := tx.UpdateSessionTotals(ctx, row.ID, session.Tokens, session.Cost); err != nil {
+		return err
+	}
+	return tx.ReplaceSessionModels(ctx, row.ID, t.sessionModelRows(session))
+}
 
-	// Insert messages
-	for _, msg := range session.Messages {
+// insertSessionContent stores the messages, tool calls and turns of a session
+func insertSessionContent(ctx context.Context, db *DB, sessionID int64, session *NormalizedSession) error {
+	if err := insertMessages(ctx, db, sessionID, session.Messages); err != nil {
+		return err
+	}
+
+	// Insert tool calls
+	for _, tc := range session.ToolCalls {
+		tcRow := &ToolCallRow{
+			SessionID:  sessionID,
+			ToolName:   tc.ToolName,
+			Arguments:  tc.Arguments,
+			Result:     tc.Result,
+			Timestamp:  tc.Timestamp.Unix(),
+			CallID:     tc.CallID,
+			IsError:    tc.IsError,
+			DurationMs: tc.Duration.Milliseconds(),
+		}
+		if tc.ExitCode != nil {
+			code := int64(*tc.ExitCode)
+			tcRow.ExitCode = &code
+		}
+		if tc.ResultOnly {
+			if err := db.UpdateToolCallResult(ctx, tcRow); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := db.InsertToolCall(ctx, tcRow); err != nil {
+			return fmt.Errorf("failed to insert tool call: %w", err)
+		}
+	}
+
+	for _, turn := range session.Turns {
+		turnRow := &TurnRow{
+			SessionID:           sessionID,
+			TurnIndex:           turn.Index,
+			Timestamp:           turn.Timestamp.Unix(),
+			Model:               turn.Model,
+			InputTokens:         int64(turn.Tokens.Input),
+			OutputTokens:        int64(turn.Tokens.Output),
+			CacheCreationTokens: int64(turn.Tokens.CacheCreation),
+			CacheReadTokens:     int64(turn.Tokens.CacheRead),
+			ReasoningTokens:     int64(turn.Tokens.Reasoning),
+			TotalTokens:         int64(turn.Tokens.Total),
+			LatencyMs:           turn.Latency.Milliseconds(),
+			UsageKey:            turn.UsageKey,
+		}
+		if _, err := db.InsertTurn(ctx, turnRow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertMessages stores the messages of a session
+func insertMessages(ctx context.Context, db *DB, sessionID int64, messages []Message) error {
+	for _, msg := range messages {
 		msgRow := &MessageRow{
 			SessionID: sessionID,
 			Role:      msg.Role,
 			Content:   msg.Content,
 			Timestamp: msg.Timestamp.Unix(),
 		}
-		if _, err := t.db.InsertMessage(ctx, msgRow); err != nil {
+		if _, err := db.InsertMessage(ctx, msgRow); err != nil {
 			return fmt.Errorf("failed to insert message: %w", err)
 		}
 	}
+	return nil
+}
 
-	// Insert tool calls
-	for _, tc := range session.ToolCalls {
-		tcRow := &ToolCallRow{
-			SessionID: sessionID,
-			ToolName:  tc.ToolName,
-			Arguments: tc.Arguments,
-			Result:    tc.Result,
-			Timestamp: tc.Timestamp.Unix(),
-		}
-		if _, err := t.db.InsertToolCall(ctx, tcRow); err != nil {
-			return fmt.Errorf("failed to insert tool call: %w", err)
+// sessionRowFromNormalized converts a parsed session into a database row
+func sessionRowFromNormalized(session *NormalizedSession) *SessionRow {
+	var endedAt *int64
+	if session.EndedAt != nil {
+		ts := session.EndedAt.Unix()
+		endedAt = &ts
+	}
+
+	var metadata string
+	if len(session.Metadata) > 0 {
+		if data, err := json.Marshal(session.Metadata); err == nil {
+			metadata = string(data)
 		}
 	}
 
-	return nil
+	return &SessionRow{
+		ExternalID:          session.ID,
+		Source:              string(session.Source),
+		ProjectPath:         session.ProjectPath,
+		Model:               session.Model,
+		Provider:            session.Provider,
+		StartedAt:           session.StartedAt.Unix(),
+		EndedAt:             endedAt,
+		InputTokens:         int64(session.Tokens.Input),
+		OutputTokens:        int64(session.Tokens.Output),
+		CacheCreationTokens: int64(session.Tokens.CacheCreation),
+		CacheReadTokens:     int64(session.Tokens.CacheRead),
+		ReasoningTokens:     int64(session.Tokens.Reasoning),
+		TotalTokens:         int64(session.Tokens.Total),
+		Cost:                session.Cost,
+		Metadata:            metadata,
+		Fingerprint:         session.Fingerprint,
+	}
 }
 
 // GetSessions returns all tracked sessions
@@ -221,57 +491,49 @@ func (t *SQLiteTracker) GetToolCalls(ctx context.Context, sessionID int64) ([]To
 	return t.db.GetToolCallsBySessionID(ctx, sessionID)
 }
 
-// GetUsageStats returns usage statistics for an agent within a period
-func (t *SQLiteTracker) GetUsageStats(ctx context.Context, agent Agent, period Period) (*UsageStatsData, error) {
-	// Calculate the time filter
-	var startTime time.Time
-	now := time.Now()
-	switch period {
-	case PeriodDay:
-		startTime = now.AddDate(0, 0, -1)
-	case PeriodWeek:
-		startTime = now.AddDate(0, 0, -7) // last 7 days
-	case PeriodMonth:
-		startTime = now.AddDate(0, 0, -30) // last 30 days
-	default:
-		startTime = now.AddDate(0, 0, -1) // default to day
-	}
-
-	startTimestamp := startTime.Unix()
+// GetUsageStats returns usage statistics for an agent within a time range
+func (t *SQLiteTracker) GetUsageStats(ctx context.Context, agent Agent, r TimeRange, project string, sort SortOrder) (*UsageStatsData, error) {
+	start, end := r.bounds()
 	source := string(agent)
 
 	// Get last session
-	lastSession, err := t.db.GetLastSession(ctx, source, startTimestamp)
+	lastSession, err := t.db.GetLastSession(ctx, source, start, end, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get last session: %w", err)
 	}
 
 	// Get top 3 models
-	topModels, err := t.db.GetTopModels(ctx, source, startTimestamp, 3)
+	topModels, err := t.db.GetTopModels(ctx, source, start, end, project, 3, sort)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get top models: %w", err)
 	}
 
+	// Get top 3 projects
+	topProjects, err := t.db.GetTopProjects(ctx, source, start, end, project, 3, sort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top projects: %w", err)
+	}
+
 	// Get aggregated stats
-	stats, err := t.db.GetAggregatedStats(ctx, source, startTimestamp)
+	stats, err := t.db.GetAggregatedStats(ctx, source, start, end, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get aggregated stats: %w", err)
 	}
 
 	// Get message count
-	msgCount, err := t.db.GetMessageCount(ctx, source, startTimestamp)
+	msgCount, err := t.db.GetMessageCount(ctx, source, start, end, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get message count: %w", err)
 	}
 
 	// Get tool call count
-	toolCallCount, err := t.db.GetToolCallCount(ctx, source, startTimestamp)
+	toolCallCount, err := t.db.GetToolCallCount(ctx, source, start, end, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tool call count: %w", err)
 	}
 
 	// Get unique projects
-	uniqueProjects, err := t.db.GetUniqueProjects(ctx, source, startTimestamp)
+	uniqueProjects, err := t.db.GetUniqueProjects(ctx, source, start, end, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get unique projects: %w", err)
 	}
@@ -280,15 +542,15 @@ func (t *SQLiteTracker) GetUsageStats(ctx context.Context, agent Agent, period P
 	var dailySummaries []DailySummary
 	var weeklySummaries []WeeklySummary
 
-	if period == PeriodWeek {
-		dailySummaries, err = t.db.GetDailySummaries(ctx, source, startTimestamp)
+	if r.Duration() > 24*time.Hour && r.Duration() <= 14*24*time.Hour {
+		dailySummaries, err = t.db.GetDailySummaries(ctx, source, start, end, project, r.Calendar)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get daily summaries: %w", err)
 		}
 	}
 
-	if period == PeriodMonth {
-		weeklySummaries, err = t.db.GetWeeklySummaries(ctx, source, startTimestamp)
+	if r.Duration() > 14*24*time.Hour {
+		weeklySummaries, err = t.db.GetWeeklySummaries(ctx, source, start, end, project, r.Calendar)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get weekly summaries: %w", err)
 		}
@@ -303,6 +565,9 @@ func (t *SQLiteTracker) GetUsageStats(ctx context.Context, agent Agent, period P
 	return &UsageStatsData{
 		LastSession:        lastSession,
 		TopModels:          topModels,
+		TopProjects:        topProjects,
+		SortBy:             sort,
+		Project:            project,
 		DailySummaries:     dailySummaries,
 		WeeklySummaries:    weeklySummaries,
 		TotalSessionTime:   stats.TotalSessionTime,
@@ -320,164 +585,63 @@ func (t *SQLiteTracker) GetUsageStats(ctx context.Context, agent Agent, period P
 	}, nil
 }
 
-// GetSessionsInPeriod returns all sessions within a time period for debug
-func (t *SQLiteTracker) GetSessionsInPeriod(ctx context.Context, agent Agent, period Period) ([]SessionRow, error) {
-	// Calculate the time filter
-	var startTime time.Time
-	now := time.Now()
-	switch period {
-	case PeriodDay:
-		startTime = now.AddDate(0, 0, -1)
-	case PeriodWeek:
-		startTime = now.AddDate(0, 0, -7)
-	case PeriodMonth:
-		startTime = now.AddDate(0, 0, -30)
-	default:
-		startTime = now.AddDate(0, 0, -1)
-	}
-
-	startTimestamp := startTime.Unix()
+// GetSessionsInPeriod returns all sessions within a time range for debug
+func (t *SQLiteTracker) GetSessionsInPeriod(ctx context.Context, agent Agent, r TimeRange, project string) ([]SessionRow, error) {
+	start, end := r.bounds()
 	source := string(agent)
 
-	return t.db.GetSessionsInPeriod(ctx, source, startTimestamp)
-}
-
-// TrackClaudeSession stores a parsed Claude session into the database
-func (t *SQLiteTracker) TrackClaudeSession(ctx context.Context, session *ClaudeSession) error {
-	// Check if session already exists
-	existing, err := t.db.GetSessionByExternalID(ctx, session.ID)
-	if err != nil {
-		return fmt.Errorf("failed to check existing session: %w", err)
-	}
-	if existing != nil {
-		if len(session.Messages) > 0 {
-			msgCount, err := t.db.GetMessageCountBySessionID(ctx, existing.ID)
-			if err != nil {
-				return fmt.Errorf("failed to check message count: %w", err)
-			}
-			if msgCount == 0 {
-				for _, msg := range session.Messages {
-					msgRow := &MessageRow{
-						SessionID: existing.ID,
-						Role:      msg.Role,
-						Content:   msg.Content,
-						Timestamp: msg.Timestamp.Unix(),
-					}
-					if _, err := t.db.InsertMessage(ctx, msgRow); err != nil {
-						return fmt.Errorf("failed to insert message: %w", err)
-					}
-				}
-				return fmt.Errorf("%w: %s", ErrSessionBackfilled, session.ID)
-			}
-		}
-		return fmt.Errorf("%w: %s", ErrSessionAlreadyTracked, session.ID)
-	}
-
-	// Convert started_at to unix timestamp
-	startedAt := session.StartedAt.Unix()
-
-	var endedAt *int64
-	if session.EndedAt != nil {
-		ts := session.EndedAt.Unix()
-		endedAt = &ts
-	}
-
-	sessionRow := &SessionRow{
-		ExternalID:          session.ID,
-		Source:              "claude",
-		ProjectPath:         session.ProjectPath,
-		Model:               session.Model,
-		Provider:            session.Provider,
-		StartedAt:           startedAt,
-		EndedAt:             endedAt,
-		InputTokens:         int64(session.Tokens.Input),
-		OutputTokens:        int64(session.Tokens.Output),
-		CacheCreationTokens: int64(session.Tokens.CacheCreation),
-		CacheReadTokens:     int64(session.Tokens.CacheRead),
-		ReasoningTokens:     int64(session.Tokens.Reasoning),
-		TotalTokens:         int64(session.Tokens.Total),
-		Cost:                session.Cost,
-	}
-
-	sessionID, err := t.db.InsertSession(ctx, sessionRow)
-	if err != nil {
-		return fmt.Errorf("failed to insert session: %w", err)
-	}
-
-	// Insert messages
-	for _, msg := range session.Messages {
-		msgRow := &MessageRow{
-			SessionID: sessionID,
-			Role:      msg.Role,
-			Content:   msg.Content,
-			Timestamp: msg.Timestamp.Unix(),
-		}
-		if _, err := t.db.InsertMessage(ctx, msgRow); err != nil {
-			return fmt.Errorf("failed to insert message: %w", err)
-		}
-	}
-
-	return nil
+	return t.db.GetSessionsInPeriod(ctx, source, start, end, project)
 }
 
 // GetUsageStatsAll returns combined usage stats for all agents
-func (t *SQLiteTracker) GetUsageStatsAll(ctx context.Context, period Period) (*UsageStatsData, error) {
-	// Calculate the time filter
-	var startTime time.Time
-	now := time.Now()
-	switch period {
-	case PeriodDay:
-		startTime = now.AddDate(0, 0, -1)
-	case PeriodWeek:
-		startTime = now.AddDate(0, 0, -7)
-	case PeriodMonth:
-		startTime = now.AddDate(0, 0, -30)
-	default:
-		startTime = now.AddDate(0, 0, -1)
-	}
-
-	startTimestamp := startTime.Unix()
+func (t *SQLiteTracker) GetUsageStatsAll(ctx context.Context, r TimeRange, project string, sort SortOrder) (*UsageStatsData, error) {
+	start, end := r.bounds()
 
 	// Get aggregated stats for all sources
-	stats, err := t.db.GetAggregatedStatsAll(ctx, startTimestamp)
+	stats, err := t.db.GetAggregatedStatsAll(ctx, start, end, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get aggregated stats: %w", err)
 	}
 
 	// Get message count across all sources
-	msgCount, err := t.db.GetMessageCountAll(ctx, startTimestamp)
+	msgCount, err := t.db.GetMessageCountAll(ctx, start, end, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get message count: %w", err)
 	}
 
 	// Get tool call count across all sources
-	toolCallCount, err := t.db.GetToolCallCountAll(ctx, startTimestamp)
+	toolCallCount, err := t.db.GetToolCallCountAll(ctx, start, end, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tool call count: %w", err)
 	}
 
 	// Get top 3 models across all sources
-	topModels, err := t.db.GetTopModelsAll(ctx, startTimestamp, 3)
+	topModels, err := t.db.GetTopModelsAll(ctx, start, end, project, 3, sort)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get top models: %w", err)
 	}
 
+	// Get top 3 projects across all sources
+	topProjects, err := t.db.GetTopProjectsAll(ctx, start, end, project, 3, sort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top projects: %w", err)
+	}
+
 	// Get unique projects
-	uniqueProjects, err := t.db.GetUniqueProjectsAll(ctx, startTimestamp)
+	uniqueProjects, err := t.db.GetUniqueProjectsAll(ctx, start, end, project)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get unique projects: %w", err)
 	}
 
 	// Get recent sessions (last 5)
-	recentSessions, err := t.db.GetRecentSessions(ctx, 5)
+	recentSessions, err := t.db.GetRecentSessions(ctx, project, 5)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recent sessions: %w", err)
 	}
 
-	// Get last sync times for all enabled agents and use the most recent
+	// Get last sync times for all registered agents and use the most recent
 	var lastSyncTime int64
-	agents := []string{"codex", "claude"}
-	for _, agent := range agents {
+	for _, agent := range ProviderNames() {
 		syncTime, err := t.db.GetLastSyncTime(ctx, agent)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get last sync time for %s: %w", agent, err)
@@ -489,6 +653,9 @@ func (t *SQLiteTracker) GetUsageStatsAll(ctx context.Context, period Period) (*U
 
 	return &UsageStatsData{
 		TopModels:          topModels,
+		TopProjects:        topProjects,
+		SortBy:             sort,
+		Project:            project,
 		RecentSessions:     recentSessions,
 		TotalSessionTime:   stats.TotalSessionTime,
 		TotalInputTokens:   stats.TotalInputTokens,
@@ -506,22 +673,8 @@ func (t *SQLiteTracker) GetUsageStatsAll(ctx context.Context, period Period) (*U
 }
 
 // GetPerAgentStats returns per-agent breakdown
-func (t *SQLiteTracker) GetPerAgentStats(ctx context.Context, period Period) ([]PerAgentStats, error) {
-	// Calculate the time filter
-	var startTime time.Time
-	now := time.Now()
-	switch period {
-	case PeriodDay:
-		startTime = now.AddDate(0, 0, -1)
-	case PeriodWeek:
-		startTime = now.AddDate(0, 0, -7)
-	case PeriodMonth:
-		startTime = now.AddDate(0, 0, -30)
-	default:
-		startTime = now.AddDate(0, 0, -1)
-	}
-
-	startTimestamp := startTime.Unix()
-
-	return t.db.GetPerAgentStats(ctx, startTimestamp)
+func (t *SQLiteTracker) GetPerAgentStats(ctx context.Context, r TimeRange, project string) ([]PerAgentStats, error) {
+	start, end := r.bounds()
+
+	return t.db.GetPerAgentStats(ctx, start, end, project)
 }
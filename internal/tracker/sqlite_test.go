@@ -0,0 +1,173 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ari/agent-usage/internal/pricing"
+)
+
+func newTestTracker(t *testing.T) *SQLiteTracker {
+	t.Helper()
+	tr, err := NewSQLiteTracker(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test tracker: %v", err)
+	}
+	t.Cleanup(func() { tr.Close() })
+	return tr
+}
+
+func TestTrackStoresNormalizedSession(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+
+	start := time.Date(2026, 2, 24, 22, 55, 0, 0, time.UTC)
+	end := start.Add(10 * time.Minute)
+	session := newNormalizedSession(AgentClaudeCode)
+	session.ID = "sess-normalized"
+	session.ProjectPath = "/test/project"
+	session.Model = "claude-sonnet-4-5"
+	session.StartedAt = start
+	session.EndedAt = &end
+	session.Tokens = TokenUsage{Input: 100, Output: 50, Total: 150}
+	session.Metadata["git_branch"] = "main"
+	session.Messages = append(session.Messages, Message{Role: "user", Content: "hi", Timestamp: start})
+	session.ToolCalls = append(session.ToolCalls, ToolCall{ToolName: "Bash", Arguments: `{"command":"ls"}`, Timestamp: start})
+
+	if err := tr.Track(ctx, session); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+
+	row, err := tr.db.GetSessionByExternalID(ctx, "sess-normalized")
+	if err != nil || row == nil {
+		t.Fatalf("GetSessionByExternalID() = %v, %v", row, err)
+	}
+	if row.Source != "claude" {
+		t.Errorf("Source = %s; want claude", row.Source)
+	}
+	if row.Metadata != `{"git_branch":"main"}` {
+		t.Errorf("Metadata = %s; want git_branch json", row.Metadata)
+	}
+
+	// Claude sessions store tool calls through the shared Track path
+	toolCalls, err := tr.GetToolCalls(ctx, row.ID)
+	if err != nil {
+		t.Fatalf("GetToolCalls() error = %v", err)
+	}
+	if len(toolCalls) != 1 || toolCalls[0].ToolName != "Bash" {
+		t.Errorf("toolCalls = %+v; want one Bash call", toolCalls)
+	}
+
+	// Tracking the same session again is reported as already tracked
+	if err := tr.Track(ctx, session); !errors.Is(err, ErrSessionAlreadyTracked) {
+		t.Errorf("second Track() error = %v; want ErrSessionAlreadyTracked", err)
+	}
+}
+
+func TestTrackUpdatesGrowingSession(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	first := `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-grow","cwd":"/p","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-grow","message":{"model":"claude-3","role":"assistant","content":"Hi","usage":{"input_tokens":10,"output_tokens":20}}}
+`
+	if err := os.WriteFile(sessionFile, []byte(first), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ParseClaudeSession(sessionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Track(ctx, session); err != nil {
+		t.Fatalf("first Track() error = %v", err)
+	}
+
+	// The session keeps going after the first sync
+	more := `{"type":"user","timestamp":"2026-02-26T10:05:00Z","sessionId":"sess-grow","input":"More"}
+{"type":"assistant","timestamp":"2026-02-26T10:05:09Z","sessionId":"sess-grow","message":{"model":"claude-3","role":"assistant","content":"Sure","usage":{"input_tokens":30,"output_tokens":40}}}
+`
+	f, err := os.OpenFile(sessionFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(more)
+	f.Close()
+
+	session, err = ParseClaudeSession(sessionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Track(ctx, session); !errors.Is(err, ErrSessionUpdated) {
+		t.Fatalf("second Track() error = %v; want ErrSessionUpdated", err)
+	}
+
+	row, err := tr.db.GetSessionByExternalID(ctx, "sess-grow")
+	if err != nil || row == nil {
+		t.Fatalf("GetSessionByExternalID() = %v, %v", row, err)
+	}
+	if row.InputTokens != 40 || row.OutputTokens != 60 {
+		t.Errorf("tokens = %d/%d; want 40/60", row.InputTokens, row.OutputTokens)
+	}
+	if row.EndedAt == nil || *row.EndedAt != time.Date(2026, 2, 26, 10, 5, 9, 0, time.UTC).Unix() {
+		t.Errorf("EndedAt = %v; want 10:05:09", row.EndedAt)
+	}
+
+	// Messages are replaced rather than duplicated
+	msgCount, err := tr.db.GetMessageCountBySessionID(ctx, row.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgCount != 4 {
+		t.Errorf("message count = %d; want 4", msgCount)
+	}
+
+	// Unchanged files are reported as already tracked
+	if err := tr.Track(ctx, session); !errors.Is(err, ErrSessionAlreadyTracked) {
+		t.Errorf("third Track() error = %v; want ErrSessionAlreadyTracked", err)
+	}
+}
+
+func TestReprice(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+
+	start := time.Date(2026, 2, 24, 22, 55, 0, 0, time.UTC)
+	session := newNormalizedSession(AgentCodex)
+	session.ID = "sess-reprice"
+	session.Model = "gpt-5.2"
+	session.Provider = "openai"
+	session.StartedAt = start
+	session.EndedAt = &start
+	session.Tokens = TokenUsage{Input: 1_000_000, Output: 100_000, Total: 1_100_000}
+	if err := tr.Track(ctx, session); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+
+	// Built-in gpt-5 rate: 1.25 input + 0.1 * 10 output
+	row, _ := tr.db.GetSessionByExternalID(ctx, "sess-reprice")
+	if row.Cost != 2.25 {
+		t.Fatalf("Cost = %v; want 2.25", row.Cost)
+	}
+
+	input := 2.0
+	tr.SetPricing(pricing.Default().WithOverrides(map[string]pricing.Override{"gpt-5.2": {Input: &input}}))
+	updated, err := tr.Reprice(ctx)
+	if err != nil || updated != 1 {
+		t.Fatalf("Reprice() = %d, %v; want 1, nil", updated, err)
+	}
+	row, _ = tr.db.GetSessionByExternalID(ctx, "sess-reprice")
+	if row.Cost != 3.0 {
+		t.Errorf("Cost = %v; want 3.0", row.Cost)
+	}
+
+	// A second run with the same table changes nothing
+	if updated, _ := tr.Reprice(ctx); updated != 0 {
+		t.Errorf("Reprice() = %d; want 0", updated)
+	}
+}
@@ -0,0 +1,442 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// SessionParser incrementally parses the JSONL lines of a single session file
+type SessionParser interface {
+	// ParseLine consumes one line of the session file
+	ParseLine(line []byte)
+	// Session returns the session parsed so far. Messages, tool calls and turns
+	// only contain items from lines consumed by this parser; totals, timestamps
+	// and metadata include everything restored from the parser state.
+	Session() *NormalizedSession
+	// State returns the state needed to resume parsing after the last line
+	State() ([]byte, error)
+}
+
+// errSessionMissing signals that an incremental sync found no stored session to append to
+var errSessionMissing = errors.New("stored session missing")
+
+// baseParser holds the session being built and the state shared by all parsers
+type baseParser struct {
+	session        *NormalizedSession
+	firstTimestamp time.Time
+	lastTimestamp  time.Time
+	lastUserTime   time.Time            // timestamp of the latest user message, for turn latency
+	turnOffset     int                  // turns emitted by earlier parser runs
+	pendingCalls   map[string]time.Time // start times of tool calls without a result yet
+	callIndex      map[string]int       // position in session.ToolCalls of calls parsed in this run
+}
+
+// baseState is the persisted form of baseParser
+type baseState struct {
+	Session        *NormalizedSession   `json:"session"`
+	FirstTimestamp time.Time            `json:"first_timestamp"`
+	LastTimestamp  time.Time            `json:"last_timestamp"`
+	LastUserTime   time.Time            `json:"last_user_time"`
+	TurnCount      int                  `json:"turn_count"`
+	PendingCalls   map[string]time.Time `json:"pending_calls,omitempty"`
+	Extra          json.RawMessage      `json:"extra,omitempty"`
+}
+
+func newBaseParser(source Agent) baseParser {
+	return baseParser{
+		session:      newNormalizedSession(source),
+		pendingCalls: make(map[string]time.Time),
+		callIndex:    make(map[string]int),
+	}
+}
+
+// observe records the timestamp of a parsed entry
+func (p *baseParser) observe(ts time.Time) {
+	if ts.IsZero() {
+		return
+	}
+	if p.firstTimestamp.IsZero() {
+		p.firstTimestamp = ts
+	}
+	p.lastTimestamp = ts
+}
+
+// observeUser records the timestamp of a user message, the start of the next turn's latency
+func (p *baseParser) observeUser(ts time.Time) {
+	if !ts.IsZero() {
+		p.lastUserTime = ts
+	}
+}
+
+// addTurn appends a turn, continuing the numbering of earlier parser runs, and returns it
+func (p *baseParser) addTurn(ts time.Time, model string, tokens TokenUsage) *Turn {
+	var latency time.Duration
+	if !p.lastUserTime.IsZero() && ts.After(p.lastUserTime) {
+		latency = ts.Sub(p.lastUserTime)
+	}
+	p.session.Turns = append(p.session.Turns, Turn{
+		Index:     p.turnOffset + len(p.session.Turns),
+		Timestamp: ts,
+		Model:     model,
+		Tokens:    tokens,
+		Latency:   latency,
+	})
+	return &p.session.Turns[len(p.session.Turns)-1]
+}
+
+// addToolCall appends a tool call whose result may follow later
+func (p *baseParser) addToolCall(call ToolCall) {
+	if call.CallID != "" {
+		p.callIndex[call.CallID] = len(p.session.ToolCalls)
+		p.pendingCalls[call.CallID] = call.Timestamp
+	}
+	p.session.ToolCalls = append(p.session.ToolCalls, call)
+}
+
+// addToolResult sets the result, exit code, error flag and duration of the
+// tool call with the given ID. Without a known duration, the time since the
+// call is used. Results of calls emitted by an earlier parser run are appended
+// as result-only tool calls.
+func (p *baseParser) addToolResult(ts time.Time, callID string, result ToolCall) {
+	if start, ok := p.pendingCalls[callID]; ok {
+		if result.Duration == 0 && ts.After(start) {
+			result.Duration = ts.Sub(start)
+		}
+		delete(p.pendingCalls, callID)
+	}
+
+	idx, ok := p.callIndex[callID]
+	if !ok {
+		result.CallID = callID
+		result.Timestamp = ts
+		result.ResultOnly = true
+		p.session.ToolCalls = append(p.session.ToolCalls, result)
+		return
+	}
+	call := &p.session.ToolCalls[idx]
+	call.Result = result.Result
+	call.ExitCode = result.ExitCode
+	call.IsError = result.IsError
+	call.Duration = result.Duration
+}
+
+// finish sets the session time range from the observed timestamps
+func (p *baseParser) finish() {
+	p.session.StartedAt = p.firstTimestamp
+	lastTimestamp := p.lastTimestamp
+	p.session.EndedAt = &lastTimestamp
+}
+
+// saveState serializes the parser state together with parser-specific extra state
+func (p *baseParser) saveState(extra any) ([]byte, error) {
+	header := *p.session
+	header.Messages = nil
+	header.ToolCalls = nil
+	header.Turns = nil
+	header.Fingerprint = ""
+
+	state := baseState{
+		Session:        &header,
+		FirstTimestamp: p.firstTimestamp,
+		LastTimestamp:  p.lastTimestamp,
+		LastUserTime:   p.lastUserTime,
+		TurnCount:      p.turnOffset + len(p.session.Turns),
+		PendingCalls:   p.pendingCalls,
+	}
+	if extra != nil {
+		data, err := json.Marshal(extra)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode parser state: %w", err)
+		}
+		state.Extra = data
+	}
+	return json.Marshal(state)
+}
+
+// loadState restores the parser state saved by saveState
+func (p *baseParser) loadState(data []byte, extra any) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var state baseState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode parser state: %w", err)
+	}
+	if state.Session != nil {
+		source := p.session.Source
+		p.session = state.Session
+		p.session.Source = source
+		p.session.Messages = make([]Message, 0)
+		p.session.ToolCalls = make([]ToolCall, 0)
+		p.session.Turns = make([]Turn, 0)
+		if p.session.Metadata == nil {
+			p.session.Metadata = make(map[string]string)
+		}
+	}
+	p.firstTimestamp = state.FirstTimestamp
+	p.lastTimestamp = state.LastTimestamp
+	p.lastUserTime = state.LastUserTime
+	p.turnOffset = state.TurnCount
+	if state.PendingCalls != nil {
+		p.pendingCalls = state.PendingCalls
+	}
+
+	if extra != nil && len(state.Extra) > 0 {
+		if err := json.Unmarshal(state.Extra, extra); err != nil {
+			return fmt.Errorf("failed to decode parser state: %w", err)
+		}
+	}
+	return nil
+}
+
+// ParseSessionFile parses a complete session file with the provider's parser
+func ParseSessionFile(p AgentProvider, path string) (*NormalizedSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	parser, err := p.NewParser(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range splitLines(string(data)) {
+		parser.ParseLine([]byte(line))
+	}
+
+	session := parser.Session()
+	session.Fingerprint = contentFingerprint(data)
+	return session, nil
+}
+
+// SyncFile brings the stored session of a single file up to date.
+// Files that did not change since the last sync are skipped without being read,
+// and files that only grew are parsed from the previously stored byte offset.
+// The returned error follows Track: nil for new sessions, ErrSessionUpdated for
+// changed sessions and ErrSessionAlreadyTracked for unchanged files, and is
+// ErrNoSession for files without a session ID, such as Claude summary files.
+// Other errors are counted as sync errors of the agent.
+func (t *SQLiteTracker) SyncFile(ctx context.Context, p AgentProvider, path string) error {
+	err := t.syncFile(ctx, p, path)
+	if err != nil && !errors.Is(err, ErrSessionUpdated) && !errors.Is(err, ErrSessionBackfilled) &&
+		!errors.Is(err, ErrSessionAlreadyTracked) && !errors.Is(err, ErrNoSession) {
+		if countErr := t.db.AddSyncError(ctx, string(p.Name())); countErr != nil {
+			return errors.Join(err, countErr)
+		}
+	}
+	return err
+}
+
+// syncFile brings the stored session of a single file up to date
+func (t *SQLiteTracker) syncFile(ctx context.Context, p AgentProvider, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	inode := fileInode(info)
+	size := info.Size()
+	mtime := info.ModTime().UnixNano()
+
+	state, err := t.db.GetSyncFile(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if state != nil && state.Inode == inode && state.Size == size && state.ModTime == mtime {
+		return fmt.Errorf("%w: %s", ErrSessionAlreadyTracked, path)
+	}
+
+	// Resume from the stored offset when the same file only grew, and its
+	// parsed bytes were not rewritten in place
+	incremental := state != nil && state.SessionID != "" && state.ParserState != "" &&
+		state.Inode == inode && size >= state.Size && state.Offset <= size &&
+		state.ResumeHash != "" && fileResumeHash(path, state.Offset) == state.ResumeHash
+	if incremental {
+		err := t.syncFileFrom(ctx, p, path, state.Offset, []byte(state.ParserState))
+		if !errors.Is(err, errSessionMissing) {
+			return err
+		}
+	}
+	return t.syncFileFrom(ctx, p, path, 0, nil)
+}
+
+// resumeWindow is the number of bytes at the start of a file and before its
+// parsed offset that are hashed to detect files rewritten in place
+const resumeWindow = 4096
+
+// resumeHash returns the hash of the first bytes of r and of the bytes before offset
+func resumeHash(r io.ReaderAt, offset int64) (string, error) {
+	head := make([]byte, min(offset, resumeWindow))
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	tail := make([]byte, min(offset, resumeWindow))
+	if _, err := r.ReadAt(tail, offset-int64(len(tail))); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return contentFingerprint(append(head, tail...)), nil
+}
+
+// fileResumeHash returns the resume hash of a file, or "" if it cannot be read
+func fileResumeHash(path string, offset int64) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	hash, err := resumeHash(f, offset)
+	if err != nil {
+		return ""
+	}
+	return hash
+}
+
+// syncFileFrom parses a session file starting at offset and stores the result
+func (t *SQLiteTracker) syncFileFrom(ctx context.Context, p AgentProvider, path string, offset int64, parserState []byte) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek file: %w", err)
+	}
+	raw, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	data := raw[:completeLinesLength(raw)]
+
+	parser, err := p.NewParser(parserState)
+	if err != nil {
+		return err
+	}
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		parser.ParseLine(line)
+	}
+
+	session := parser.Session()
+	if session.ID == "" {
+		// Remember the file so it is not parsed again until it changes
+		row := &SyncFileRow{
+			Path:     path,
+			Source:   string(p.Name()),
+			Inode:    fileInode(info),
+			Size:     offset + int64(len(raw)),
+			ModTime:  info.ModTime().UnixNano(),
+			SyncedAt: time.Now().Unix(),
+		}
+		if err := t.db.UpsertSyncFile(ctx, row); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w in %s", ErrNoSession, path)
+	}
+
+	newState, err := parser.State()
+	if err != nil {
+		return err
+	}
+
+	var trackErr error
+	if offset == 0 {
+		session.Fingerprint = contentFingerprint(data)
+		trackErr = t.Track(ctx, session)
+	} else {
+		trackErr = t.appendSession(ctx, session)
+	}
+	if trackErr != nil && !errors.Is(trackErr, ErrSessionUpdated) &&
+		!errors.Is(trackErr, ErrSessionBackfilled) && !errors.Is(trackErr, ErrSessionAlreadyTracked) {
+		return trackErr
+	}
+
+	hash, err := resumeHash(f, offset+int64(len(data)))
+	if err != nil {
+		return err
+	}
+	row := &SyncFileRow{
+		Path:        path,
+		Source:      string(p.Name()),
+		Inode:       fileInode(info),
+		Size:        offset + int64(len(raw)),
+		ModTime:     info.ModTime().UnixNano(),
+		Offset:      offset + int64(len(data)),
+		ParserState: string(newState),
+		ResumeHash:  hash,
+		SessionID:   session.ID,
+		SyncedAt:    time.Now().Unix(),
+	}
+	if err := t.db.UpsertSyncFile(ctx, row); err != nil {
+		return err
+	}
+	return trackErr
+}
+
+// appendSession stores the items parsed from newly appended lines of a session
+// file and refreshes the session totals
+func (t *SQLiteTracker) appendSession(ctx context.Context, session *NormalizedSession) error {
+	existing, err := t.db.GetSessionByExternalID(ctx, session.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check existing session: %w", err)
+	}
+	if existing == nil {
+		return errSessionMissing
+	}
+
+	session.Price(t.pricing)
+	row := sessionRowFromNormalized(session)
+	unchanged := len(session.Messages) == 0 && len(session.ToolCalls) == 0 && len(session.Turns) == 0 &&
+		row.TotalTokens == existing.TotalTokens && equalEndedAt(row.EndedAt, existing.EndedAt)
+	if unchanged {
+		return fmt.Errorf("%w: %s", ErrSessionAlreadyTracked, session.ID)
+	}
+
+	err = t.db.WithTx(ctx, func(tx *DB) error {
+		if err := tx.UpdateSession(ctx, existing.ID, row); err != nil {
+			return err
+		}
+		return t.storeSessionContent(ctx, tx, existing.ID, session)
+	})
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrSessionUpdated, session.ID)
+}
+
+// completeLinesLength returns the length of data up to the last complete line.
+// An unterminated trailing line is only included when it is valid JSON, since
+// it may still be in the middle of being written.
+func completeLinesLength(data []byte) int {
+	idx := bytes.LastIndexByte(data, '\n')
+	tail := bytes.TrimSpace(data[idx+1:])
+	if len(tail) == 0 || json.Valid(tail) {
+		return len(data)
+	}
+	return idx + 1
+}
+
+func equalEndedAt(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// contentFingerprint returns a stable hash of a session file's content
+func contentFingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,484 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendToFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncFileIncremental(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("claude")
+
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	first := `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-tail","cwd":"/p","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-tail","message":{"model":"claude-3","role":"assistant","content":"Hi","usage":{"input_tokens":10,"output_tokens":20}}}
+`
+	if err := os.WriteFile(sessionFile, []byte(first), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.SyncFile(ctx, provider, sessionFile); err != nil {
+		t.Fatalf("first SyncFile() error = %v", err)
+	}
+
+	// Untouched files are skipped
+	if err := tr.SyncFile(ctx, provider, sessionFile); !errors.Is(err, ErrSessionAlreadyTracked) {
+		t.Fatalf("second SyncFile() error = %v; want ErrSessionAlreadyTracked", err)
+	}
+
+	// Appended lines are parsed from the stored offset, including a line
+	// that is still being written
+	appendToFile(t, sessionFile, `{"type":"user","timestamp":"2026-02-26T10:05:00Z","sessionId":"sess-tail","input":"More"}
+{"type":"assistant","timestamp":"2026-02-26T10:05:09Z","sessionId":"sess-tail","message":{"model":"claude-3",`)
+
+	if err := tr.SyncFile(ctx, provider, sessionFile); !errors.Is(err, ErrSessionUpdated) {
+		t.Fatalf("third SyncFile() error = %v; want ErrSessionUpdated", err)
+	}
+
+	state, err := tr.db.GetSyncFile(ctx, sessionFile)
+	if err != nil || state == nil {
+		t.Fatalf("GetSyncFile() = %v, %v", state, err)
+	}
+	info, _ := os.Stat(sessionFile)
+	if state.Offset >= info.Size() {
+		t.Errorf("Offset = %d; want the partial line (file size %d) left unconsumed", state.Offset, info.Size())
+	}
+
+	appendToFile(t, sessionFile, `"role":"assistant","content":"Sure","usage":{"input_tokens":30,"output_tokens":40}}}
+`)
+	if err := tr.SyncFile(ctx, provider, sessionFile); !errors.Is(err, ErrSessionUpdated) {
+		t.Fatalf("fourth SyncFile() error = %v; want ErrSessionUpdated", err)
+	}
+
+	row, err := tr.db.GetSessionByExternalID(ctx, "sess-tail")
+	if err != nil || row == nil {
+		t.Fatalf("GetSessionByExternalID() = %v, %v", row, err)
+	}
+	if row.InputTokens != 40 || row.OutputTokens != 60 {
+		t.Errorf("tokens = %d/%d; want 40/60", row.InputTokens, row.OutputTokens)
+	}
+	if row.StartedAt != time.Date(2026, 2, 26, 10, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("StartedAt = %d; want the first entry's timestamp", row.StartedAt)
+	}
+	if row.EndedAt == nil || *row.EndedAt != time.Date(2026, 2, 26, 10, 5, 9, 0, time.UTC).Unix() {
+		t.Errorf("EndedAt = %v; want 10:05:09", row.EndedAt)
+	}
+
+	messages, err := tr.GetMessages(ctx, row.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 4 {
+		t.Errorf("len(messages) = %d; want 4", len(messages))
+	}
+}
+
+func TestSyncFileWithoutSession(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("claude")
+
+	summaryFile := filepath.Join(t.TempDir(), "summary.jsonl")
+	if err := os.WriteFile(summaryFile, []byte(`{"type":"summary","summary":"Fix the build","leafUuid":"u1"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, summaryFile); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("first SyncFile() error = %v; want ErrNoSession", err)
+	}
+
+	// The file is not parsed again until it changes
+	if err := tr.SyncFile(ctx, provider, summaryFile); !errors.Is(err, ErrSessionAlreadyTracked) {
+		t.Fatalf("second SyncFile() error = %v; want ErrSessionAlreadyTracked", err)
+	}
+	appendToFile(t, summaryFile, `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-late","cwd":"/p","input":"Hello"}`+"\n")
+	if err := tr.SyncFile(ctx, provider, summaryFile); err != nil {
+		t.Fatalf("SyncFile() after a session was written error = %v", err)
+	}
+}
+
+func TestSyncFileRewrittenFile(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("claude")
+
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-rewrite","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-rewrite","message":{"model":"claude-3","role":"assistant","content":"Hi","usage":{"input_tokens":10,"output_tokens":20}}}
+`
+	if err := os.WriteFile(sessionFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, sessionFile); err != nil {
+		t.Fatalf("first SyncFile() error = %v", err)
+	}
+
+	// A shorter file is re-parsed from the start
+	shorter := `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-rewrite","input":"Hello"}
+`
+	if err := os.WriteFile(sessionFile, []byte(shorter), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, sessionFile); !errors.Is(err, ErrSessionUpdated) {
+		t.Fatalf("second SyncFile() error = %v; want ErrSessionUpdated", err)
+	}
+
+	row, _ := tr.db.GetSessionByExternalID(ctx, "sess-rewrite")
+	if row.InputTokens != 0 {
+		t.Errorf("InputTokens = %d; want 0 after rewrite", row.InputTokens)
+	}
+	msgCount, _ := tr.db.GetMessageCountBySessionID(ctx, row.ID)
+	if msgCount != 1 {
+		t.Errorf("message count = %d; want 1", msgCount)
+	}
+}
+
+func TestSyncFileRewrittenInPlace(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("claude")
+
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-inplace","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-inplace","message":{"model":"claude-3","role":"assistant","content":"Hi","usage":{"input_tokens":10,"output_tokens":20}}}
+`
+	if err := os.WriteFile(sessionFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, sessionFile); err != nil {
+		t.Fatalf("first SyncFile() error = %v", err)
+	}
+
+	// Different, longer content in the same file is parsed from the start
+	// rather than from the stored offset
+	rewritten := `{"type":"user","timestamp":"2026-02-26T11:00:00Z","sessionId":"sess-inplace","input":"Summarize the conversation so far"}
+{"type":"assistant","timestamp":"2026-02-26T11:00:05Z","sessionId":"sess-inplace","message":{"model":"claude-3","role":"assistant","content":"Done","usage":{"input_tokens":300,"output_tokens":40}}}
+{"type":"user","timestamp":"2026-02-26T11:01:00Z","sessionId":"sess-inplace","input":"Thanks"}
+`
+	if err := os.WriteFile(sessionFile, []byte(rewritten), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, sessionFile); !errors.Is(err, ErrSessionUpdated) {
+		t.Fatalf("second SyncFile() error = %v; want ErrSessionUpdated", err)
+	}
+
+	row, _ := tr.db.GetSessionByExternalID(ctx, "sess-inplace")
+	if row.InputTokens != 300 || row.OutputTokens != 40 {
+		t.Errorf("tokens = %d in, %d out; want 300 in, 40 out", row.InputTokens, row.OutputTokens)
+	}
+	msgCount, _ := tr.db.GetMessageCountBySessionID(ctx, row.ID)
+	if msgCount != 3 {
+		t.Errorf("message count = %d; want 3", msgCount)
+	}
+}
+
+func TestCodexParserResume(t *testing.T) {
+	lines := []string{
+		`{"type":"session_meta","timestamp":"2026-02-24T22:55:00Z","payload":{"id":"resume-1","cwd":"/p","model_provider":"openai"}}`,
+		`{"type":"event_msg","timestamp":"2026-02-24T22:55:10Z","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":100,"output_tokens":10,"total_tokens":110},"last_token_usage":{"input_tokens":100,"output_tokens":10,"total_tokens":110}}}}`,
+		`{"type":"event_msg","timestamp":"2026-02-24T22:56:10Z","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":300,"output_tokens":30,"total_tokens":330},"last_token_usage":{"input_tokens":200,"output_tokens":20,"total_tokens":220}}}}`,
+	}
+
+	first, err := newCodexParser(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.ParseLine([]byte(lines[0]))
+	first.ParseLine([]byte(lines[1]))
+	state, err := first.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := newCodexParser(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed.ParseLine([]byte(lines[2]))
+	session := resumed.Session()
+
+	if session.ID != "resume-1" || session.ProjectPath != "/p" {
+		t.Errorf("session header not restored: %+v", session)
+	}
+	if session.Tokens.Total != 330 {
+		t.Errorf("Tokens.Total = %d; want 330", session.Tokens.Total)
+	}
+	if len(session.Turns) != 1 || session.Turns[0].Index != 1 {
+		t.Errorf("Turns = %+v; want only the new turn with index 1", session.Turns)
+	}
+	if !session.StartedAt.Equal(time.Date(2026, 2, 24, 22, 55, 0, 0, time.UTC)) {
+		t.Errorf("StartedAt = %v; want restored first timestamp", session.StartedAt)
+	}
+}
+
+func TestSyncFileDuplicateClaudeUsage(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("claude")
+	dir := t.TempDir()
+
+	original := filepath.Join(dir, "original.jsonl")
+	if err := os.WriteFile(original, []byte(`{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-a","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-a","requestId":"req_1","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":"Hi","usage":{"input_tokens":100,"output_tokens":20}}}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, original); err != nil {
+		t.Fatalf("SyncFile() error = %v", err)
+	}
+
+	// A later content block of the same response arrives in the next sync
+	appendToFile(t, original, `{"type":"assistant","timestamp":"2026-02-26T10:00:06Z","sessionId":"sess-a","requestId":"req_1","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":"More","usage":{"input_tokens":100,"output_tokens":20}}}
+`)
+	tr.SyncFile(ctx, provider, original)
+
+	// A resumed session starts with a copy of the earlier entries
+	resumed := filepath.Join(dir, "resumed.jsonl")
+	if err := os.WriteFile(resumed, []byte(`{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-b","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-b","requestId":"req_1","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":"Hi","usage":{"input_tokens":100,"output_tokens":20}}}
+{"type":"user","timestamp":"2026-02-26T11:00:00Z","sessionId":"sess-b","input":"Continue"}
+{"type":"assistant","timestamp":"2026-02-26T11:00:04Z","sessionId":"sess-b","requestId":"req_2","message":{"id":"msg_2","model":"claude-3","role":"assistant","content":"Sure","usage":{"input_tokens":30,"output_tokens":7}}}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, resumed); err != nil {
+		t.Fatalf("SyncFile() error = %v", err)
+	}
+
+	for _, want := range []struct {
+		id    string
+		total int64
+		turns int
+	}{{"sess-a", 120, 1}, {"sess-b", 37, 1}} {
+		row, err := tr.db.GetSessionByExternalID(ctx, want.id)
+		if err != nil || row == nil {
+			t.Fatalf("GetSessionByExternalID(%q) = %v, %v", want.id, row, err)
+		}
+		if row.TotalTokens != want.total {
+			t.Errorf("%s TotalTokens = %d; want %d", want.id, row.TotalTokens, want.total)
+		}
+		turns, _ := tr.db.GetTurns(ctx, SessionFilter{})
+		n := 0
+		for _, turn := range turns {
+			if turn.ExternalID == want.id {
+				n++
+			}
+		}
+		if n != want.turns {
+			t.Errorf("%s has %d turns; want %d", want.id, n, want.turns)
+		}
+	}
+
+	// Re-syncing everything from scratch, as after a migration, keeps the copied usage excluded
+	if _, err := tr.db.db.ExecContext(ctx, `DELETE FROM sync_files; UPDATE sessions SET fingerprint = NULL`); err != nil {
+		t.Fatal(err)
+	}
+	tr.SyncFile(ctx, provider, resumed)
+	tr.SyncFile(ctx, provider, original)
+	if row, _ := tr.db.GetSessionByExternalID(ctx, "sess-b"); row.TotalTokens != 37 {
+		t.Errorf("sess-b TotalTokens after full re-sync = %d; want 37", row.TotalTokens)
+	}
+}
+
+func TestSyncFileDuplicateClaudeUsageOrder(t *testing.T) {
+	ctx := context.Background()
+	provider, _ := LookupProvider("claude")
+	dir := t.TempDir()
+
+	original := filepath.Join(dir, "original.jsonl")
+	if err := os.WriteFile(original, []byte(`{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-a","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-a","requestId":"req_1","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":"Hi","usage":{"input_tokens":100,"output_tokens":20}}}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resumed := filepath.Join(dir, "resumed.jsonl")
+	if err := os.WriteFile(resumed, []byte(`{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-b","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-b","requestId":"req_1","message":{"id":"msg_1","model":"claude-3","role":"assistant","content":"Hi","usage":{"input_tokens":100,"output_tokens":20}}}
+{"type":"user","timestamp":"2026-02-26T11:00:00Z","sessionId":"sess-b","input":"Continue"}
+{"type":"assistant","timestamp":"2026-02-26T11:00:04Z","sessionId":"sess-b","requestId":"req_2","message":{"id":"msg_2","model":"claude-3","role":"assistant","content":"Sure","usage":{"input_tokens":30,"output_tokens":7}}}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, order := range [][]string{{original, resumed}, {resumed, original}} {
+		tr := newTestTracker(t)
+		for _, path := range order {
+			if err := tr.SyncFile(ctx, provider, path); err != nil {
+				t.Fatalf("SyncFile(%s) error = %v", filepath.Base(path), err)
+			}
+		}
+		for id, want := range map[string]int64{"sess-a": 120, "sess-b": 37} {
+			row, err := tr.db.GetSessionByExternalID(ctx, id)
+			if err != nil || row == nil {
+				t.Fatalf("GetSessionByExternalID(%q) = %v, %v", id, row, err)
+			}
+			if row.TotalTokens != want {
+				t.Errorf("%s first: %s TotalTokens = %d; want %d", filepath.Base(order[0]), id, row.TotalTokens, want)
+			}
+			models, err := tr.db.GetSessionModels(ctx, row.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(models) != 1 || models[0].TotalTokens != want {
+				t.Errorf("%s first: %s models = %+v; want %d claude-3 tokens", filepath.Base(order[0]), id, models, want)
+			}
+		}
+	}
+}
+
+func TestSyncFileStoresModelSplit(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("codex")
+
+	sessionFile := filepath.Join(t.TempDir(), "rollout.jsonl")
+	lines := splitLines(codexModelSwitchRollout)
+	if err := os.WriteFile(sessionFile, []byte(strings.Join(lines[:6], "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, sessionFile); err != nil {
+		t.Fatalf("SyncFile() error = %v", err)
+	}
+
+	// The model switch arrives in an incremental sync
+	appendToFile(t, sessionFile, strings.Join(lines[6:], "\n")+"\n")
+	tr.SyncFile(ctx, provider, sessionFile)
+
+	detail, err := tr.GetSessionDetail(ctx, "0199a1c2-7d4e-7a10-9b3f-5c2e8d1f4a60")
+	if err != nil {
+		t.Fatalf("GetSessionDetail() error = %v", err)
+	}
+	if detail.Session.InputTokens != 6000 || detail.Session.CacheReadTokens != 24000 || detail.Session.CacheCreationTokens != 0 {
+		t.Errorf("Session = %+v", detail.Session)
+	}
+	if len(detail.Models) != 2 || detail.Models[0].Model != "gpt-5" || detail.Models[0].TotalTokens != 12500 ||
+		detail.Models[1].Model != "gpt-5-mini" || detail.Models[1].TotalTokens != 19000 {
+		t.Fatalf("Models = %+v", detail.Models)
+	}
+	if math.Abs(detail.Models[0].Cost+detail.Models[1].Cost-detail.Session.Cost) > 1e-9 ||
+		math.Abs(detail.CostBreakdown.Total()-detail.Session.Cost) > 1e-9 {
+		t.Errorf("Cost = %v; models %v + %v, breakdown %v", detail.Session.Cost, detail.Models[0].Cost,
+			detail.Models[1].Cost, detail.CostBreakdown.Total())
+	}
+
+	if n, err := tr.Reprice(ctx); err != nil || n != 0 {
+		t.Errorf("Reprice() = %d, %v; want no changes", n, err)
+	}
+}
+
+func TestTopModelsSplitSwitchedSession(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("codex")
+
+	sessionFile := filepath.Join(t.TempDir(), "rollout.jsonl")
+	if err := os.WriteFile(sessionFile, []byte(codexModelSwitchRollout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, sessionFile); err != nil {
+		t.Fatalf("SyncFile() error = %v", err)
+	}
+
+	since := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC).Unix()
+	until := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC).Unix()
+	check := func(name string, models []ModelUsage, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s() error = %v", name, err)
+		}
+		if len(models) != 2 || models[0].Model != "gpt-5" || models[0].TotalTokens != 12500 || models[0].SessionCount != 1 ||
+			models[1].Model != "gpt-5-mini" || models[1].TotalTokens != 19000 || models[1].SessionCount != 1 {
+			t.Errorf("%s() = %+v; want gpt-5 with 12500 and gpt-5-mini with 19000 tokens", name, models)
+		}
+	}
+	models, err := tr.db.GetTopModels(ctx, "codex", since, until, "/home/dev/app", 10, SortCost)
+	check("GetTopModels", models, err)
+	models, err = tr.db.GetTopModelsAll(ctx, since, until, "/home/dev/app", 10, SortCost)
+	check("GetTopModelsAll", models, err)
+
+	if models, _ := tr.db.GetTopModels(ctx, "claude", since, until, "", 10, SortCost); len(models) != 0 {
+		t.Errorf("GetTopModels(claude) = %+v; want none", models)
+	}
+	if models, _ := tr.db.GetTopModelsAll(ctx, since, until, "/other", 10, SortCost); len(models) != 0 {
+		t.Errorf("GetTopModelsAll(/other) = %+v; want none", models)
+	}
+}
+
+func TestSyncFilePairsCodexToolOutput(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("codex")
+
+	sessionFile := filepath.Join(t.TempDir(), "rollout.jsonl")
+	if err := os.WriteFile(sessionFile, []byte(`{"timestamp":"2026-03-02T10:00:00Z","type":"session_meta","payload":{"id":"pair-1","cwd":"/p","model_provider":"openai"}}
+{"timestamp":"2026-03-02T10:00:01Z","type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{\"command\":[\"make\"]}","call_id":"call_1"}}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, sessionFile); err != nil {
+		t.Fatalf("SyncFile() error = %v", err)
+	}
+
+	// The output of a call stored by the previous sync updates the stored call
+	appendToFile(t, sessionFile, `{"timestamp":"2026-03-02T10:00:31Z","type":"response_item","payload":{"type":"function_call_output","call_id":"call_1","output":"make: *** No targets.  Stop."}}
+`)
+	tr.SyncFile(ctx, provider, sessionFile)
+
+	row, _ := tr.db.GetSessionByExternalID(ctx, "pair-1")
+	calls, err := tr.db.GetToolCallsBySessionID(ctx, row.ID)
+	if err != nil {
+		t.Fatalf("GetToolCallsBySessionID() error = %v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("tool calls = %+v; want one call", calls)
+	}
+	if calls[0].Result != "make: *** No targets.  Stop." || calls[0].DurationMs != 30000 || calls[0].ExitCode != nil {
+		t.Errorf("tool call = %+v", calls[0])
+	}
+}
+
+func TestSyncFileCountsErrors(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("codex")
+
+	missing := filepath.Join(t.TempDir(), "missing.jsonl")
+	for i := 0; i < 2; i++ {
+		if err := tr.SyncFile(ctx, provider, missing); err == nil {
+			t.Fatal("SyncFile() of a missing file succeeded")
+		}
+	}
+
+	// Files without a session are skipped, not failed
+	claude, _ := LookupProvider("claude")
+	summaryFile := filepath.Join(t.TempDir(), "summary.jsonl")
+	if err := os.WriteFile(summaryFile, []byte(`{"type":"summary","summary":"Fix the build","leafUuid":"u1"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr.SyncFile(ctx, claude, summaryFile)
+
+	if n, err := tr.db.GetSyncErrors(ctx, "codex"); err != nil || n != 2 {
+		t.Errorf("GetSyncErrors(codex) = %d, %v; want 2", n, err)
+	}
+	if n, err := tr.db.GetSyncErrors(ctx, "claude"); err != nil || n != 0 {
+		t.Errorf("GetSyncErrors(claude) = %d, %v; want 0", n, err)
+	}
+}
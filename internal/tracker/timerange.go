@@ -0,0 +1,193 @@
+package tracker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar defines how days and weeks are bucketed
+type Calendar struct {
+	Location     *time.Location
+	FirstWeekday time.Weekday
+}
+
+// DefaultCalendar uses the local time zone and weeks starting on Monday
+func DefaultCalendar() Calendar {
+	return Calendar{Location: time.Local, FirstWeekday: time.Monday}
+}
+
+// location returns the calendar time zone, defaulting to local time
+func (c Calendar) location() *time.Location {
+	if c.Location == nil {
+		return time.Local
+	}
+	return c.Location
+}
+
+// DayStart returns midnight of the day containing t in the calendar's time zone
+func (c Calendar) DayStart(t time.Time) time.Time {
+	t = t.In(c.location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// WeekStart returns midnight of the first day of the week containing t
+func (c Calendar) WeekStart(t time.Time) time.Time {
+	day := c.DayStart(t)
+	offset := (int(day.Weekday()) - int(c.FirstWeekday) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// ParseWeekday parses a week start day name such as "monday" or "sunday"
+func ParseWeekday(name string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday: %s", name)
+}
+
+// TimeRange is the half-open interval [Start, End) that stats are computed over
+type TimeRange struct {
+	Start    time.Time
+	End      time.Time
+	Label    string   // human-readable description such as "week" or "2026-09"
+	Calendar Calendar // time zone and week start used to bucket days and weeks
+}
+
+// bounds returns the range as Unix timestamps for SQL queries
+func (r TimeRange) bounds() (int64, int64) {
+	return r.Start.Unix(), r.End.Unix()
+}
+
+// Duration returns the length of the range
+func (r TimeRange) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// String returns the range label, or its bounds if it has no label
+func (r TimeRange) String() string {
+	if r.Label != "" {
+		return r.Label
+	}
+	if r.Start.Unix() <= 0 {
+		return "until " + r.End.Format("2006-01-02 15:04")
+	}
+	return r.Start.Format("2006-01-02 15:04") + " - " + r.End.Format("2006-01-02 15:04")
+}
+
+// PeriodNames lists the period names accepted by ParsePeriod besides YYYY-MM and YYYY-MM-DD
+var PeriodNames = []string{
+	"day", "week", "month", "today", "yesterday",
+	"this-week", "last-week", "this-month", "last-month",
+}
+
+// ParsePeriod resolves a period name relative to now.
+// Rolling periods (day, week, month) end at now and cover the last 1, 7 or 30
+// days. Calendar periods (today, yesterday, this-week, last-week, this-month,
+// last-month, YYYY-MM, YYYY-MM-DD) cover whole days in the calendar's time zone.
+func ParsePeriod(name string, now time.Time, cal Calendar) (TimeRange, error) {
+	loc := cal.location()
+	now = now.In(loc)
+	today := cal.DayStart(now)
+	weekStart := cal.WeekStart(now)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+
+	r := TimeRange{Label: name, Calendar: cal}
+	switch name {
+	case string(PeriodDay), "":
+		r.Start, r.End, r.Label = now.AddDate(0, 0, -1), now, string(PeriodDay)
+	case string(PeriodWeek):
+		r.Start, r.End = now.AddDate(0, 0, -7), now
+	case string(PeriodMonth):
+		r.Start, r.End = now.AddDate(0, 0, -30), now
+	case "today":
+		r.Start, r.End = today, today.AddDate(0, 0, 1)
+	case "yesterday":
+		r.Start, r.End = today.AddDate(0, 0, -1), today
+	case "this-week":
+		r.Start, r.End = weekStart, weekStart.AddDate(0, 0, 7)
+	case "last-week":
+		r.Start, r.End = weekStart.AddDate(0, 0, -7), weekStart
+	case "this-month":
+		r.Start, r.End = monthStart, monthStart.AddDate(0, 1, 0)
+	case "last-month":
+		r.Start, r.End = monthStart.AddDate(0, -1, 0), monthStart
+	default:
+		if t, err := time.ParseInLocation("2006-01", name, loc); err == nil {
+			r.Start, r.End = t, t.AddDate(0, 1, 0)
+		} else if t, err := time.ParseInLocation("2006-01-02", name, loc); err == nil {
+			r.Start, r.End = t, t.AddDate(0, 0, 1)
+		} else {
+			return TimeRange{}, fmt.Errorf("invalid period: %s. Use %s, YYYY-MM or YYYY-MM-DD", name, strings.Join(PeriodNames, ", "))
+		}
+	}
+	return r, nil
+}
+
+// ParseTime parses an absolute time (RFC3339 or YYYY-MM-DD in the calendar's
+// time zone) or a relative duration before now such as 30m, 12h, 3d or 2w
+func ParseTime(value string, now time.Time, cal Calendar) (time.Time, error) {
+	now = now.In(cal.location())
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if len(value) > 1 {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err == nil && n >= 0 {
+			switch value[len(value)-1] {
+			case 'm':
+				return now.Add(-time.Duration(n) * time.Minute), nil
+			case 'h':
+				return now.Add(-time.Duration(n) * time.Hour), nil
+			case 'd':
+				return now.AddDate(0, 0, -n), nil
+			case 'w':
+				return now.AddDate(0, 0, -7*n), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s. Use RFC3339, YYYY-MM-DD or a relative duration like 3d", value)
+}
+
+// ResolveTimeRange combines a period name with optional --since and --until
+// values, which replace the start and end of the period. Without a period name,
+// --since alone selects everything from that time until now, and --until alone
+// everything before that time. A --until date includes that day.
+func ResolveTimeRange(period, since, until string, now time.Time, cal Calendar) (TimeRange, error) {
+	r, err := ParsePeriod(period, now, cal)
+	if err != nil {
+		return TimeRange{}, err
+	}
+	if since == "" && until == "" {
+		return r, nil
+	}
+
+	if period == "" {
+		r.Start, r.End = time.Unix(0, 0).In(cal.location()), now.In(cal.location())
+	}
+	if since != "" {
+		if r.Start, err = ParseTime(since, now, cal); err != nil {
+			return TimeRange{}, err
+		}
+	}
+	if until != "" {
+		if r.End, err = ParseTime(until, now, cal); err != nil {
+			return TimeRange{}, err
+		}
+		// A date includes that whole day
+		if _, err := time.Parse("2006-01-02", until); err == nil {
+			r.End = r.End.AddDate(0, 0, 1)
+		}
+	}
+	if !r.End.After(r.Start) {
+		return TimeRange{}, fmt.Errorf("invalid range: %s is not before %s", r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339))
+	}
+	r.Label = ""
+	return r, nil
+}
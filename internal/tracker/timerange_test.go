@@ -0,0 +1,277 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+var utcCalendar = Calendar{Location: time.UTC, FirstWeekday: time.Monday}
+
+func TestParsePeriod(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 9, 16, 14, 30, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+	}{
+		{"day", now.AddDate(0, 0, -1), now},
+		{"", now.AddDate(0, 0, -1), now},
+		{"week", now.AddDate(0, 0, -7), now},
+		{"month", now.AddDate(0, 0, -30), now},
+		{"today", day(2026, 9, 16), day(2026, 9, 17)},
+		{"yesterday", day(2026, 9, 15), day(2026, 9, 16)},
+		{"this-week", day(2026, 9, 14), day(2026, 9, 21)},
+		{"last-week", day(2026, 9, 7), day(2026, 9, 14)},
+		{"this-month", day(2026, 9, 1), day(2026, 10, 1)},
+		{"last-month", day(2026, 8, 1), day(2026, 9, 1)},
+		{"2026-02", day(2026, 2, 1), day(2026, 3, 1)},
+		{"2026-09-01", day(2026, 9, 1), day(2026, 9, 2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParsePeriod(tt.name, now, utcCalendar)
+			if err != nil {
+				t.Fatalf("ParsePeriod(%q) error = %v", tt.name, err)
+			}
+			if !r.Start.Equal(tt.start) || !r.End.Equal(tt.end) {
+				t.Errorf("ParsePeriod(%q) = %v - %v; want %v - %v", tt.name, r.Start, r.End, tt.start, tt.end)
+			}
+		})
+	}
+
+	if _, err := ParsePeriod("fortnight", now, utcCalendar); err == nil {
+		t.Error("Expected error for unknown period")
+	}
+}
+
+func TestParsePeriodSundayWeek(t *testing.T) {
+	// Weeks start on Monday, so Sunday belongs to the week started six days earlier
+	now := time.Date(2026, 9, 20, 10, 0, 0, 0, time.UTC)
+	r, err := ParsePeriod("this-week", now, utcCalendar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC); !r.Start.Equal(want) {
+		t.Errorf("Start = %v; want %v", r.Start, want)
+	}
+}
+
+func TestResolveTimeRange(t *testing.T) {
+	now := time.Date(2026, 9, 16, 14, 30, 0, 0, time.UTC)
+
+	r, err := ResolveTimeRange("", "3d", "", now, utcCalendar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Start.Equal(now.AddDate(0, 0, -3)) || !r.End.Equal(now) {
+		t.Errorf("--since 3d = %v - %v", r.Start, r.End)
+	}
+
+	r, err = ResolveTimeRange("2026-09", "", "2026-09-10", now, utcCalendar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Start.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)) || !r.End.Equal(time.Date(2026, 9, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("2026-09 --until 2026-09-10 = %v - %v", r.Start, r.End)
+	}
+
+	r, err = ResolveTimeRange("", "2026-09-01T08:00:00Z", "2026-09-01T20:00:00+02:00", now, utcCalendar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Duration() != 10*time.Hour {
+		t.Errorf("RFC3339 range duration = %v; want 10h", r.Duration())
+	}
+
+	// --until alone has no lower bound
+	for _, c := range []struct {
+		until string
+		want  time.Time
+	}{
+		{"2026-09-01", time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC)},
+		{"3d", now.AddDate(0, 0, -3)},
+	} {
+		r, err = ResolveTimeRange("", "", c.until, now, utcCalendar)
+		if err != nil {
+			t.Fatalf("--until %s: %v", c.until, err)
+		}
+		if r.Start.Unix() != 0 || !r.End.Equal(c.want) {
+			t.Errorf("--until %s = %v - %v; want everything before %v", c.until, r.Start, r.End, c.want)
+		}
+	}
+	if got := r.String(); got != "until 2026-09-13 14:30" {
+		t.Errorf("String() = %q; want until 2026-09-13 14:30", got)
+	}
+
+	// --since and --until with the same date select that day
+	r, err = ResolveTimeRange("", "2026-09-15", "2026-09-15", now, utcCalendar)
+	if err != nil {
+		t.Fatalf("--since and --until 2026-09-15: %v", err)
+	}
+	if !r.Start.Equal(time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC)) || r.Duration() != 24*time.Hour {
+		t.Errorf("--since and --until 2026-09-15 = %v - %v; want that day", r.Start, r.End)
+	}
+
+	if _, err := ResolveTimeRange("", "1d", "2d", now, utcCalendar); err == nil {
+		t.Error("Expected error when --until is before --since")
+	}
+	if _, err := ResolveTimeRange("", "soon", "", now, utcCalendar); err == nil {
+		t.Error("Expected error for invalid --since")
+	}
+}
+
+func TestGetUsageStatsUpperBound(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+
+	inside := time.Date(2026, 8, 15, 12, 0, 0, 0, time.UTC)
+	after := time.Date(2026, 9, 2, 12, 0, 0, 0, time.UTC)
+	for i, started := range []time.Time{inside, after} {
+		_, err := tr.db.InsertSession(ctx, &SessionRow{
+			ExternalID: []string{"inside", "after"}[i],
+			Source:     "claude",
+			StartedAt:  started.Unix(),
+			Cost:       1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, _ := ParsePeriod("2026-08", time.Date(2026, 9, 16, 0, 0, 0, 0, time.UTC), utcCalendar)
+	stats, err := tr.GetUsageStatsAll(ctx, r, "", SortSessions)
+	if err != nil {
+		t.Fatalf("GetUsageStatsAll() error = %v", err)
+	}
+	if stats.SessionCount != 1 || stats.TotalCost != 1 {
+		t.Errorf("SessionCount = %d, TotalCost = %v; want only the August session", stats.SessionCount, stats.TotalCost)
+	}
+
+	perAgent, err := tr.GetPerAgentStats(ctx, r, "")
+	if err != nil || len(perAgent) != 1 || perAgent[0].SessionCount != 1 {
+		t.Errorf("GetPerAgentStats() = %+v, %v; want one claude session", perAgent, err)
+	}
+}
+
+func TestParsePeriodSundayWeekStart(t *testing.T) {
+	cal := Calendar{Location: time.UTC, FirstWeekday: time.Sunday}
+	now := time.Date(2026, 9, 20, 10, 0, 0, 0, time.UTC) // Sunday
+	r, err := ParsePeriod("last-week", now, cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 9, 13, 0, 0, 0, 0, time.UTC); !r.Start.Equal(want) {
+		t.Errorf("Start = %v; want %v", r.Start, want)
+	}
+	if want := time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC); !r.End.Equal(want) {
+		t.Errorf("End = %v; want %v", r.End, want)
+	}
+}
+
+func TestParsePeriodDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal := Calendar{Location: ny, FirstWeekday: time.Monday}
+	now := time.Date(2026, 11, 20, 12, 0, 0, 0, ny)
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"2026-03-08", 23 * time.Hour},       // clocks spring forward
+		{"2026-11-01", 25 * time.Hour},       // clocks fall back
+		{"2026-03", (31*24 - 1) * time.Hour}, // March loses an hour
+		{"this-week", 7 * 24 * time.Hour},    // no transition
+		{"2026-10-26", 24 * time.Hour},       // Monday before fall back
+	}
+	for _, tt := range tests {
+		r, err := ParsePeriod(tt.name, now, cal)
+		if err != nil {
+			t.Fatalf("ParsePeriod(%q) error = %v", tt.name, err)
+		}
+		if r.Duration() != tt.duration {
+			t.Errorf("ParsePeriod(%q) duration = %v; want %v", tt.name, r.Duration(), tt.duration)
+		}
+		if r.Start.Hour() != 0 || r.End.In(ny).Hour() != 0 {
+			t.Errorf("ParsePeriod(%q) = %v - %v; want local midnights", tt.name, r.Start, r.End)
+		}
+	}
+}
+
+func TestDailySummariesTimezone(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 23:30 EDT on the day clocks spring forward is already March 9 in UTC,
+	// and 00:30 EST after falling back is 04:30 UTC on the same local day
+	starts := []time.Time{
+		time.Date(2026, 3, 8, 23, 30, 0, 0, ny),
+		time.Date(2026, 3, 8, 1, 0, 0, 0, ny),
+		time.Date(2026, 11, 1, 0, 30, 0, 0, ny),
+		time.Date(2026, 11, 1, 23, 30, 0, 0, ny),
+	}
+	for i, started := range starts {
+		_, err := tr.db.InsertSession(ctx, &SessionRow{
+			ExternalID:  string(rune('a' + i)),
+			Source:      "claude",
+			StartedAt:   started.Unix(),
+			TotalTokens: 100,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	until := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	cal := Calendar{Location: ny, FirstWeekday: time.Monday}
+	daily, err := tr.db.GetDailySummaries(ctx, "claude", 0, until, "", cal)
+	if err != nil {
+		t.Fatalf("GetDailySummaries() error = %v", err)
+	}
+	got := make(map[string]int64)
+	for _, d := range daily {
+		got[d.Date] = d.SessionCount
+	}
+	want := map[string]int64{"2026-03-08": 2, "2026-11-01": 2}
+	if len(got) != len(want) || got["2026-03-08"] != 2 || got["2026-11-01"] != 2 {
+		t.Errorf("daily buckets = %v; want %v", got, want)
+	}
+	// Newest day first
+	if daily[0].Date != "2026-11-01" {
+		t.Errorf("daily[0] = %s; want 2026-11-01", daily[0].Date)
+	}
+
+	// In UTC the late March 8 session moves to March 9
+	daily, _ = tr.db.GetDailySummaries(ctx, "claude", 0, until, "", utcCalendar)
+	if len(daily) != 4 {
+		t.Errorf("UTC daily buckets = %+v; want 4 days", daily)
+	}
+
+	weekly, err := tr.db.GetWeeklySummaries(ctx, "claude", 0, until, "", Calendar{Location: ny, FirstWeekday: time.Sunday})
+	if err != nil {
+		t.Fatalf("GetWeeklySummaries() error = %v", err)
+	}
+	// March 8 and November 1 2026 are Sundays, so each starts its own week
+	if len(weekly) != 2 || weekly[0].WeekStart != "2026/11/01" || weekly[1].WeekStart != "2026/03/08" {
+		t.Errorf("weekly buckets = %+v", weekly)
+	}
+
+	weekly, _ = tr.db.GetWeeklySummaries(ctx, "claude", 0, until, "", cal)
+	// With Monday weeks, each Sunday belongs to the week starting six days earlier
+	if len(weekly) != 2 || weekly[0].WeekStart != "2026/10/26" || weekly[1].WeekStart != "2026/03/02" {
+		t.Errorf("Monday weekly buckets = %+v", weekly)
+	}
+}
@@ -0,0 +1,398 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SessionToolCall is a stored tool call together with the session it belongs to
+type SessionToolCall struct {
+	ExternalID  string `json:"external_id"`
+	Source      string `json:"source"`
+	ProjectPath string `json:"project_path"`
+	ToolCallRow
+}
+
+// ToolUsage summarizes the calls of one tool
+type ToolUsage struct {
+	ToolName       string  `json:"tool_name"`
+	Calls          int64   `json:"calls"`
+	Errors         int64   `json:"errors"`
+	ErrorRate      float64 `json:"error_rate"`       // fraction of calls that failed
+	TimedCalls     int64   `json:"timed_calls"`      // calls with a known duration
+	MeanDurationMs float64 `json:"mean_duration_ms"` // over timed calls
+	P50DurationMs  int64   `json:"p50_duration_ms"`
+	P95DurationMs  int64   `json:"p95_duration_ms"`
+}
+
+// ToolGroupUsage summarizes the tool calls of one agent or project
+type ToolGroupUsage struct {
+	Name      string  `json:"name"`
+	Calls     int64   `json:"calls"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	TopTool   string  `json:"top_tool"` // most called tool
+}
+
+// CommandUsage counts the runs of a normalized shell command
+type CommandUsage struct {
+	Command string `json:"command"`
+	Runs    int64  `json:"runs"`
+	Errors  int64  `json:"errors"`
+}
+
+// FileUsage counts the tool calls that edited a file
+type FileUsage struct {
+	Path  string `json:"path"`
+	Edits int64  `json:"edits"`
+}
+
+// ToolStats is the tool usage of the sessions matching a filter
+type ToolStats struct {
+	CallCount    int64            `json:"call_count"`
+	SessionCount int64            `json:"session_count"`
+	ErrorCount   int64            `json:"error_count"`
+	Tools        []ToolUsage      `json:"tools"`    // most called first
+	Agents       []ToolGroupUsage `json:"agents"`   // most calls first
+	Projects     []ToolGroupUsage `json:"projects"` // most calls first
+	Commands     []CommandUsage   `json:"commands"` // most run shell commands
+	Files        []FileUsage      `json:"files"`    // most edited files
+}
+
+// GetSessionToolCalls returns the tool calls of the sessions matching the
+// filter, ordered by session and time. Its sort order, limit and offset are ignored.
+func (db *DB) GetSessionToolCalls(ctx context.Context, f SessionFilter) ([]SessionToolCall, error) {
+	where, args := f.where()
+	query := `SELECT s.external_id, s.source, COALESCE(s.project_path, ''), t.id, t.session_id, t.tool_name,
+		COALESCE(t.arguments, ''), COALESCE(t.result, ''), t.timestamp, COALESCE(t.call_id, ''), t.exit_code,
+		COALESCE(t.is_error, 0), COALESCE(t.duration_ms, 0)
+		FROM tool_calls t
+		JOIN sessions s ON s.id = t.session_id` + where + `
+		ORDER BY t.session_id, t.timestamp, t.id`
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tool calls: %w", err)
+	}
+	defer rows.Close()
+
+	var calls []SessionToolCall
+	for rows.Next() {
+		var c SessionToolCall
+		if err := rows.Scan(&c.ExternalID, &c.Source, &c.ProjectPath, &c.ID, &c.SessionID, &c.ToolName,
+			&c.Arguments, &c.Result, &c.Timestamp, &c.CallID, &c.ExitCode, &c.IsError, &c.DurationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan tool call: %w", err)
+		}
+		calls = append(calls, c)
+	}
+	return calls, rows.Err()
+}
+
+// GetToolStats analyzes the tool calls of the sessions matching the filter,
+// listing up to limit entries per section
+func (t *SQLiteTracker) GetToolStats(ctx context.Context, f SessionFilter, limit int) (*ToolStats, error) {
+	calls, err := t.db.GetSessionToolCalls(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return BuildToolStats(calls, limit), nil
+}
+
+// toolGroup accumulates the calls of one agent or project
+type toolGroup struct {
+	usage ToolGroupUsage
+	tools map[string]int64
+}
+
+// addToGroup counts a tool call in the named group
+func addToGroup(groups map[string]*toolGroup, name string, c SessionToolCall) {
+	g, ok := groups[name]
+	if !ok {
+		g = &toolGroup{usage: ToolGroupUsage{Name: name}, tools: make(map[string]int64)}
+		groups[name] = g
+	}
+	g.usage.Calls++
+	if c.IsError {
+		g.usage.Errors++
+	}
+	g.tools[c.ToolName]++
+}
+
+// nearestRank returns the nearest-rank percentile p of sorted values
+func nearestRank(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// BuildToolStats computes the tool statistics of tool calls ordered by session,
+// listing up to limit entries per section
+func BuildToolStats(calls []SessionToolCall, limit int) *ToolStats {
+	stats := &ToolStats{
+		CallCount: int64(len(calls)),
+		Tools:     []ToolUsage{},
+		Agents:    []ToolGroupUsage{},
+		Projects:  []ToolGroupUsage{},
+		Commands:  []CommandUsage{},
+		Files:     []FileUsage{},
+	}
+
+	byTool := make(map[string]*ToolUsage)
+	durations := make(map[string][]int64)
+	agents := make(map[string]*toolGroup)
+	projects := make(map[string]*toolGroup)
+	commands := make(map[string]*CommandUsage)
+	files := make(map[string]*FileUsage)
+	lastSession := int64(-1)
+	for _, c := range calls {
+		if c.SessionID != lastSession {
+			stats.SessionCount++
+			lastSession = c.SessionID
+		}
+		if c.IsError {
+			stats.ErrorCount++
+		}
+
+		u, ok := byTool[c.ToolName]
+		if !ok {
+			u = &ToolUsage{ToolName: c.ToolName}
+			byTool[c.ToolName] = u
+		}
+		u.Calls++
+		if c.IsError {
+			u.Errors++
+		}
+		if c.DurationMs > 0 {
+			durations[c.ToolName] = append(durations[c.ToolName], c.DurationMs)
+		}
+
+		addToGroup(agents, c.Source, c)
+		addToGroup(projects, c.ProjectPath, c)
+
+		for _, command := range shellCommands(c.Arguments) {
+			cu, ok := commands[command]
+			if !ok {
+				cu = &CommandUsage{Command: command}
+				commands[command] = cu
+			}
+			cu.Runs++
+			if c.IsError {
+				cu.Errors++
+			}
+		}
+		for _, path := range editedFiles(c.ToolName, c.Arguments) {
+			fu, ok := files[path]
+			if !ok {
+				fu = &FileUsage{Path: path}
+				files[path] = fu
+			}
+			fu.Edits++
+		}
+	}
+
+	for name, u := range byTool {
+		u.ErrorRate = float64(u.Errors) / float64(u.Calls)
+		if d := durations[name]; len(d) > 0 {
+			sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+			var sum int64
+			for _, v := range d {
+				sum += v
+			}
+			u.TimedCalls = int64(len(d))
+			u.MeanDurationMs = float64(sum) / float64(len(d))
+			u.P50DurationMs = nearestRank(d, 50)
+			u.P95DurationMs = nearestRank(d, 95)
+		}
+		stats.Tools = append(stats.Tools, *u)
+	}
+	sort.Slice(stats.Tools, func(i, j int) bool {
+		if stats.Tools[i].Calls != stats.Tools[j].Calls {
+			return stats.Tools[i].Calls > stats.Tools[j].Calls
+		}
+		return stats.Tools[i].ToolName < stats.Tools[j].ToolName
+	})
+	stats.Tools = limitTo(stats.Tools, limit)
+
+	stats.Agents = limitTo(groupUsage(agents), limit)
+	stats.Projects = limitTo(groupUsage(projects), limit)
+
+	for _, cu := range commands {
+		stats.Commands = append(stats.Commands, *cu)
+	}
+	sort.Slice(stats.Commands, func(i, j int) bool {
+		if stats.Commands[i].Runs != stats.Commands[j].Runs {
+			return stats.Commands[i].Runs > stats.Commands[j].Runs
+		}
+		return stats.Commands[i].Command < stats.Commands[j].Command
+	})
+	stats.Commands = limitTo(stats.Commands, limit)
+
+	for _, fu := range files {
+		stats.Files = append(stats.Files, *fu)
+	}
+	sort.Slice(stats.Files, func(i, j int) bool {
+		if stats.Files[i].Edits != stats.Files[j].Edits {
+			return stats.Files[i].Edits > stats.Files[j].Edits
+		}
+		return stats.Files[i].Path < stats.Files[j].Path
+	})
+	stats.Files = limitTo(stats.Files, limit)
+	return stats
+}
+
+// groupUsage returns the usage of each group, most calls first
+func groupUsage(groups map[string]*toolGroup) []ToolGroupUsage {
+	usage := make([]ToolGroupUsage, 0, len(groups))
+	for _, g := range groups {
+		g.usage.ErrorRate = float64(g.usage.Errors) / float64(g.usage.Calls)
+		for tool, n := range g.tools {
+			if top := g.tools[g.usage.TopTool]; n > top || (n == top && tool < g.usage.TopTool) {
+				g.usage.TopTool = tool
+			}
+		}
+		usage = append(usage, g.usage)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Calls != usage[j].Calls {
+			return usage[i].Calls > usage[j].Calls
+		}
+		return usage[i].Name < usage[j].Name
+	})
+	return usage
+}
+
+// limitTo returns at most limit items, or all items if limit is not positive
+func limitTo[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
+// commandPrefixes are programs whose first argument is a subcommand worth
+// keeping when normalizing a shell command
+var commandPrefixes = map[string]bool{
+	"git": true, "go": true, "npm": true, "npx": true, "pnpm": true, "yarn": true, "bun": true,
+	"cargo": true, "docker": true, "kubectl": true, "make": true, "gh": true, "pip": true,
+	"uv": true, "poetry": true, "dotnet": true, "mvn": true, "gradle": true,
+}
+
+// shellCommands returns the normalized commands run by a shell tool call, or
+// nil if the arguments hold no command. Arguments are the JSON input of the
+// tool: {"command": "..."} for Claude's Bash, {"command": ["bash", "-lc", "..."]}
+// for Codex's shell and local_shell, or {"cmd": "..."}.
+func shellCommands(arguments string) []string {
+	var args struct {
+		Command json.RawMessage `json:"command"`
+		Cmd     string          `json:"cmd"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil
+	}
+
+	script := args.Cmd
+	var text string
+	var argv []string
+	if err := json.Unmarshal(args.Command, &text); err == nil {
+		script = text
+	} else if err := json.Unmarshal(args.Command, &argv); err == nil && len(argv) > 0 {
+		// Commands wrapped in a shell run the script passed with -c or -lc
+		if len(argv) >= 3 && (argv[1] == "-c" || argv[1] == "-lc") {
+			script = argv[2]
+		} else {
+			script = strings.Join(argv, " ")
+		}
+	}
+
+	var commands []string
+	for _, part := range splitShellScript(script) {
+		if command := normalizeCommand(part); command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// splitShellScript splits the first line of a script into the commands joined
+// by &&, ||, ; and |
+func splitShellScript(script string) []string {
+	script, _, _ = strings.Cut(strings.TrimSpace(script), "\n")
+	for _, sep := range []string{"&&", "||", ";"} {
+		script = strings.ReplaceAll(script, sep, "|")
+	}
+	return strings.Split(script, "|")
+}
+
+// normalizeCommand reduces a command to its program, plus the subcommand for
+// programs like git and go. Environment assignments, sudo and cd are skipped.
+func normalizeCommand(command string) string {
+	fields := strings.Fields(command)
+	for len(fields) > 0 && (strings.Contains(fields[0], "=") || fields[0] == "sudo") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || fields[0] == "cd" {
+		return ""
+	}
+
+	program := filepath.Base(fields[0])
+	if commandPrefixes[program] && len(fields) > 1 && !strings.HasPrefix(fields[1], "-") {
+		return program + " " + fields[1]
+	}
+	return program
+}
+
+// editTools are the tools whose file_path or notebook_path argument is the edited file
+var editTools = map[string]bool{"Edit": true, "MultiEdit": true, "Write": true, "NotebookEdit": true}
+
+// editedFiles returns the files changed by a tool call: the file_path of
+// Claude's edit tools, or the files added or updated by a Codex patch
+func editedFiles(toolName, arguments string) []string {
+	if editTools[toolName] {
+		var args struct {
+			FilePath     string `json:"file_path"`
+			NotebookPath string `json:"notebook_path"`
+		}
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return nil
+		}
+		if args.FilePath != "" {
+			return []string{args.FilePath}
+		}
+		if args.NotebookPath != "" {
+			return []string{args.NotebookPath}
+		}
+		return nil
+	}
+
+	// apply_patch receives the patch as raw input or inside JSON arguments,
+	// and shell tools may run apply_patch with the patch inline
+	if !strings.Contains(arguments, "*** Begin Patch") {
+		return nil
+	}
+	patch := arguments
+	var args struct {
+		Input   string          `json:"input"`
+		Command json.RawMessage `json:"command"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err == nil {
+		var argv []string
+		patch = args.Input
+		if err := json.Unmarshal(args.Command, &argv); err == nil {
+			patch = strings.Join(argv, "\n")
+		}
+	}
+
+	var paths []string
+	for _, line := range strings.Split(patch, "\n") {
+		for _, prefix := range []string{"*** Update File: ", "*** Add File: "} {
+			if path, ok := strings.CutPrefix(strings.TrimSpace(line), prefix); ok {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
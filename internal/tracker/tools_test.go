@@ -0,0 +1,79 @@
+package tracker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildToolStats(t *testing.T) {
+	calls := []SessionToolCall{
+		{Source: "codex", ProjectPath: "/src/api", ToolCallRow: ToolCallRow{SessionID: 1, ToolName: "shell", DurationMs: 1000,
+			Arguments: `{"command":["bash","-lc","cd /src/api && go test ./..."]}`}},
+		{Source: "codex", ProjectPath: "/src/api", ToolCallRow: ToolCallRow{SessionID: 1, ToolName: "shell", DurationMs: 3000, IsError: true,
+			Arguments: `{"command":["bash","-lc","go test ./... | tail -5"]}`}},
+		{Source: "codex", ProjectPath: "/src/api", ToolCallRow: ToolCallRow{SessionID: 1, ToolName: "apply_patch",
+			Arguments: "*** Begin Patch\n*** Update File: main.go\n@@\n-a\n+b\n*** Add File: util.go\n+c\n*** End Patch"}},
+		{Source: "claude", ProjectPath: "/src/web", ToolCallRow: ToolCallRow{SessionID: 2, ToolName: "shell",
+			Arguments: `{"command":"git status"}`}},
+		{Source: "claude", ProjectPath: "/src/web", ToolCallRow: ToolCallRow{SessionID: 2, ToolName: "Read", IsError: true,
+			Arguments: `{"file_path":"main.go"}`}},
+		{Source: "claude", ProjectPath: "/src/web", ToolCallRow: ToolCallRow{SessionID: 2, ToolName: "Edit",
+			Arguments: `{"file_path":"main.go","old_string":"a","new_string":"b"}`}},
+	}
+
+	stats := BuildToolStats(calls, 2)
+	if stats.CallCount != 6 || stats.SessionCount != 2 || stats.ErrorCount != 2 {
+		t.Errorf("CallCount, SessionCount, ErrorCount = %d, %d, %d; want 6, 2, 2", stats.CallCount, stats.SessionCount, stats.ErrorCount)
+	}
+	if len(stats.Tools) != 2 {
+		t.Fatalf("Tools = %+v; want the 2 most called", stats.Tools)
+	}
+	want := ToolUsage{ToolName: "shell", Calls: 3, Errors: 1, ErrorRate: 1.0 / 3, TimedCalls: 2, MeanDurationMs: 2000,
+		P50DurationMs: 1000, P95DurationMs: 3000}
+	if stats.Tools[0] != want {
+		t.Errorf("Tools[0] = %+v; want %+v", stats.Tools[0], want)
+	}
+	// Ties are ordered by name
+	if stats.Tools[1].ToolName != "Edit" {
+		t.Errorf("Tools[1] = %+v; want Edit", stats.Tools[1])
+	}
+
+	wantAgents := []ToolGroupUsage{
+		{Name: "claude", Calls: 3, Errors: 1, ErrorRate: 1.0 / 3, TopTool: "Edit"},
+		{Name: "codex", Calls: 3, Errors: 1, ErrorRate: 1.0 / 3, TopTool: "shell"},
+	}
+	if !reflect.DeepEqual(stats.Agents, wantAgents) {
+		t.Errorf("Agents = %+v; want %+v", stats.Agents, wantAgents)
+	}
+	if len(stats.Projects) != 2 || stats.Projects[0].Name != "/src/api" {
+		t.Errorf("Projects = %+v; want /src/api first", stats.Projects)
+	}
+
+	wantCommands := []CommandUsage{{Command: "go test", Runs: 2, Errors: 1}, {Command: "git status", Runs: 1}}
+	if !reflect.DeepEqual(stats.Commands, wantCommands) {
+		t.Errorf("Commands = %+v; want %+v", stats.Commands, wantCommands)
+	}
+	wantFiles := []FileUsage{{Path: "main.go", Edits: 2}, {Path: "util.go", Edits: 1}}
+	if !reflect.DeepEqual(stats.Files, wantFiles) {
+		t.Errorf("Files = %+v; want %+v", stats.Files, wantFiles)
+	}
+}
+
+func TestShellCommands(t *testing.T) {
+	tests := []struct {
+		arguments string
+		want      []string
+	}{
+		{`{"command":"git commit -m 'x' && git push"}`, []string{"git commit", "git push"}},
+		{`{"command":["bash","-lc","CGO_ENABLED=0 /usr/local/go/bin/go build ./..."]}`, []string{"go build"}},
+		{`{"command":["rg","-n","TODO"]}`, []string{"rg"}},
+		{`{"cmd":"sudo make -j4; ls"}`, []string{"make", "ls"}},
+		{`{"file_path":"main.go"}`, nil},
+		{`not json`, nil},
+	}
+	for _, tt := range tests {
+		if got := shellCommands(tt.arguments); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("shellCommands(%s) = %q; want %q", tt.arguments, got, tt.want)
+		}
+	}
+}
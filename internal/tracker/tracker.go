@@ -13,6 +13,10 @@ const (
 var (
 	ErrSessionAlreadyTracked = errors.New("session already tracked")
 	ErrSessionBackfilled     = errors.New("session backfilled")
+	ErrSessionUpdated        = errors.New("session updated")
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrAmbiguousSession      = errors.New("session id prefix matches several sessions")
+	ErrNoSession             = errors.New("no session id found")
 )
 
 // Session represents a tracking session for an agent
@@ -0,0 +1,182 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SessionTurn is a stored turn together with the session it belongs to
+type SessionTurn struct {
+	ExternalID  string `json:"external_id"`
+	Source      string `json:"source"`
+	ProjectPath string `json:"project_path"`
+	TurnRow
+}
+
+// TokenDistribution summarizes one token type over all turns of a report
+type TokenDistribution struct {
+	Type string  `json:"type"` // input, output, cache_creation, cache_read, reasoning, total or context
+	Mean float64 `json:"mean"`
+	P50  int64   `json:"p50"`
+	P90  int64   `json:"p90"`
+	P99  int64   `json:"p99"`
+	Max  int64   `json:"max"`
+}
+
+// ContextGrowth measures how fast the prompt of a session grows from turn to turn
+type ContextGrowth struct {
+	ExternalID    string  `json:"external_id"`
+	Source        string  `json:"source"`
+	ProjectPath   string  `json:"project_path"`
+	Model         string  `json:"model"`
+	Turns         int64   `json:"turns"`
+	FirstContext  int64   `json:"first_context"` // context tokens of the first turn
+	LastContext   int64   `json:"last_context"`  // context tokens of the last turn
+	GrowthPerTurn float64 `json:"growth_per_turn"`
+}
+
+// TurnStats is the turn-level analysis of the sessions matching a filter
+type TurnStats struct {
+	TurnCount     int64               `json:"turn_count"`
+	SessionCount  int64               `json:"session_count"`
+	Distribution  []TokenDistribution `json:"distribution"`
+	Slowest       []SessionTurn       `json:"slowest"`        // turns with the highest latency
+	FastestGrowth []ContextGrowth     `json:"fastest_growth"` // sessions whose context grows fastest
+}
+
+// GetTurns returns the turns of the sessions matching the filter, ordered by
+// session and turn index. Its sort order, limit and offset are ignored.
+func (db *DB) GetTurns(ctx context.Context, f SessionFilter) ([]SessionTurn, error) {
+	where, args := f.where()
+	query := `SELECT s.external_id, s.source, COALESCE(s.project_path, ''), t.id, t.session_id, t.turn_index, t.timestamp,
+		COALESCE(t.model, ''), t.input_tokens, t.output_tokens, t.cache_creation_tokens, t.cache_read_tokens,
+		t.reasoning_tokens, t.total_tokens, t.latency_ms
+		FROM turns t
+		JOIN sessions s ON s.id = t.session_id` + where + `
+		ORDER BY t.session_id, t.turn_index`
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query turns: %w", err)
+	}
+	defer rows.Close()
+
+	var turns []SessionTurn
+	for rows.Next() {
+		var t SessionTurn
+		if err := rows.Scan(&t.ExternalID, &t.Source, &t.ProjectPath, &t.ID, &t.SessionID, &t.TurnIndex, &t.Timestamp,
+			&t.Model, &t.InputTokens, &t.OutputTokens, &t.CacheCreationTokens, &t.CacheReadTokens,
+			&t.ReasoningTokens, &t.TotalTokens, &t.LatencyMs); err != nil {
+			return nil, fmt.Errorf("failed to scan turn: %w", err)
+		}
+		turns = append(turns, t)
+	}
+	return turns, rows.Err()
+}
+
+// GetTurnStats analyzes the turns of the sessions matching the filter, listing
+// up to limit slowest turns and fastest growing sessions
+func (t *SQLiteTracker) GetTurnStats(ctx context.Context, f SessionFilter, limit int) (*TurnStats, error) {
+	turns, err := t.db.GetTurns(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTurnStats(turns, limit), nil
+}
+
+// BuildTurnStats computes the turn statistics of turns ordered by session and turn index
+func BuildTurnStats(turns []SessionTurn, limit int) *TurnStats {
+	stats := &TurnStats{
+		TurnCount:     int64(len(turns)),
+		Distribution:  []TokenDistribution{},
+		Slowest:       []SessionTurn{},
+		FastestGrowth: []ContextGrowth{},
+	}
+	if len(turns) == 0 {
+		return stats
+	}
+
+	for _, d := range []struct {
+		name  string
+		value func(TurnRow) int64
+	}{
+		{"input", func(t TurnRow) int64 { return t.InputTokens }},
+		{"output", func(t TurnRow) int64 { return t.OutputTokens }},
+		{"cache_creation", func(t TurnRow) int64 { return t.CacheCreationTokens }},
+		{"cache_read", func(t TurnRow) int64 { return t.CacheReadTokens }},
+		{"reasoning", func(t TurnRow) int64 { return t.ReasoningTokens }},
+		{"total", func(t TurnRow) int64 { return t.TotalTokens }},
+		{"context", TurnRow.ContextTokens},
+	} {
+		values := make([]int64, len(turns))
+		for i, t := range turns {
+			values[i] = d.value(t.TurnRow)
+		}
+		stats.Distribution = append(stats.Distribution, distribution(d.name, values))
+	}
+
+	for _, t := range turns {
+		if t.LatencyMs > 0 {
+			stats.Slowest = append(stats.Slowest, t)
+		}
+	}
+	sort.SliceStable(stats.Slowest, func(i, j int) bool { return stats.Slowest[i].LatencyMs > stats.Slowest[j].LatencyMs })
+	if limit > 0 && len(stats.Slowest) > limit {
+		stats.Slowest = stats.Slowest[:limit]
+	}
+
+	// Turns are grouped by session, so each session is a contiguous run
+	for start := 0; start < len(turns); {
+		end := start
+		for end < len(turns) && turns[end].SessionID == turns[start].SessionID {
+			end++
+		}
+		stats.SessionCount++
+		if end-start >= 2 {
+			first, last := turns[start], turns[end-1]
+			stats.FastestGrowth = append(stats.FastestGrowth, ContextGrowth{
+				ExternalID:    first.ExternalID,
+				Source:        first.Source,
+				ProjectPath:   first.ProjectPath,
+				Model:         last.Model,
+				Turns:         int64(end - start),
+				FirstContext:  first.ContextTokens(),
+				LastContext:   last.ContextTokens(),
+				GrowthPerTurn: float64(last.ContextTokens()-first.ContextTokens()) / float64(end-start-1),
+			})
+		}
+		start = end
+	}
+	sort.SliceStable(stats.FastestGrowth, func(i, j int) bool {
+		return stats.FastestGrowth[i].GrowthPerTurn > stats.FastestGrowth[j].GrowthPerTurn
+	})
+	if limit > 0 && len(stats.FastestGrowth) > limit {
+		stats.FastestGrowth = stats.FastestGrowth[:limit]
+	}
+	return stats
+}
+
+// distribution returns the mean, nearest-rank percentiles and maximum of values
+func distribution(name string, values []int64) TokenDistribution {
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum int64
+	for _, v := range sorted {
+		sum += v
+	}
+	percentile := func(p float64) int64 {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		return sorted[max(rank, 1)-1]
+	}
+	return TokenDistribution{
+		Type: name,
+		Mean: float64(sum) / float64(len(sorted)),
+		P50:  percentile(50),
+		P90:  percentile(90),
+		P99:  percentile(99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
@@ -0,0 +1,83 @@
+package tracker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncFileStoresTurns(t *testing.T) {
+	tr := newTestTracker(t)
+	ctx := context.Background()
+	provider, _ := LookupProvider("claude")
+
+	sessionFile := filepath.Join(t.TempDir(), "session.jsonl")
+	first := `{"type":"user","timestamp":"2026-02-26T10:00:00Z","sessionId":"sess-turns","cwd":"/p","input":"Hello"}
+{"type":"assistant","timestamp":"2026-02-26T10:00:05Z","sessionId":"sess-turns","message":{"model":"claude-3","role":"assistant","content":"Hi","usage":{"input_tokens":100,"output_tokens":20}}}
+`
+	if err := os.WriteFile(sessionFile, []byte(first), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SyncFile(ctx, provider, sessionFile); err != nil {
+		t.Fatalf("SyncFile() error = %v", err)
+	}
+
+	// The latency of appended turns is measured from a user message parsed in an earlier sync
+	appendToFile(t, sessionFile, `{"type":"user","timestamp":"2026-02-26T10:05:00Z","sessionId":"sess-turns","input":"More"}
+{"type":"assistant","timestamp":"2026-02-26T10:05:09Z","sessionId":"sess-turns","message":{"model":"claude-3","role":"assistant","content":"Sure","usage":{"input_tokens":10,"output_tokens":30,"cache_read_input_tokens":400}}}
+{"type":"assistant","timestamp":"2026-02-26T10:05:30Z","sessionId":"sess-turns","message":{"model":"claude-3","role":"assistant","content":"Done","usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":700}}}
+`)
+	tr.SyncFile(ctx, provider, sessionFile)
+
+	turns, err := tr.db.GetTurns(ctx, SessionFilter{})
+	if err != nil {
+		t.Fatalf("GetTurns() error = %v", err)
+	}
+	if len(turns) != 3 {
+		t.Fatalf("GetTurns() returned %d turns; want 3", len(turns))
+	}
+	for i, want := range []struct {
+		latency int64
+		context int64
+	}{{5000, 100}, {9000, 410}, {30000, 710}} {
+		if turns[i].TurnIndex != i || turns[i].LatencyMs != want.latency || turns[i].ContextTokens() != want.context {
+			t.Errorf("turn %d = %+v; want latency %d and context %d", i, turns[i].TurnRow, want.latency, want.context)
+		}
+	}
+
+	stats, err := tr.GetTurnStats(ctx, SessionFilter{}, 2)
+	if err != nil {
+		t.Fatalf("GetTurnStats() error = %v", err)
+	}
+	if stats.TurnCount != 3 || stats.SessionCount != 1 {
+		t.Errorf("TurnCount, SessionCount = %d, %d; want 3, 1", stats.TurnCount, stats.SessionCount)
+	}
+	if len(stats.Slowest) != 2 || stats.Slowest[0].LatencyMs != 30000 || stats.Slowest[1].LatencyMs != 9000 {
+		t.Errorf("Slowest = %+v", stats.Slowest)
+	}
+	if len(stats.FastestGrowth) != 1 || stats.FastestGrowth[0].GrowthPerTurn != 305 {
+		t.Errorf("FastestGrowth = %+v; want 305 tokens per turn", stats.FastestGrowth)
+	}
+
+	// Replacing the session content replaces its turns
+	row, _ := tr.db.GetSessionByExternalID(ctx, "sess-turns")
+	if err := tr.db.DeleteSessionContent(ctx, row.ID); err != nil {
+		t.Fatal(err)
+	}
+	if turns, _ := tr.db.GetTurns(ctx, SessionFilter{}); len(turns) != 0 {
+		t.Errorf("GetTurns() after delete = %+v", turns)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	values := []int64{10, 1, 4, 3, 2, 9, 8, 7, 6, 5}
+	got := distribution("total", values)
+	want := TokenDistribution{Type: "total", Mean: 5.5, P50: 5, P90: 9, P99: 10, Max: 10}
+	if got != want {
+		t.Errorf("distribution() = %+v; want %+v", got, want)
+	}
+	if values[0] != 10 {
+		t.Error("distribution() reordered its input")
+	}
+}
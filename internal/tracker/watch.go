@@ -0,0 +1,224 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchResult is the outcome of syncing one changed session file
+type WatchResult struct {
+	Source Agent
+	Path   string
+	Err    error // follows SyncFile: nil for a new session, ErrSessionUpdated for a changed one
+}
+
+// Watcher syncs session files as agents write them. Changes are collected
+// until no file has changed for Debounce, or for at most MaxDelay while
+// agents keep writing, and each changed file is then synced incrementally.
+type Watcher struct {
+	Debounce time.Duration
+	MaxDelay time.Duration
+	// OnSync, if set, receives the result of every synced file
+	OnSync func(WatchResult)
+	// AfterSync, if set, runs after a batch of changed files stored new or updated sessions
+	AfterSync func(context.Context)
+	// OnError, if set, receives errors of the file watcher, after which every
+	// watched directory is synced again
+	OnError func(error)
+
+	tracker *SQLiteTracker
+	fs      *fsnotify.Watcher
+	roots   map[string]AgentProvider
+}
+
+// NewWatcher returns a watcher that stores sessions in t
+func NewWatcher(t *SQLiteTracker) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	return &Watcher{
+		Debounce: 2 * time.Second,
+		MaxDelay: 30 * time.Second,
+		tracker:  t,
+		fs:       fsw,
+		roots:    make(map[string]AgentProvider),
+	}, nil
+}
+
+// Add watches dir and its subdirectories for session files of provider
+func (w *Watcher) Add(p AgentProvider, dir string) error {
+	dir = filepath.Clean(dir)
+	if err := w.addTree(dir); err != nil {
+		return err
+	}
+	w.roots[dir] = p
+	return nil
+}
+
+// Close stops watching all directories
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+// addTree watches dir and every directory below it
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// A missing root is an error; directories removed while walking are not
+			if path == dir {
+				return fmt.Errorf("failed to watch %s: %w", dir, err)
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if err := w.fs.Add(path); err != nil {
+				return fmt.Errorf("failed to watch %s: %w", path, err)
+			}
+		}
+		return nil
+	})
+}
+
+// provider returns the provider whose watched directory contains path
+func (w *Watcher) provider(path string) AgentProvider {
+	var match AgentProvider
+	longest := -1
+	for root, p := range w.roots {
+		if (path == root || strings.HasPrefix(path, root+string(filepath.Separator))) && len(root) > longest {
+			match, longest = p, len(root)
+		}
+	}
+	return match
+}
+
+// Run syncs changed session files until ctx is canceled. Files changed
+// before the watcher started are not synced; run a full sync first. Errors of
+// the file watcher, such as an overflowed event queue, may lose events, so
+// they are followed by a sync of every watched directory.
+func (w *Watcher) Run(ctx context.Context) error {
+	pending := make(map[string]AgentProvider)
+	var first time.Time
+	var flush <-chan time.Time
+
+	queue := func(path string, p AgentProvider) {
+		if len(pending) == 0 {
+			first = time.Now()
+		}
+		pending[path] = p
+		delay := min(w.Debounce, w.MaxDelay-time.Since(first))
+		flush = time.After(max(delay, 0))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return nil
+			}
+			p := w.provider(event.Name)
+			if p == nil {
+				continue
+			}
+			switch {
+			case event.Has(fsnotify.Create):
+				for _, path := range w.created(event.Name) {
+					queue(path, p)
+				}
+			case event.Has(fsnotify.Write) && filepath.Ext(event.Name) == ".jsonl":
+				queue(event.Name, p)
+			}
+
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return nil
+			}
+			if w.OnError != nil {
+				w.OnError(fmt.Errorf("failed to watch session files: %w", err))
+			}
+			for path, p := range w.rescan() {
+				queue(path, p)
+			}
+
+		case <-flush:
+			w.sync(ctx, pending)
+			pending = make(map[string]AgentProvider)
+			flush = nil
+		}
+	}
+}
+
+// rescan watches every directory below the roots again and returns all their
+// session files. Unchanged files are skipped by SyncFile without being read.
+func (w *Watcher) rescan() map[string]AgentProvider {
+	files := make(map[string]AgentProvider)
+	for root, p := range w.roots {
+		if err := w.addTree(root); err != nil {
+			continue
+		}
+		paths, err := p.FindSessions(root)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			files[path] = p
+		}
+	}
+	return files
+}
+
+// created watches a created directory and returns the session files in it,
+// since files written before the watch was added send no events. A created
+// file is returned if it is a session file.
+func (w *Watcher) created(path string) []string {
+	if err := w.addTree(path); err != nil {
+		return nil
+	}
+	var files []string
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(p) == ".jsonl" {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files
+}
+
+// sync syncs the pending files in path order and records the sync time of
+// each agent that had a file stored
+func (w *Watcher) sync(ctx context.Context, pending map[string]AgentProvider) {
+	paths := make([]string, 0, len(pending))
+	for path := range pending {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	synced := make(map[Agent]bool)
+	for _, path := range paths {
+		p := pending[path]
+		err := w.tracker.SyncFile(ctx, p, path)
+		if err == nil || errors.Is(err, ErrSessionUpdated) || errors.Is(err, ErrSessionBackfilled) {
+			synced[p.Name()] = true
+		}
+		if w.OnSync != nil {
+			w.OnSync(WatchResult{Source: p.Name(), Path: path, Err: err})
+		}
+	}
+	for agent := range synced {
+		w.tracker.SetLastSyncTime(ctx, string(agent), time.Now().Unix())
+	}
+	if len(synced) > 0 && w.AfterSync != nil {
+		w.AfterSync(ctx)
+	}
+}
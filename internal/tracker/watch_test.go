@@ -0,0 +1,140 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatcherSyncsChangedFiles(t *testing.T) {
+	tr := newTestTracker(t)
+	provider, _ := LookupProvider("codex")
+	dir := t.TempDir()
+
+	w, err := NewWatcher(tr)
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	defer w.Close()
+	w.Debounce = 50 * time.Millisecond
+	results := make(chan WatchResult, 10)
+	w.OnSync = func(r WatchResult) { results <- r }
+	if err := w.Add(provider, dir); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	next := func() WatchResult {
+		t.Helper()
+		select {
+		case r := <-results:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("no file synced")
+			return WatchResult{}
+		}
+	}
+
+	// Files in directories created after the watch started are found
+	sessionFile := filepath.Join(dir, "2026", "03", "02", "rollout.jsonl")
+	if err := os.MkdirAll(filepath.Dir(sessionFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sessionFile, []byte(`{"timestamp":"2026-03-02T10:00:00Z","type":"session_meta","payload":{"id":"watch-1","cwd":"/p","model_provider":"openai"}}
+{"timestamp":"2026-03-02T10:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Hello"}]}}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r := next(); r.Path != sessionFile || r.Source != AgentCodex || r.Err != nil {
+		t.Fatalf("first result = %+v; want new session from %s", r, sessionFile)
+	}
+
+	appendToFile(t, sessionFile, `{"timestamp":"2026-03-02T10:00:05Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Hi"}]}}
+`)
+	if r := next(); !errors.Is(r.Err, ErrSessionUpdated) {
+		t.Fatalf("second result = %+v; want ErrSessionUpdated", r)
+	}
+
+	detail, err := tr.GetSessionDetail(ctx, "watch-1")
+	if err != nil {
+		t.Fatalf("GetSessionDetail() error = %v", err)
+	}
+	if len(detail.Messages) != 2 {
+		t.Errorf("stored %d messages; want 2", len(detail.Messages))
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestWatcherResyncsAfterError(t *testing.T) {
+	tr := newTestTracker(t)
+	provider, _ := LookupProvider("codex")
+	dir := t.TempDir()
+
+	// Written before the watch started, so only a resync finds it
+	sessionFile := filepath.Join(dir, "rollout.jsonl")
+	if err := os.WriteFile(sessionFile, []byte(`{"timestamp":"2026-03-02T10:00:00Z","type":"session_meta","payload":{"id":"watch-2","cwd":"/p","model_provider":"openai"}}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWatcher(tr)
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	defer w.Close()
+	w.Debounce = 50 * time.Millisecond
+	results := make(chan WatchResult, 10)
+	w.OnSync = func(r WatchResult) { results <- r }
+	watchErrs := make(chan error, 1)
+	w.OnError = func(err error) { watchErrs <- err }
+	if err := w.Add(provider, dir); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	next := func() WatchResult {
+		t.Helper()
+		select {
+		case r := <-results:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("no file synced")
+			return WatchResult{}
+		}
+	}
+
+	w.fs.Errors <- fsnotify.ErrEventOverflow
+	if err := <-watchErrs; !errors.Is(err, fsnotify.ErrEventOverflow) {
+		t.Errorf("OnError() got %v; want ErrEventOverflow", err)
+	}
+	if r := next(); r.Path != sessionFile || r.Err != nil {
+		t.Fatalf("result after error = %+v; want new session from %s", r, sessionFile)
+	}
+
+	// The watcher keeps running
+	appendToFile(t, sessionFile, `{"timestamp":"2026-03-02T10:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Hello"}]}}
+`)
+	if r := next(); !errors.Is(r.Err, ErrSessionUpdated) {
+		t.Fatalf("result after write = %+v; want ErrSessionUpdated", r)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+// BudgetsReport is the JSON document written by the budget check command.
+// The JSON renderer fills in SchemaVersion and Exceeded.
+type BudgetsReport struct {
+	SchemaVersion int                    `json:"schema_version"`
+	Exceeded      bool                   `json:"exceeded"` // whether any budget is over its cap
+	Budgets       []tracker.BudgetStatus `json:"budgets"`
+}
+
+// budgetBarWidth is the number of cells of a budget progress bar
+const budgetBarWidth = 20
+
+// progressBar draws the used fraction of a budget, full once it is exceeded
+func progressBar(fraction float64) string {
+	filled := int(fraction * budgetBarWidth)
+	filled = max(0, min(filled, budgetBarWidth))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", budgetBarWidth-filled) + "]"
+}
+
+// budgetColor is green below 80% of the cap, yellow up to the cap and red above it
+func budgetColor(s tracker.BudgetStatus) string {
+	switch {
+	case s.Exceeded:
+		return ColorRed
+	case s.Fraction >= 0.8:
+		return ColorYellow
+	default:
+		return ColorGreen
+	}
+}
+
+// budgetName names the scope of a budget, e.g. "All agents" or "project api"
+func budgetName(b tracker.Budget) string {
+	if b.Scope == tracker.ScopeGlobal {
+		return "All agents"
+	}
+	if b.Scope == tracker.ScopeAgent {
+		return tracker.DisplayName(b.Name)
+	}
+	return string(b.Scope) + " " + b.Name
+}
+
+// formatBudgetAmount formats dollars or tokens
+func formatBudgetAmount(unit tracker.BudgetUnit, amount float64) string {
+	if unit == tracker.UnitTokens {
+		return FormatTokens(int64(amount))
+	}
+	return FormatCost(amount)
+}
+
+// writeBudgets writes a progress bar per budget
+func writeBudgets(w io.Writer, statuses []tracker.BudgetStatus) {
+	fmt.Fprintf(w, "\n%s%sBudgets%s\n", ColorBold, ColorYellow, ColorReset)
+	for _, s := range statuses {
+		fmt.Fprintf(w, "  %-24s %-8s %s%s %4.0f%%%s  %s / %s\n", truncate(budgetName(s.Budget), 24), s.Period,
+			budgetColor(s), progressBar(s.Fraction), s.Fraction*100, ColorReset,
+			formatBudgetAmount(s.Unit, s.Used), formatBudgetAmount(s.Unit, s.Limit))
+	}
+}
+
+// budgetsTable has one row per budget
+func budgetsTable(statuses []tracker.BudgetStatus) table {
+	t := table{header: []string{"scope", "name", "period", "unit", "limit", "used", "fraction", "exceeded", "start", "end"}}
+	for _, s := range statuses {
+		t.rows = append(t.rows, []string{string(s.Scope), s.Name, string(s.Period), string(s.Unit),
+			strconv.FormatFloat(s.Limit, 'f', -1, 64), strconv.FormatFloat(s.Used, 'f', -1, 64),
+			strconv.FormatFloat(s.Fraction, 'f', 4, 64), strconv.FormatBool(s.Exceeded),
+			strconv.FormatInt(s.Start, 10), strconv.FormatInt(s.End, 10)})
+	}
+	return t
+}
+
+// markdownBudgetsTable has one row per budget with a progress bar
+func markdownBudgetsTable(statuses []tracker.BudgetStatus) table {
+	t := table{header: []string{"Budget", "Period", "Progress", "Used", "Limit"}}
+	for _, s := range statuses {
+		t.rows = append(t.rows, []string{budgetName(s.Budget), string(s.Period),
+			"`" + progressBar(s.Fraction) + "` " + formatRate(s.Fraction),
+			formatBudgetAmount(s.Unit, s.Used), formatBudgetAmount(s.Unit, s.Limit)})
+	}
+	return t
+}
+
+func (textRenderer) RenderBudgets(w io.Writer, report *BudgetsReport) error {
+	if len(report.Budgets) == 0 {
+		fmt.Fprintf(w, "%sNo budgets configured%s\n", ColorYellow, ColorReset)
+		return nil
+	}
+	writeBudgets(w, report.Budgets)
+	if tracker.BudgetsExceeded(report.Budgets) {
+		fmt.Fprintf(w, "\n%sBudget exceeded%s\n", ColorRed, ColorReset)
+	}
+	return nil
+}
+
+func (jsonRenderer) RenderBudgets(w io.Writer, report *BudgetsReport) error {
+	budgets := report.Budgets
+	if budgets == nil {
+		budgets = []tracker.BudgetStatus{}
+	}
+	return writeJSON(w, BudgetsReport{
+		SchemaVersion: JSONSchemaVersion,
+		Exceeded:      tracker.BudgetsExceeded(budgets),
+		Budgets:       budgets,
+	})
+}
+
+func (r delimitedRenderer) RenderBudgets(w io.Writer, report *BudgetsReport) error {
+	return r.write(w, budgetsTable(report.Budgets))
+}
+
+func (markdownRenderer) RenderBudgets(w io.Writer, report *BudgetsReport) error {
+	fmt.Fprint(w, "## Budgets\n\n")
+	heading := "Within Budget"
+	if tracker.BudgetsExceeded(report.Budgets) {
+		heading = "Budget Exceeded"
+	}
+	writeMarkdownSection(w, heading, markdownBudgetsTable(report.Budgets))
+	return nil
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -51,108 +52,221 @@ func FormatCost(cost float64) string {
 	return fmt.Sprintf("$%.2f", cost)
 }
 
+// location is the time zone timestamps are displayed in
+var location = time.Local
+
+// SetLocation sets the time zone timestamps are displayed in
+func SetLocation(loc *time.Location) {
+	location = loc
+}
+
 // FormatDateTime formats a Unix timestamp into a human-readable datetime
 func FormatDateTime(timestamp int64) string {
 	if timestamp == 0 {
 		return "-"
 	}
-	t := time.Unix(timestamp, 0)
+	t := time.Unix(timestamp, 0).In(location)
 	return t.Format("2006-01-02 15:04:05")
 }
 
 // DisplayUsageStats displays the usage statistics with formatting
-func DisplayUsageStats(agent string, period tracker.Period, stats *tracker.UsageStatsData) {
-	// Print period header
-	periodStr := string(period)
-	if period == "" {
-		periodStr = "day"
-	}
-	fmt.Printf("\n%s Usage Statistics - %s\n", strings.Title(agent), strings.Title(periodStr))
-	fmt.Println(strings.Repeat("=", 60))
+func DisplayUsageStats(agent string, period tracker.TimeRange, stats *tracker.UsageStatsData) {
+	writeUsageStats(os.Stdout, agent, period, stats)
+}
+
+// writeUsageStats writes the colored text form of the usage statistics
+func writeUsageStats(w io.Writer, agent string, period tracker.TimeRange, stats *tracker.UsageStatsData) {
+	fmt.Fprintf(w, "\n%s Usage Statistics - %s\n", tracker.DisplayName(agent), titlePeriod(period))
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+	writeProjectFilter(w, stats.Project)
 
 	// Last Session
-	fmt.Printf("\n%s%sLast Session%s\n", ColorBold, ColorBlue, ColorReset)
+	fmt.Fprintf(w, "\n%s%sLast Session%s\n", ColorBold, ColorBlue, ColorReset)
 	if stats.LastSession != nil {
-		fmt.Printf("  ID:         %s\n", stats.LastSession.ExternalID)
-		fmt.Printf("  Start:      %s\n", FormatDateTime(stats.LastSession.StartedAt))
-		fmt.Printf("  Project:    %s\n", stats.LastSession.ProjectPath)
-		fmt.Printf("  Model:      %s\n", stats.LastSession.Model)
-		fmt.Printf("  Provider:   %s\n", stats.LastSession.Provider)
+		fmt.Fprintf(w, "  ID:         %s\n", stats.LastSession.ExternalID)
+		fmt.Fprintf(w, "  Start:      %s\n", FormatDateTime(stats.LastSession.StartedAt))
+		fmt.Fprintf(w, "  Project:    %s\n", stats.LastSession.ProjectPath)
+		fmt.Fprintf(w, "  Model:      %s\n", stats.LastSession.Model)
+		fmt.Fprintf(w, "  Provider:   %s\n", stats.LastSession.Provider)
 		if stats.LastSession.EndedAt != nil {
 			duration := *stats.LastSession.EndedAt - stats.LastSession.StartedAt
-			fmt.Printf("  End:        %s\n", FormatDateTime(*stats.LastSession.EndedAt))
-			fmt.Printf("  Duration:   %s\n", FormatDuration(duration))
+			fmt.Fprintf(w, "  End:        %s\n", FormatDateTime(*stats.LastSession.EndedAt))
+			fmt.Fprintf(w, "  Duration:   %s\n", FormatDuration(duration))
 		}
-		fmt.Printf("  Tokens:     %s (in: %s, out: %s, cache: %s/%s)\n",
+		fmt.Fprintf(w, "  Tokens:     %s (in: %s, out: %s, cache: %s/%s)\n",
 			FormatTokens(stats.LastSession.TotalTokens),
 			FormatTokens(stats.LastSession.InputTokens),
 			FormatTokens(stats.LastSession.OutputTokens),
 			FormatTokens(stats.LastSession.CacheCreationTokens),
 			FormatTokens(stats.LastSession.CacheReadTokens))
-		fmt.Printf("  Messages:   %d\n", stats.LastSession.MessageCount)
+		fmt.Fprintf(w, "  Messages:   %d\n", stats.LastSession.MessageCount)
+		fmt.Fprintf(w, "  Cost:       %s\n", FormatCost(stats.LastSession.Cost))
 	} else {
-		fmt.Printf("  %sNo sessions in this period%s\n", ColorYellow, ColorReset)
+		fmt.Fprintf(w, "  %sNo sessions in this period%s\n", ColorYellow, ColorReset)
 	}
 
 	// Summary Stats
-	fmt.Printf("\n%s%sSummary%s\n", ColorBold, ColorMagenta, ColorReset)
-	fmt.Printf("  Total Sessions:     %d\n", stats.SessionCount)
-	fmt.Printf("  Total Session Time: %s\n", FormatDuration(stats.TotalSessionTime))
-	fmt.Printf("  Total Tokens:       %s (in: %s, out: %s, cache: %s/%s)\n",
+	fmt.Fprintf(w, "\n%s%sSummary%s\n", ColorBold, ColorMagenta, ColorReset)
+	fmt.Fprintf(w, "  Total Sessions:     %d\n", stats.SessionCount)
+	fmt.Fprintf(w, "  Total Session Time: %s\n", FormatDuration(stats.TotalSessionTime))
+	fmt.Fprintf(w, "  Total Tokens:       %s (in: %s, out: %s, cache: %s/%s)\n",
 		FormatTokens(stats.TotalTokens),
 		FormatTokens(stats.TotalInputTokens),
 		FormatTokens(stats.TotalOutputTokens),
 		FormatTokens(stats.TotalCacheCreation),
 		FormatTokens(stats.TotalCacheRead))
-	fmt.Printf("  Total Messages:     %d\n", stats.TotalMessages)
+	fmt.Fprintf(w, "  Total Messages:     %d\n", stats.TotalMessages)
+	fmt.Fprintf(w, "  Total Tool Calls:   %d\n", stats.TotalToolCalls)
+	fmt.Fprintf(w, "  Total Cost:         %s\n", FormatCost(stats.TotalCost))
 
 	// Last Sync Time
-	fmt.Printf("  Last Sync:         ")
+	fmt.Fprintf(w, "  Last Sync:         ")
 	if stats.LastSyncTime > 0 {
-		fmt.Printf("%s\n", FormatDateTime(stats.LastSyncTime))
+		fmt.Fprintf(w, "%s\n", FormatDateTime(stats.LastSyncTime))
 	} else {
-		fmt.Printf("%sNever synced%s\n", ColorYellow, ColorReset)
+		fmt.Fprintf(w, "%sNever synced%s\n", ColorYellow, ColorReset)
 	}
 
-	// Daily Summary (for weekly period)
+	// Daily Summary (for ranges up to two weeks)
 	if len(stats.DailySummaries) > 0 {
-		fmt.Printf("\n%s%sDaily Summary (last 7 days)%s\n", ColorBold, ColorCyan, ColorReset)
-		fmt.Printf("  %-12s %10s %12s %12s\n", "Date", "Sessions", "Duration", "Tokens")
-		fmt.Printf("  %s\n", strings.Repeat("-", 52))
+		fmt.Fprintf(w, "\n%s%sDaily Summary%s\n", ColorBold, ColorCyan, ColorReset)
+		fmt.Fprintf(w, "  %-12s %10s %12s %12s %10s\n", "Date", "Sessions", "Duration", "Tokens", "Cost")
+		fmt.Fprintf(w, "  %s\n", strings.Repeat("-", 63))
 		for _, d := range stats.DailySummaries {
-			fmt.Printf("  %-12s %10d %12s %12s\n",
+			fmt.Fprintf(w, "  %-12s %10d %12s %12s %10s\n",
 				d.Date,
 				d.SessionCount,
 				FormatDuration(d.TotalTime),
-				FormatTokens(d.TotalTokens))
+				FormatTokens(d.TotalTokens),
+				FormatCost(d.TotalCost))
 		}
 	}
 
-	// Weekly Summary (for monthly period)
+	// Weekly Summary (for longer ranges)
 	if len(stats.WeeklySummaries) > 0 {
-		fmt.Printf("\n%s%sWeekly Summary (last 30 days)%s\n", ColorBold, ColorCyan, ColorReset)
-		fmt.Printf("  %-12s %10s %12s %12s\n", "Week", "Sessions", "Duration", "Tokens")
-		fmt.Printf("  %s\n", strings.Repeat("-", 52))
-		for _, w := range stats.WeeklySummaries {
-			fmt.Printf("  %-12s %10d %12s %12s\n",
-				w.WeekStart,
-				w.SessionCount,
-				FormatDuration(w.TotalTime),
-				FormatTokens(w.TotalTokens))
+		fmt.Fprintf(w, "\n%s%sWeekly Summary%s\n", ColorBold, ColorCyan, ColorReset)
+		fmt.Fprintf(w, "  %-12s %10s %12s %12s %10s\n", "Week", "Sessions", "Duration", "Tokens", "Cost")
+		fmt.Fprintf(w, "  %s\n", strings.Repeat("-", 63))
+		for _, week := range stats.WeeklySummaries {
+			fmt.Fprintf(w, "  %-12s %10d %12s %12s %10s\n",
+				week.WeekStart,
+				week.SessionCount,
+				FormatDuration(week.TotalTime),
+				FormatTokens(week.TotalTokens),
+				FormatCost(week.TotalCost))
 		}
 	}
 
-	// Top Models
-	fmt.Printf("\n%s%sTop Models (by session count)%s\n", ColorBold, ColorGreen, ColorReset)
-	if len(stats.TopModels) > 0 {
-		for i, m := range stats.TopModels {
-			fmt.Printf("  %d. %s - %d sessions\n", i+1, m.Model, m.SessionCount)
+	writeTopModels(w, stats)
+	writeTopProjects(w, stats)
+
+	fmt.Fprintln(w, "\n"+strings.Repeat("=", 60))
+}
+
+// writeTopModels writes the top models in the order chosen by stats.SortBy
+func writeTopModels(w io.Writer, stats *tracker.UsageStatsData) {
+	fmt.Fprintf(w, "\n%s%sTop Models (%s)%s\n", ColorBold, ColorGreen, sortLabel(stats.SortBy), ColorReset)
+	if len(stats.TopModels) == 0 {
+		fmt.Fprintf(w, "  %sNo data%s\n", ColorYellow, ColorReset)
+		return
+	}
+	for i, m := range stats.TopModels {
+		fmt.Fprintf(w, "  %d. %s - %d sessions, %s tokens, %s\n",
+			i+1, m.Model, m.SessionCount, FormatTokens(m.TotalTokens), FormatCost(m.TotalCost))
+	}
+}
+
+// writeTopProjects writes the top projects in the order chosen by stats.SortBy
+func writeTopProjects(w io.Writer, stats *tracker.UsageStatsData) {
+	fmt.Fprintf(w, "\n%s%sTop Projects (%s)%s\n", ColorBold, ColorGreen, sortLabel(stats.SortBy), ColorReset)
+	if len(stats.TopProjects) == 0 {
+		fmt.Fprintf(w, "  %sNo data%s\n", ColorYellow, ColorReset)
+		return
+	}
+	for i, p := range stats.TopProjects {
+		fmt.Fprintf(w, "  %d. %s - %d sessions, %s tokens, %s\n",
+			i+1, projectName(p.ProjectPath), p.SessionCount, FormatTokens(p.TotalTokens), FormatCost(p.TotalCost))
+	}
+}
+
+// sortLabel describes a sort order in section headings
+func sortLabel(sort tracker.SortOrder) string {
+	switch sort {
+	case tracker.SortCost:
+		return "by cost"
+	case tracker.SortTokens:
+		return "by tokens"
+	case tracker.SortTime:
+		return "by session time"
+	case tracker.SortRecent:
+		return "most recent first"
+	case tracker.SortOldest:
+		return "oldest first"
+	default:
+		return "by session count"
+	}
+}
+
+// writeProjectFilter writes the project filter below a heading, if one is set
+func writeProjectFilter(w io.Writer, project string) {
+	if project != "" {
+		fmt.Fprintf(w, "Project: %s\n", project)
+	}
+}
+
+// writeProjects writes the colored text form of the projects report
+func writeProjects(w io.Writer, period tracker.TimeRange, project string, sort tracker.SortOrder, projects []tracker.ProjectStats) {
+	fmt.Fprintf(w, "\n%sProjects - %s (%s)%s\n", ColorBold, titlePeriod(period), sortLabel(sort), ColorReset)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+	writeProjectFilter(w, project)
+
+	if len(projects) == 0 {
+		fmt.Fprintf(w, "\n  %sNo sessions in this period%s\n", ColorYellow, ColorReset)
+		return
+	}
+
+	fmt.Fprintf(w, "\n  %-20s %8s %10s %24s %8s %10s  %-20s %s\n",
+		"Project", "Sessions", "Time", "Tokens (in/out/crea/read)", "Messages", "Cost", "Top Model", "Last Active")
+	fmt.Fprintf(w, "  %s\n", strings.Repeat("-", 130))
+	for _, p := range projects {
+		model := p.TopModel
+		if model == "" {
+			model = "(unknown)"
 		}
-	} else {
-		fmt.Printf("  %sNo data%s\n", ColorYellow, ColorReset)
+		fmt.Fprintf(w, "  %-20s %8d %10s %24s %8d %10s  %-20s %s\n",
+			truncate(projectName(p.ProjectPath), 20),
+			p.SessionCount,
+			FormatDuration(p.TotalTime),
+			fmt.Sprintf("%s/%s/%s/%s",
+				FormatTokens(p.TotalInputTokens),
+				FormatTokens(p.TotalOutputTokens),
+				FormatTokens(p.TotalCacheCreation),
+				FormatTokens(p.TotalCacheRead)),
+			p.TotalMessages,
+			FormatCost(p.TotalCost),
+			truncate(model, 20),
+			FormatDateTime(p.LastActivity))
+	}
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
 	}
+	return string(r[:n-1]) + "…"
+}
 
-	fmt.Println("\n" + strings.Repeat("=", 60))
+// projectName returns the folder name of a project path
+func projectName(path string) string {
+	if path == "" {
+		return "(no project)"
+	}
+	if idx := strings.LastIndex(path, "/"); idx >= 0 {
+		return path[idx+1:]
+	}
+	return path
 }
 
 // Error displays an error message
@@ -161,19 +275,20 @@ func Error(msg string) {
 }
 
 // DisplayAllStats displays combined usage statistics for all agents
-func DisplayAllStats(period tracker.Period, stats *tracker.UsageStatsData, perAgent []tracker.PerAgentStats) {
-	// Print period header
-	periodStr := string(period)
-	if period == "" {
-		periodStr = "day"
-	}
-	fmt.Printf("\n%sCombined Usage Statistics - %s%s\n", ColorBold, strings.Title(periodStr), ColorReset)
-	fmt.Println(strings.Repeat("=", 60))
+func DisplayAllStats(period tracker.TimeRange, stats *tracker.UsageStatsData, perAgent []tracker.PerAgentStats) {
+	writeAllStats(os.Stdout, period, stats, perAgent)
+}
+
+// writeAllStats writes the colored text form of the combined usage statistics
+func writeAllStats(w io.Writer, period tracker.TimeRange, stats *tracker.UsageStatsData, perAgent []tracker.PerAgentStats) {
+	fmt.Fprintf(w, "\n%sCombined Usage Statistics - %s%s\n", ColorBold, titlePeriod(period), ColorReset)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+	writeProjectFilter(w, stats.Project)
 
 	// Per-agent breakdown
-	fmt.Printf("\n%s%sPer-Agent Breakdown%s\n", ColorBold, ColorBlue, ColorReset)
-	fmt.Printf("  %-12s %10s %12s %24s %10s\n", "Agent", "Sessions", "Time", "Tokens (in/out/crea/read)", "Messages")
-	fmt.Printf("  %s\n", strings.Repeat("-", 74))
+	fmt.Fprintf(w, "\n%s%sPer-Agent Breakdown%s\n", ColorBold, ColorBlue, ColorReset)
+	fmt.Fprintf(w, "  %-12s %10s %12s %24s %10s %10s\n", "Agent", "Sessions", "Time", "Tokens (in/out/crea/read)", "Messages", "Cost")
+	fmt.Fprintf(w, "  %s\n", strings.Repeat("-", 85))
 
 	var totalSessions int64
 	var totalTime int64
@@ -183,15 +298,11 @@ func DisplayAllStats(period tracker.Period, stats *tracker.UsageStatsData, perAg
 	var totalCacheCreation int64
 	var totalCacheRead int64
 	var totalMessages int64
+	var totalCost float64
 
 	for _, p := range perAgent {
-		source := p.Source
-		if source == "codex" {
-			source = "Codex"
-		} else if source == "claude" {
-			source = "Claude"
-		}
-		fmt.Printf("  %-12s %10d %12s %s %10d\n",
+		source := tracker.DisplayName(p.Source)
+		fmt.Fprintf(w, "  %-12s %10d %12s %24s %10d %10s\n",
 			source,
 			p.SessionCount,
 			FormatDuration(p.TotalTime),
@@ -200,7 +311,8 @@ func DisplayAllStats(period tracker.Period, stats *tracker.UsageStatsData, perAg
 				FormatTokens(p.TotalOutputTokens),
 				FormatTokens(p.TotalCacheCreation),
 				FormatTokens(p.TotalCacheRead)),
-			p.TotalMessages)
+			p.TotalMessages,
+			FormatCost(p.TotalCost))
 		totalSessions += p.SessionCount
 		totalTime += p.TotalTime
 		totalTokens += p.TotalTokens
@@ -209,11 +321,12 @@ func DisplayAllStats(period tracker.Period, stats *tracker.UsageStatsData, perAg
 		totalCacheCreation += p.TotalCacheCreation
 		totalCacheRead += p.TotalCacheRead
 		totalMessages += p.TotalMessages
+		totalCost += p.TotalCost
 	}
 
 	// Combined totals
-	fmt.Printf("  %s\n", strings.Repeat("-", 74))
-	fmt.Printf("  %-12s %10d %12s %s %10d\n",
+	fmt.Fprintf(w, "  %s\n", strings.Repeat("-", 85))
+	fmt.Fprintf(w, "  %-12s %10d %12s %24s %10d %10s\n",
 		"Total",
 		totalSessions,
 		FormatDuration(totalTime),
@@ -222,50 +335,45 @@ func DisplayAllStats(period tracker.Period, stats *tracker.UsageStatsData, perAg
 			FormatTokens(totalOutputTokens),
 			FormatTokens(totalCacheCreation),
 			FormatTokens(totalCacheRead)),
-		totalMessages)
+		totalMessages,
+		FormatCost(totalCost))
 
 	// Summary Stats
-	fmt.Printf("\n%s%sSummary%s\n", ColorBold, ColorMagenta, ColorReset)
-	fmt.Printf("  Total Sessions:      %d\n", stats.SessionCount)
-	fmt.Printf("  Total Session Time:  %s\n", FormatDuration(stats.TotalSessionTime))
-	fmt.Printf("  Total Tokens:        %s (in: %s, out: %s, cache: %s/%s)\n",
+	fmt.Fprintf(w, "\n%s%sSummary%s\n", ColorBold, ColorMagenta, ColorReset)
+	fmt.Fprintf(w, "  Total Sessions:      %d\n", stats.SessionCount)
+	fmt.Fprintf(w, "  Total Session Time:  %s\n", FormatDuration(stats.TotalSessionTime))
+	fmt.Fprintf(w, "  Total Tokens:        %s (in: %s, out: %s, cache: %s/%s)\n",
 		FormatTokens(stats.TotalTokens),
 		FormatTokens(stats.TotalInputTokens),
 		FormatTokens(stats.TotalOutputTokens),
 		FormatTokens(stats.TotalCacheCreation),
 		FormatTokens(stats.TotalCacheRead))
-	fmt.Printf("  Total Messages:      %d\n", stats.TotalMessages)
-	fmt.Printf("  Unique Projects:     %d\n", stats.UniqueProjects)
+	fmt.Fprintf(w, "  Total Messages:      %d\n", stats.TotalMessages)
+	fmt.Fprintf(w, "  Total Tool Calls:    %d\n", stats.TotalToolCalls)
+	fmt.Fprintf(w, "  Total Cost:          %s\n", FormatCost(stats.TotalCost))
+	fmt.Fprintf(w, "  Unique Projects:     %d\n", stats.UniqueProjects)
 
 	// Last Sync Time
-	fmt.Printf("  Last Sync:          ")
+	fmt.Fprintf(w, "  Last Sync:          ")
 	if stats.LastSyncTime > 0 {
-		fmt.Printf("%s\n", FormatDateTime(stats.LastSyncTime))
+		fmt.Fprintf(w, "%s\n", FormatDateTime(stats.LastSyncTime))
 	} else {
-		fmt.Printf("%sNever synced%s\n", ColorYellow, ColorReset)
+		fmt.Fprintf(w, "%sNever synced%s\n", ColorYellow, ColorReset)
 	}
 
-	// Top Models
-	fmt.Printf("\n%s%sTop Models (by session count)%s\n", ColorBold, ColorGreen, ColorReset)
-	if len(stats.TopModels) > 0 {
-		for i, m := range stats.TopModels {
-			fmt.Printf("  %d. %s - %d sessions\n", i+1, m.Model, m.SessionCount)
-		}
-	} else {
-		fmt.Printf("  %sNo data%s\n", ColorYellow, ColorReset)
+	if len(stats.Budgets) > 0 {
+		writeBudgets(w, stats.Budgets)
 	}
 
+	writeTopModels(w, stats)
+	writeTopProjects(w, stats)
+
 	// Recent Sessions
-	fmt.Printf("\n%s%sLast %d Sessions%s\n", ColorBold, ColorCyan, len(stats.RecentSessions), ColorReset)
+	fmt.Fprintf(w, "\n%s%sLast %d Sessions%s\n", ColorBold, ColorCyan, len(stats.RecentSessions), ColorReset)
 	if len(stats.RecentSessions) > 0 {
 		for i, s := range stats.RecentSessions {
 			// Format source name
-			source := s.Source
-			if source == "codex" {
-				source = "Codex"
-			} else if source == "claude" {
-				source = "Claude"
-			}
+			source := tracker.DisplayName(s.Source)
 
 			// Format model
 			model := s.Model
@@ -273,19 +381,10 @@ func DisplayAllStats(period tracker.Period, stats *tracker.UsageStatsData, perAg
 				model = "(unknown)"
 			}
 
-			// Get project name from path
-			project := s.ProjectPath
-			if project != "" {
-				// Extract just the folder name
-				if idx := strings.LastIndex(project, "/"); idx >= 0 {
-					project = project[idx+1:]
-				}
-			} else {
-				project = "(no project)"
-			}
+			project := projectName(s.ProjectPath)
 
 			// Format time
-			startTime := time.Unix(s.StartedAt, 0)
+			startTime := time.Unix(s.StartedAt, 0).In(location)
 			timeStr := startTime.Format("Jan 02 15:04")
 
 			// Duration
@@ -300,12 +399,12 @@ func DisplayAllStats(period tracker.Period, stats *tracker.UsageStatsData, perAg
 			creaTokens := FormatTokens(s.CacheCreationTokens)
 			readTokens := FormatTokens(s.CacheReadTokens)
 
-			fmt.Printf("  %d. %s %s | %s | %s | %s | %s (cache: %s/%s, msgs: %d)\n",
-				i+1, timeStr, source, model, project, duration, tokens, creaTokens, readTokens, s.MessageCount)
+			fmt.Fprintf(w, "  %d. %s %s | %s | %s | %s | %s | %s (cache: %s/%s, msgs: %d)\n",
+				i+1, timeStr, source, model, project, duration, tokens, FormatCost(s.Cost), creaTokens, readTokens, s.MessageCount)
 		}
 	} else {
-		fmt.Printf("  %sNo data%s\n", ColorYellow, ColorReset)
+		fmt.Fprintf(w, "  %sNo data%s\n", ColorYellow, ColorReset)
 	}
 
-	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Fprintln(w, "\n"+strings.Repeat("=", 60))
 }
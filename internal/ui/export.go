@@ -0,0 +1,252 @@
+package ui
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+// ExportFormat names a transcript format of the export-session command
+type ExportFormat string
+
+const (
+	ExportMarkdown ExportFormat = "md"
+	ExportHTML     ExportFormat = "html"
+	ExportJSON     ExportFormat = "json"
+)
+
+// ExportFormats lists the supported transcript formats
+var ExportFormats = []ExportFormat{ExportMarkdown, ExportHTML, ExportJSON}
+
+// ParseExportFormat returns the transcript format with the given name
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch ExportFormat(name) {
+	case ExportMarkdown, "markdown":
+		return ExportMarkdown, nil
+	case ExportHTML, ExportJSON:
+		return ExportFormat(name), nil
+	default:
+		names := make([]string, len(ExportFormats))
+		for i, f := range ExportFormats {
+			names[i] = string(f)
+		}
+		return "", fmt.Errorf("invalid format: %s. Use %s", name, strings.Join(names, ", "))
+	}
+}
+
+// ExportSession writes the transcript of a session in the given format
+func ExportSession(w io.Writer, format ExportFormat, d *tracker.SessionDetail) error {
+	switch format {
+	case ExportHTML:
+		return exportHTML(w, d)
+	case ExportJSON:
+		return jsonRenderer{}.RenderSession(w, d)
+	default:
+		exportMarkdown(w, d)
+		return nil
+	}
+}
+
+// contentPart is a piece of message content, either thinking or visible text
+type contentPart struct {
+	Thinking bool
+	Text     string
+}
+
+// splitThinking splits message content at the thinking markers
+func splitThinking(content string) []contentPart {
+	var parts []contentPart
+	add := func(thinking bool, text string) {
+		if text = strings.Trim(text, "\n"); text != "" {
+			parts = append(parts, contentPart{Thinking: thinking, Text: text})
+		}
+	}
+	for {
+		start := strings.Index(content, tracker.ThinkingStart)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(content[start:], tracker.ThinkingEnd)
+		if end < 0 {
+			break
+		}
+		end += start
+		add(false, content[:start])
+		add(true, content[start+len(tracker.ThinkingStart):end])
+		content = content[end+len(tracker.ThinkingEnd):]
+	}
+	add(false, content)
+	return parts
+}
+
+// roleTitle returns the heading of a message role
+func roleTitle(role string) string {
+	if role == "" {
+		return "Message"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// transcriptSummary returns the header fields of a transcript
+func transcriptSummary(d *tracker.SessionDetail) [][2]string {
+	s := d.Session
+	tokens := fmt.Sprintf("%s (input %s, output %s, cache write %s, cache read %s, reasoning %s)",
+		FormatTokens(s.TotalTokens), FormatTokens(s.InputTokens), FormatTokens(s.OutputTokens),
+		FormatTokens(s.CacheCreationTokens), FormatTokens(s.CacheReadTokens), FormatTokens(s.ReasoningTokens))
+	return [][2]string{
+		{"Agent", tracker.DisplayName(s.Source)},
+		{"Project", s.ProjectPath},
+		{"Model", s.Model},
+		{"Started", FormatDateTime(s.StartedAt)},
+		{"Duration", sessionDuration(s)},
+		{"Messages", fmt.Sprintf("%d", len(d.Messages))},
+		{"Tool calls", fmt.Sprintf("%d", len(d.ToolCalls))},
+		{"Tokens", tokens},
+		{"Cost", FormatCost(s.Cost)},
+	}
+}
+
+// fence returns a code fence longer than any backtick run in s
+func fence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// writeCodeBlock writes s as a fenced code block
+func writeCodeBlock(w io.Writer, lang, s string) {
+	f := fence(s)
+	fmt.Fprintf(w, "%s%s\n%s\n%s\n\n", f, lang, strings.TrimRight(s, "\n"), f)
+}
+
+// exportMarkdown writes the transcript as GitHub-flavored Markdown, with
+// thinking and tool calls in collapsed <details> blocks
+func exportMarkdown(w io.Writer, d *tracker.SessionDetail) {
+	fmt.Fprintf(w, "# Session %s\n\n", d.Session.ExternalID)
+	t := table{header: []string{"Field", "Value"}}
+	for _, f := range transcriptSummary(d) {
+		t.rows = append(t.rows, []string{"**" + f[0] + "**", f[1]})
+	}
+	writeMarkdownSection(w, "Summary", t)
+
+	fmt.Fprint(w, "## Transcript\n\n")
+	for _, e := range timeline(d) {
+		if m := e.Message; m != nil {
+			fmt.Fprintf(w, "### %s · %s\n\n", roleTitle(m.Role), formatTime(m.Timestamp))
+			for _, part := range splitThinking(m.Content) {
+				if part.Thinking {
+					fmt.Fprint(w, "<details>\n<summary>Thinking</summary>\n\n")
+					writeCodeBlock(w, "text", part.Text)
+					fmt.Fprint(w, "</details>\n\n")
+				} else {
+					fmt.Fprintf(w, "%s\n\n", part.Text)
+				}
+			}
+			continue
+		}
+		tc := e.ToolCall
+		fmt.Fprintf(w, "<details>\n<summary>Tool call: %s · %s</summary>\n\n",
+			template.HTMLEscapeString(tc.ToolName), formatTime(tc.Timestamp))
+		writeCodeBlock(w, "json", tc.Arguments)
+		if tc.Result != "" {
+			fmt.Fprint(w, "Result:\n\n")
+			writeCodeBlock(w, "text", tc.Result)
+		}
+		fmt.Fprint(w, "</details>\n\n")
+	}
+}
+
+// htmlTranscript is the data of transcriptTemplate
+type htmlTranscript struct {
+	Session *tracker.SessionDetail
+	Summary [][2]string
+	Entries []htmlEntry
+}
+
+// htmlEntry is a message or tool call of an HTML transcript
+type htmlEntry struct {
+	Time     string
+	Role     string
+	Parts    []contentPart
+	ToolCall *tracker.ToolCallRow
+}
+
+var transcriptTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Session {{.Session.Session.ExternalID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #1f2328; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: 4px 12px; border-bottom: 1px solid #d0d7de; }
+.entry { margin: 1em 0; padding: 0.5em 1em; border-left: 4px solid #d0d7de; }
+.user { border-color: #0969da; }
+.assistant { border-color: #8250df; }
+.meta { color: #656d76; font-size: 0.85em; }
+pre { white-space: pre-wrap; word-wrap: break-word; background: #f6f8fa; padding: 8px; border-radius: 6px; }
+.text { white-space: pre-wrap; }
+details { margin: 0.5em 0; }
+summary { cursor: pointer; color: #656d76; }
+</style>
+</head>
+<body>
+<h1>Session {{.Session.Session.ExternalID}}</h1>
+<table>
+{{- range .Summary}}
+<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{- end}}
+</table>
+{{- range .Entries}}
+{{- if .ToolCall}}
+<details class="entry tool">
+<summary>Tool call: <code>{{.ToolCall.ToolName}}</code> <span class="meta">{{.Time}}</span></summary>
+<pre>{{.ToolCall.Arguments}}</pre>
+{{- if .ToolCall.Result}}
+<div class="meta">Result</div>
+<pre>{{.ToolCall.Result}}</pre>
+{{- end}}
+</details>
+{{- else}}
+<div class="entry {{.Role}}">
+<div class="meta"><strong>{{.Role}}</strong> {{.Time}}</div>
+{{- range .Parts}}
+{{- if .Thinking}}
+<details class="thinking"><summary>Thinking</summary><pre>{{.Text}}</pre></details>
+{{- else}}
+<div class="text">{{.Text}}</div>
+{{- end}}
+{{- end}}
+</div>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// exportHTML writes the transcript as a self-contained HTML page, with thinking
+// and tool calls in collapsed <details> elements
+func exportHTML(w io.Writer, d *tracker.SessionDetail) error {
+	data := htmlTranscript{Session: d, Summary: transcriptSummary(d)}
+	for _, e := range timeline(d) {
+		if m := e.Message; m != nil {
+			data.Entries = append(data.Entries, htmlEntry{Time: formatTime(m.Timestamp), Role: m.Role, Parts: splitThinking(m.Content)})
+		} else {
+			data.Entries = append(data.Entries, htmlEntry{Time: formatTime(e.ToolCall.Timestamp), ToolCall: e.ToolCall})
+		}
+	}
+	if err := transcriptTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
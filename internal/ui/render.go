@@ -0,0 +1,445 @@
+package ui
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+// Format names an output format of the stats and usage commands
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatTSV      Format = "tsv"
+	FormatMarkdown Format = "markdown"
+)
+
+// Formats lists the supported output formats
+var Formats = []Format{FormatText, FormatJSON, FormatCSV, FormatTSV, FormatMarkdown}
+
+// JSONSchemaVersion is bumped whenever a field of the JSON output is renamed or removed
+const JSONSchemaVersion = 1
+
+// Renderer writes usage statistics in one output format
+type Renderer interface {
+	// RenderUsageStats writes the statistics of a single agent
+	RenderUsageStats(w io.Writer, agent string, period tracker.TimeRange, stats *tracker.UsageStatsData) error
+	// RenderAllStats writes the combined statistics of all agents
+	RenderAllStats(w io.Writer, period tracker.TimeRange, stats *tracker.UsageStatsData, perAgent []tracker.PerAgentStats) error
+	// RenderProjects writes the per-project report
+	RenderProjects(w io.Writer, period tracker.TimeRange, report *ProjectsReport) error
+	// RenderSessions writes one page of the sessions list
+	RenderSessions(w io.Writer, report *SessionsReport) error
+	// RenderSession writes the detail of a single session
+	RenderSession(w io.Writer, detail *tracker.SessionDetail) error
+	// RenderSearch writes full-text search results
+	RenderSearch(w io.Writer, report *SearchReport) error
+	// RenderTurns writes the turn-level usage report
+	RenderTurns(w io.Writer, report *TurnsReport) error
+	// RenderTools writes the tool usage report
+	RenderTools(w io.Writer, report *ToolsReport) error
+	// RenderBudgets writes the usage of each budget
+	RenderBudgets(w io.Writer, report *BudgetsReport) error
+}
+
+// NewRenderer returns the renderer for the named format
+func NewRenderer(format string) (Renderer, error) {
+	switch Format(format) {
+	case FormatText, "":
+		return textRenderer{}, nil
+	case FormatJSON:
+		return jsonRenderer{}, nil
+	case FormatCSV:
+		return delimitedRenderer{comma: ','}, nil
+	case FormatTSV:
+		return delimitedRenderer{comma: '\t'}, nil
+	case FormatMarkdown:
+		return markdownRenderer{}, nil
+	default:
+		names := make([]string, len(Formats))
+		for i, f := range Formats {
+			names[i] = string(f)
+		}
+		return nil, fmt.Errorf("invalid format: %s. Use %s", format, strings.Join(names, ", "))
+	}
+}
+
+// textRenderer writes the colored terminal output
+type textRenderer struct{}
+
+func (textRenderer) RenderUsageStats(w io.Writer, agent string, period tracker.TimeRange, stats *tracker.UsageStatsData) error {
+	writeUsageStats(w, agent, period, stats)
+	return nil
+}
+
+func (textRenderer) RenderAllStats(w io.Writer, period tracker.TimeRange, stats *tracker.UsageStatsData, perAgent []tracker.PerAgentStats) error {
+	writeAllStats(w, period, stats, perAgent)
+	return nil
+}
+
+func (textRenderer) RenderProjects(w io.Writer, period tracker.TimeRange, report *ProjectsReport) error {
+	writeProjects(w, period, report.Project, report.SortBy, report.Projects)
+	return nil
+}
+
+// UsageReport is the JSON document written by the usage command
+type UsageReport struct {
+	SchemaVersion int                     `json:"schema_version"`
+	Agent         string                  `json:"agent"`
+	Period        string                  `json:"period"`
+	Start         int64                   `json:"start"`
+	End           int64                   `json:"end"`
+	Stats         *tracker.UsageStatsData `json:"stats"`
+}
+
+// StatsReport is the JSON document written by the stats command
+type StatsReport struct {
+	SchemaVersion int                     `json:"schema_version"`
+	Period        string                  `json:"period"`
+	Start         int64                   `json:"start"`
+	End           int64                   `json:"end"`
+	Stats         *tracker.UsageStatsData `json:"stats"`
+	PerAgent      []tracker.PerAgentStats `json:"per_agent"`
+}
+
+// ProjectsReport is the JSON document written by the projects command.
+// The renderers fill in SchemaVersion, Period, Start and End.
+type ProjectsReport struct {
+	SchemaVersion int                    `json:"schema_version"`
+	Period        string                 `json:"period"`
+	Start         int64                  `json:"start"`
+	End           int64                  `json:"end"`
+	Project       string                 `json:"project,omitempty"`
+	SortBy        tracker.SortOrder      `json:"sort_by"`
+	Projects      []tracker.ProjectStats `json:"projects"`
+}
+
+// jsonRenderer writes indented JSON documents
+type jsonRenderer struct{}
+
+func (jsonRenderer) RenderUsageStats(w io.Writer, agent string, period tracker.TimeRange, stats *tracker.UsageStatsData) error {
+	return writeJSON(w, UsageReport{
+		SchemaVersion: JSONSchemaVersion,
+		Agent:         agent,
+		Period:        period.String(),
+		Start:         period.Start.Unix(),
+		End:           period.End.Unix(),
+		Stats:         withEmptySlices(stats),
+	})
+}
+
+func (jsonRenderer) RenderAllStats(w io.Writer, period tracker.TimeRange, stats *tracker.UsageStatsData, perAgent []tracker.PerAgentStats) error {
+	if perAgent == nil {
+		perAgent = []tracker.PerAgentStats{}
+	}
+	return writeJSON(w, StatsReport{
+		SchemaVersion: JSONSchemaVersion,
+		Period:        period.String(),
+		Start:         period.Start.Unix(),
+		End:           period.End.Unix(),
+		Stats:         withEmptySlices(stats),
+		PerAgent:      perAgent,
+	})
+}
+
+func (jsonRenderer) RenderProjects(w io.Writer, period tracker.TimeRange, report *ProjectsReport) error {
+	out := *report
+	out.SchemaVersion = JSONSchemaVersion
+	out.Period = period.String()
+	out.Start = period.Start.Unix()
+	out.End = period.End.Unix()
+	if out.Projects == nil {
+		out.Projects = []tracker.ProjectStats{}
+	}
+	return writeJSON(w, out)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode json: %w", err)
+	}
+	return nil
+}
+
+// withEmptySlices returns a copy of stats whose list fields encode as [] rather than null
+func withEmptySlices(stats *tracker.UsageStatsData) *tracker.UsageStatsData {
+	out := *stats
+	if out.RecentSessions == nil {
+		out.RecentSessions = []tracker.SessionRow{}
+	}
+	if out.TopModels == nil {
+		out.TopModels = []tracker.ModelUsage{}
+	}
+	if out.TopProjects == nil {
+		out.TopProjects = []tracker.ProjectUsage{}
+	}
+	if out.DailySummaries == nil {
+		out.DailySummaries = []tracker.DailySummary{}
+	}
+	if out.WeeklySummaries == nil {
+		out.WeeklySummaries = []tracker.WeeklySummary{}
+	}
+	return &out
+}
+
+// table is a header row followed by data rows, shared by the CSV, TSV and markdown renderers
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+var totalsHeader = []string{
+	"sessions", "session_time", "input_tokens", "output_tokens", "cache_creation_tokens",
+	"cache_read_tokens", "total_tokens", "messages", "cost",
+}
+
+// usageTable has one row with the totals of a single agent
+func usageTable(agent string, period tracker.TimeRange, stats *tracker.UsageStatsData) table {
+	return table{
+		header: append([]string{"agent", "period"}, totalsHeader...),
+		rows: [][]string{append([]string{agent, period.String()}, totalsRow(
+			stats.SessionCount, stats.TotalSessionTime, stats.TotalInputTokens, stats.TotalOutputTokens,
+			stats.TotalCacheCreation, stats.TotalCacheRead, stats.TotalTokens, stats.TotalMessages, stats.TotalCost)...)},
+	}
+}
+
+// perAgentTable has one row per agent followed by a total row
+func perAgentTable(period tracker.TimeRange, stats *tracker.UsageStatsData, perAgent []tracker.PerAgentStats) table {
+	t := table{header: append([]string{"agent", "period"}, totalsHeader...)}
+	p := period.String()
+	for _, a := range perAgent {
+		t.rows = append(t.rows, append([]string{a.Source, p}, totalsRow(
+			a.SessionCount, a.TotalTime, a.TotalInputTokens, a.TotalOutputTokens,
+			a.TotalCacheCreation, a.TotalCacheRead, a.TotalTokens, a.TotalMessages, a.TotalCost)...))
+	}
+	t.rows = append(t.rows, append([]string{"total", p}, totalsRow(
+		stats.SessionCount, stats.TotalSessionTime, stats.TotalInputTokens, stats.TotalOutputTokens,
+		stats.TotalCacheCreation, stats.TotalCacheRead, stats.TotalTokens, stats.TotalMessages, stats.TotalCost)...))
+	return t
+}
+
+// projectsTable has one row per project
+func projectsTable(period tracker.TimeRange, projects []tracker.ProjectStats) table {
+	t := table{header: append(append([]string{"project", "period"}, totalsHeader...), "top_model", "last_activity")}
+	p := period.String()
+	for _, pr := range projects {
+		row := append([]string{pr.ProjectPath, p}, totalsRow(
+			pr.SessionCount, pr.TotalTime, pr.TotalInputTokens, pr.TotalOutputTokens,
+			pr.TotalCacheCreation, pr.TotalCacheRead, pr.TotalTokens, pr.TotalMessages, pr.TotalCost)...)
+		t.rows = append(t.rows, append(row, pr.TopModel, strconv.FormatInt(pr.LastActivity, 10)))
+	}
+	return t
+}
+
+func totalsRow(sessions, time, input, output, cacheCreation, cacheRead, total, messages int64, cost float64) []string {
+	return []string{
+		strconv.FormatInt(sessions, 10),
+		strconv.FormatInt(time, 10),
+		strconv.FormatInt(input, 10),
+		strconv.FormatInt(output, 10),
+		strconv.FormatInt(cacheCreation, 10),
+		strconv.FormatInt(cacheRead, 10),
+		strconv.FormatInt(total, 10),
+		strconv.FormatInt(messages, 10),
+		formatCostValue(cost),
+	}
+}
+
+// formatCostValue formats a cost in USD without a currency sign
+func formatCostValue(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', 4, 64)
+}
+
+// delimitedRenderer writes CSV or TSV with a header row
+type delimitedRenderer struct {
+	comma rune
+}
+
+func (r delimitedRenderer) RenderUsageStats(w io.Writer, agent string, period tracker.TimeRange, stats *tracker.UsageStatsData) error {
+	return r.write(w, usageTable(agent, period, stats))
+}
+
+func (r delimitedRenderer) RenderAllStats(w io.Writer, period tracker.TimeRange, stats *tracker.UsageStatsData, perAgent []tracker.PerAgentStats) error {
+	return r.write(w, perAgentTable(period, stats, perAgent))
+}
+
+func (r delimitedRenderer) RenderProjects(w io.Writer, period tracker.TimeRange, report *ProjectsReport) error {
+	return r.write(w, projectsTable(period, report.Projects))
+}
+
+func (r delimitedRenderer) write(w io.Writer, t table) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = r.comma
+	if err := cw.Write(t.header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	if err := cw.WriteAll(t.rows); err != nil {
+		return fmt.Errorf("failed to write rows: %w", err)
+	}
+	return nil
+}
+
+// markdownRenderer writes GitHub-flavored markdown tables
+type markdownRenderer struct{}
+
+func (markdownRenderer) RenderUsageStats(w io.Writer, agent string, period tracker.TimeRange, stats *tracker.UsageStatsData) error {
+	fmt.Fprintf(w, "## %s Usage Statistics - %s\n\n", tracker.DisplayName(agent), titlePeriod(period))
+	if stats.Project != "" {
+		fmt.Fprintf(w, "Project: `%s`\n\n", stats.Project)
+	}
+	writeMarkdownSummary(w, stats)
+
+	if len(stats.DailySummaries) > 0 {
+		t := table{header: []string{"Date", "Sessions", "Duration", "Tokens", "Cost"}}
+		for _, d := range stats.DailySummaries {
+			t.rows = append(t.rows, []string{d.Date, strconv.FormatInt(d.SessionCount, 10),
+				FormatDuration(d.TotalTime), FormatTokens(d.TotalTokens), FormatCost(d.TotalCost)})
+		}
+		writeMarkdownSection(w, "Daily Summary", t)
+	}
+	if len(stats.WeeklySummaries) > 0 {
+		t := table{header: []string{"Week", "Sessions", "Duration", "Tokens", "Cost"}}
+		for _, week := range stats.WeeklySummaries {
+			t.rows = append(t.rows, []string{week.WeekStart, strconv.FormatInt(week.SessionCount, 10),
+				FormatDuration(week.TotalTime), FormatTokens(week.TotalTokens), FormatCost(week.TotalCost)})
+		}
+		writeMarkdownSection(w, "Weekly Summary", t)
+	}
+
+	writeMarkdownTop(w, stats)
+	return nil
+}
+
+func (markdownRenderer) RenderAllStats(w io.Writer, period tracker.TimeRange, stats *tracker.UsageStatsData, perAgent []tracker.PerAgentStats) error {
+	fmt.Fprintf(w, "## Combined Usage Statistics - %s\n\n", titlePeriod(period))
+	if stats.Project != "" {
+		fmt.Fprintf(w, "Project: `%s`\n\n", stats.Project)
+	}
+
+	t := table{header: []string{"Agent", "Sessions", "Time", "Input", "Output", "Cache Write", "Cache Read", "Messages", "Cost"}}
+	for _, a := range perAgent {
+		t.rows = append(t.rows, []string{tracker.DisplayName(a.Source), strconv.FormatInt(a.SessionCount, 10),
+			FormatDuration(a.TotalTime), FormatTokens(a.TotalInputTokens), FormatTokens(a.TotalOutputTokens),
+			FormatTokens(a.TotalCacheCreation), FormatTokens(a.TotalCacheRead),
+			strconv.FormatInt(a.TotalMessages, 10), FormatCost(a.TotalCost)})
+	}
+	writeMarkdownSection(w, "Per-Agent Breakdown", t)
+	writeMarkdownSummary(w, stats)
+	if len(stats.Budgets) > 0 {
+		writeMarkdownSection(w, "Budgets", markdownBudgetsTable(stats.Budgets))
+	}
+	writeMarkdownTop(w, stats)
+
+	if len(stats.RecentSessions) > 0 {
+		t := table{header: []string{"Started", "Agent", "Model", "Project", "Duration", "Tokens", "Cost"}}
+		for _, s := range stats.RecentSessions {
+			duration := "-"
+			if s.EndedAt != nil {
+				duration = FormatDuration(*s.EndedAt - s.StartedAt)
+			}
+			t.rows = append(t.rows, []string{FormatDateTime(s.StartedAt), tracker.DisplayName(s.Source), s.Model,
+				projectName(s.ProjectPath), duration, FormatTokens(s.TotalTokens), FormatCost(s.Cost)})
+		}
+		writeMarkdownSection(w, "Recent Sessions", t)
+	}
+	return nil
+}
+
+func (markdownRenderer) RenderProjects(w io.Writer, period tracker.TimeRange, report *ProjectsReport) error {
+	fmt.Fprintf(w, "## Projects - %s\n\n", titlePeriod(period))
+	if report.Project != "" {
+		fmt.Fprintf(w, "Project: `%s`\n\n", report.Project)
+	}
+
+	t := table{header: []string{"Project", "Sessions", "Time", "Input", "Output", "Cache Write", "Cache Read", "Messages", "Cost", "Top Model", "Last Active"}}
+	for _, p := range report.Projects {
+		t.rows = append(t.rows, []string{p.ProjectPath, strconv.FormatInt(p.SessionCount, 10),
+			FormatDuration(p.TotalTime), FormatTokens(p.TotalInputTokens), FormatTokens(p.TotalOutputTokens),
+			FormatTokens(p.TotalCacheCreation), FormatTokens(p.TotalCacheRead),
+			strconv.FormatInt(p.TotalMessages, 10), FormatCost(p.TotalCost), p.TopModel, FormatDateTime(p.LastActivity)})
+	}
+	writeMarkdownSection(w, "Projects ("+sortLabel(report.SortBy)+")", t)
+	return nil
+}
+
+func writeMarkdownSummary(w io.Writer, stats *tracker.UsageStatsData) {
+	t := table{
+		header: []string{"Metric", "Value"},
+		rows: [][]string{
+			{"Sessions", strconv.FormatInt(stats.SessionCount, 10)},
+			{"Session Time", FormatDuration(stats.TotalSessionTime)},
+			{"Tokens", FormatTokens(stats.TotalTokens)},
+			{"Input Tokens", FormatTokens(stats.TotalInputTokens)},
+			{"Output Tokens", FormatTokens(stats.TotalOutputTokens)},
+			{"Cache Write Tokens", FormatTokens(stats.TotalCacheCreation)},
+			{"Cache Read Tokens", FormatTokens(stats.TotalCacheRead)},
+			{"Messages", strconv.FormatInt(stats.TotalMessages, 10)},
+			{"Tool Calls", strconv.FormatInt(stats.TotalToolCalls, 10)},
+			{"Unique Projects", strconv.FormatInt(stats.UniqueProjects, 10)},
+			{"Cost", FormatCost(stats.TotalCost)},
+		},
+	}
+	writeMarkdownSection(w, "Summary", t)
+}
+
+func writeMarkdownTop(w io.Writer, stats *tracker.UsageStatsData) {
+	models := table{header: []string{"Model", "Sessions", "Tokens", "Cost"}}
+	for _, m := range stats.TopModels {
+		models.rows = append(models.rows, []string{m.Model, strconv.FormatInt(m.SessionCount, 10),
+			FormatTokens(m.TotalTokens), FormatCost(m.TotalCost)})
+	}
+	writeMarkdownSection(w, "Top Models ("+sortLabel(stats.SortBy)+")", models)
+
+	projects := table{header: []string{"Project", "Sessions", "Tokens", "Cost"}}
+	for _, p := range stats.TopProjects {
+		projects.rows = append(projects.rows, []string{projectName(p.ProjectPath), strconv.FormatInt(p.SessionCount, 10),
+			FormatTokens(p.TotalTokens), FormatCost(p.TotalCost)})
+	}
+	writeMarkdownSection(w, "Top Projects ("+sortLabel(stats.SortBy)+")", projects)
+}
+
+// writeMarkdownSection writes a heading followed by a table, or "No data" when it has no rows
+func writeMarkdownSection(w io.Writer, heading string, t table) {
+	fmt.Fprintf(w, "### %s\n\n", heading)
+	if len(t.rows) == 0 {
+		fmt.Fprint(w, "No data\n\n")
+		return
+	}
+	writeMarkdownRow(w, t.header)
+	sep := make([]string, len(t.header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	writeMarkdownRow(w, sep)
+	for _, row := range t.rows {
+		writeMarkdownRow(w, row)
+	}
+	fmt.Fprintln(w)
+}
+
+func writeMarkdownRow(w io.Writer, cells []string) {
+	escaped := make([]string, len(cells))
+	for i, c := range cells {
+		escaped[i] = strings.ReplaceAll(c, "|", "\\|")
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+}
+
+// titlePeriod returns the period label with its first letter capitalized
+func titlePeriod(period tracker.TimeRange) string {
+	label := period.String()
+	if label == "" {
+		return label
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
@@ -0,0 +1,398 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+func testStats() (*tracker.UsageStatsData, []tracker.PerAgentStats) {
+	stats := &tracker.UsageStatsData{
+		SessionCount:      3,
+		TotalSessionTime:  600,
+		TotalInputTokens:  1000,
+		TotalOutputTokens: 500,
+		TotalTokens:       1500,
+		TotalCost:         0.0105,
+		TotalMessages:     12,
+		TopModels:         []tracker.ModelUsage{{Model: "claude-sonnet-4-5", SessionCount: 3, TotalTokens: 1500, TotalCost: 0.0105}},
+		SortBy:            tracker.SortCost,
+	}
+	perAgent := []tracker.PerAgentStats{
+		{Source: "claude", SessionCount: 2, TotalTime: 400, TotalInputTokens: 800, TotalOutputTokens: 400, TotalTokens: 1200, TotalCost: 0.0084, TotalMessages: 8},
+		{Source: "codex", SessionCount: 1, TotalTime: 200, TotalInputTokens: 200, TotalOutputTokens: 100, TotalTokens: 300, TotalCost: 0.0021, TotalMessages: 4},
+	}
+	return stats, perAgent
+}
+
+func TestNewRendererInvalidFormat(t *testing.T) {
+	if _, err := NewRenderer("xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestJSONRendererSchema(t *testing.T) {
+	stats, perAgent := testStats()
+	r, _ := NewRenderer("json")
+
+	var buf bytes.Buffer
+	if err := r.RenderAllStats(&buf, tracker.TimeRange{Label: "week"}, stats, perAgent); err != nil {
+		t.Fatalf("RenderAllStats() error = %v", err)
+	}
+
+	var report map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, buf.String())
+	}
+	if report["schema_version"] != float64(JSONSchemaVersion) || report["period"] != "week" {
+		t.Errorf("Unexpected header fields: %v", report)
+	}
+
+	s := report["stats"].(map[string]any)
+	if s["total_cost"] != 0.0105 || s["session_count"] != float64(3) || s["sort_by"] != "cost" {
+		t.Errorf("Unexpected stats: %v", s)
+	}
+	// Empty lists are encoded as [] so consumers can iterate without null checks
+	if recent, ok := s["recent_sessions"].([]any); !ok || len(recent) != 0 {
+		t.Errorf("recent_sessions = %v; want []", s["recent_sessions"])
+	}
+
+	agents := report["per_agent"].([]any)
+	first := agents[0].(map[string]any)
+	if first["source"] != "claude" || first["total_cost"] != 0.0084 {
+		t.Errorf("Unexpected per-agent entry: %v", first)
+	}
+}
+
+func TestDelimitedRenderer(t *testing.T) {
+	stats, perAgent := testStats()
+
+	r, _ := NewRenderer("csv")
+	var buf bytes.Buffer
+	if err := r.RenderAllStats(&buf, tracker.TimeRange{Label: "day"}, stats, perAgent); err != nil {
+		t.Fatalf("RenderAllStats() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header, 2 agents and total, got:\n%s", buf.String())
+	}
+	if lines[1] != "claude,day,2,400,800,400,0,0,1200,8,0.0084" {
+		t.Errorf("Unexpected claude row: %s", lines[1])
+	}
+	if !strings.HasPrefix(lines[3], "total,day,3,") {
+		t.Errorf("Unexpected total row: %s", lines[3])
+	}
+
+	r, _ = NewRenderer("tsv")
+	buf.Reset()
+	if err := r.RenderUsageStats(&buf, "claude", tracker.TimeRange{Label: "day"}, stats); err != nil {
+		t.Fatalf("RenderUsageStats() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "claude\tday\t3\t600\t") {
+		t.Errorf("Unexpected tsv output:\n%s", buf.String())
+	}
+}
+
+func TestMarkdownRenderer(t *testing.T) {
+	stats, perAgent := testStats()
+	r, _ := NewRenderer("markdown")
+
+	var buf bytes.Buffer
+	if err := r.RenderAllStats(&buf, tracker.TimeRange{Label: "month"}, stats, perAgent); err != nil {
+		t.Fatalf("RenderAllStats() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"## Combined Usage Statistics - Month",
+		"| Claude | 2 | 6.7m |",
+		"### Top Models (by cost)",
+		"| claude-sonnet-4-5 | 3 | 1.5K | $0.01 |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderProjects(t *testing.T) {
+	report := &ProjectsReport{
+		Project: "/work/*",
+		SortBy:  tracker.SortCost,
+		Projects: []tracker.ProjectStats{
+			{ProjectPath: "/work/api", SessionCount: 3, TotalTime: 1200, TotalInputTokens: 310, TotalOutputTokens: 150,
+				TotalTokens: 460, TotalCost: 0.8, TotalMessages: 9, TopModel: "claude-sonnet-4-5", LastActivity: 1790000000},
+		},
+	}
+	period := tracker.TimeRange{Label: "week"}
+
+	r, _ := NewRenderer("json")
+	var buf bytes.Buffer
+	if err := r.RenderProjects(&buf, period, report); err != nil {
+		t.Fatalf("RenderProjects() error = %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, buf.String())
+	}
+	if doc["schema_version"] != float64(JSONSchemaVersion) || doc["project"] != "/work/*" || doc["sort_by"] != "cost" {
+		t.Errorf("Unexpected header fields: %v", doc)
+	}
+	projects := doc["projects"].([]any)
+	if p := projects[0].(map[string]any); p["top_model"] != "claude-sonnet-4-5" || p["last_activity"] != float64(1790000000) {
+		t.Errorf("Unexpected project: %v", p)
+	}
+
+	r, _ = NewRenderer("csv")
+	buf.Reset()
+	if err := r.RenderProjects(&buf, period, report); err != nil {
+		t.Fatalf("RenderProjects() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], ",cost,top_model,last_activity") {
+		t.Fatalf("Unexpected csv output:\n%s", buf.String())
+	}
+	if lines[1] != "/work/api,week,3,1200,310,150,0,0,460,9,0.8000,claude-sonnet-4-5,1790000000" {
+		t.Errorf("Unexpected project row: %s", lines[1])
+	}
+
+	r, _ = NewRenderer("text")
+	buf.Reset()
+	if err := r.RenderProjects(&buf, period, &ProjectsReport{SortBy: tracker.SortSessions}); err != nil {
+		t.Fatalf("RenderProjects() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "No sessions in this period") {
+		t.Errorf("Unexpected empty text output:\n%s", buf.String())
+	}
+}
+
+func TestRenderSessions(t *testing.T) {
+	ended := int64(1790000600)
+	report := &SessionsReport{
+		Total:  45,
+		Page:   2,
+		Limit:  20,
+		SortBy: tracker.SortRecent,
+		Sessions: []tracker.SessionRow{
+			{ExternalID: "4baa9501-2706-4697-9a18-b74df590bbe5", Source: "claude", ProjectPath: "/work/api", Model: "claude-sonnet-4-5",
+				StartedAt: 1790000000, EndedAt: &ended, TotalTokens: 1500, MessageCount: 4, Cost: 0.25},
+		},
+	}
+	if report.Pages() != 3 {
+		t.Errorf("Pages() = %d; want 3", report.Pages())
+	}
+
+	r, _ := NewRenderer("csv")
+	var buf bytes.Buffer
+	if err := r.RenderSessions(&buf, report); err != nil {
+		t.Fatalf("RenderSessions() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[1] != "4baa9501-2706-4697-9a18-b74df590bbe5,claude,/work/api,claude-sonnet-4-5,1790000000,1790000600,0,0,0,0,0,1500,4,0.2500" {
+		t.Errorf("Unexpected csv output:\n%s", buf.String())
+	}
+
+	r, _ = NewRenderer("text")
+	buf.Reset()
+	if err := r.RenderSessions(&buf, report); err != nil {
+		t.Fatalf("RenderSessions() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "4baa9501-270…") || !strings.Contains(buf.String(), "Page 2 of 3 (45 sessions)") {
+		t.Errorf("Unexpected text output:\n%s", buf.String())
+	}
+}
+
+func TestRenderSession(t *testing.T) {
+	detail := &tracker.SessionDetail{
+		Session: tracker.SessionRow{ExternalID: "abc", Source: "codex", InputTokens: 1000, TotalTokens: 1000, Cost: 0.002},
+		Messages: []tracker.MessageRow{
+			{Role: "user", Content: "list files", Timestamp: 100},
+			{Role: "assistant", Content: "done", Timestamp: 105},
+		},
+		ToolCalls: []tracker.ToolCallRow{{ToolName: "shell", Arguments: `{"command":"ls"}`, Timestamp: 102}},
+	}
+
+	r, _ := NewRenderer("json")
+	var buf bytes.Buffer
+	if err := r.RenderSession(&buf, detail); err != nil {
+		t.Fatalf("RenderSession() error = %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, buf.String())
+	}
+	if doc["schema_version"] != float64(JSONSchemaVersion) || doc["session"].(map[string]any)["external_id"] != "abc" {
+		t.Errorf("Unexpected JSON: %v", doc)
+	}
+	if messages := doc["messages"].([]any); len(messages) != 2 || messages[0].(map[string]any)["role"] != "user" {
+		t.Errorf("Unexpected messages: %v", doc["messages"])
+	}
+
+	// CSV output interleaves messages and tool calls by time
+	r, _ = NewRenderer("csv")
+	buf.Reset()
+	if err := r.RenderSession(&buf, detail); err != nil {
+		t.Fatalf("RenderSession() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "102,tool_call,shell,") || !strings.HasPrefix(lines[3], "105,message,assistant,") {
+		t.Errorf("Unexpected timeline:\n%s", buf.String())
+	}
+}
+
+func TestRenderSearch(t *testing.T) {
+	report := &SearchReport{Query: "login", Results: []tracker.SearchResult{{
+		ExternalID: "abc", Source: "claude", ProjectPath: "/work/api", Role: "user", Timestamp: 100,
+		Snippet: "fix the\n" + tracker.SnippetStart + "login" + tracker.SnippetEnd + " test",
+	}}}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `"snippet": "fix the [login] test"`},
+		{"csv", "abc,claude,/work/api,user,100,fix the [login] test"},
+		{"markdown", "fix the **login** test"},
+	}
+	for _, tt := range tests {
+		r, _ := NewRenderer(tt.format)
+		var buf bytes.Buffer
+		if err := r.RenderSearch(&buf, report); err != nil {
+			t.Fatalf("%s: RenderSearch() error = %v", tt.format, err)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("%s: output missing %q:\n%s", tt.format, tt.want, buf.String())
+		}
+	}
+	if strings.Contains(report.Results[0].Snippet, "[") {
+		t.Error("RenderSearch() modified the report")
+	}
+}
+
+func TestExportSession(t *testing.T) {
+	detail := &tracker.SessionDetail{
+		Session: tracker.SessionRow{ExternalID: "abc", Source: "claude", Model: "claude-sonnet-4-5", TotalTokens: 1000, Cost: 0.01},
+		Messages: []tracker.MessageRow{
+			{Role: "user", Content: "fix <script>", Timestamp: 100},
+			{Role: "assistant", Content: tracker.ThinkingStart + "look at ```go code" + tracker.ThinkingEnd + "\nDone", Timestamp: 105},
+		},
+		ToolCalls: []tracker.ToolCallRow{{ToolName: "Bash", Arguments: `{"command":"ls"}`, Result: "main.go", Timestamp: 102}},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportSession(&buf, ExportMarkdown, detail); err != nil {
+		t.Fatalf("ExportSession(md) error = %v", err)
+	}
+	md := buf.String()
+	for _, want := range []string{"# Session abc", "<summary>Tool call: Bash", "<summary>Thinking</summary>", "````text\nlook at ```go code\n````", "Done\n"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Index(md, "Tool call") > strings.Index(md, "Thinking") {
+		t.Errorf("Tool call not in time order:\n%s", md)
+	}
+
+	buf.Reset()
+	if err := ExportSession(&buf, ExportHTML, detail); err != nil {
+		t.Fatalf("ExportSession(html) error = %v", err)
+	}
+	html := buf.String()
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "fix &lt;script&gt;") {
+		t.Errorf("HTML content not escaped:\n%s", html)
+	}
+	if strings.Count(html, "<details") != 2 {
+		t.Errorf("Expected collapsible tool call and thinking:\n%s", html)
+	}
+
+	if _, err := ParseExportFormat("pdf"); err == nil {
+		t.Error("ParseExportFormat(pdf) succeeded")
+	}
+}
+
+func TestRenderTurns(t *testing.T) {
+	turns := []tracker.SessionTurn{
+		{ExternalID: "abc", TurnRow: tracker.TurnRow{SessionID: 1, TurnIndex: 0, InputTokens: 100, TotalTokens: 120, LatencyMs: 2500}},
+		{ExternalID: "abc", TurnRow: tracker.TurnRow{SessionID: 1, TurnIndex: 1, InputTokens: 300, TotalTokens: 330, LatencyMs: 90000}},
+	}
+	report := &TurnsReport{TurnStats: tracker.BuildTurnStats(turns, 10)}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"text", "1.5m"},
+		{"json", `"growth_per_turn": 200`},
+		{"csv", "input,2,200.0,100,300,300,300"},
+		{"markdown", "| abc | 0 |"},
+	}
+	for _, tt := range tests {
+		r, _ := NewRenderer(tt.format)
+		var buf bytes.Buffer
+		if err := r.RenderTurns(&buf, report); err != nil {
+			t.Fatalf("%s: RenderTurns() error = %v", tt.format, err)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("%s: output missing %q:\n%s", tt.format, tt.want, buf.String())
+		}
+	}
+}
+
+func TestRenderTools(t *testing.T) {
+	calls := []tracker.SessionToolCall{
+		{ToolCallRow: tracker.ToolCallRow{SessionID: 1, ToolName: "shell", DurationMs: 1500}},
+		{ToolCallRow: tracker.ToolCallRow{SessionID: 1, ToolName: "shell", IsError: true}},
+	}
+	report := &ToolsReport{ToolStats: tracker.BuildToolStats(calls, 10)}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"text", "50.0%"},
+		{"json", `"mean_duration_ms": 1500`},
+		{"csv", "shell,2,1,0.5000,1,1500.0,1500,1500"},
+		{"markdown", "| shell | 2 | 1 | 50.0% | 1.5s | 1.5s | 1.5s |"},
+	}
+	for _, tt := range tests {
+		r, _ := NewRenderer(tt.format)
+		var buf bytes.Buffer
+		if err := r.RenderTools(&buf, report); err != nil {
+			t.Fatalf("%s: RenderTools() error = %v", tt.format, err)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("%s: output missing %q:\n%s", tt.format, tt.want, buf.String())
+		}
+	}
+}
+
+func TestRenderBudgets(t *testing.T) {
+	report := &BudgetsReport{Budgets: []tracker.BudgetStatus{
+		{Budget: tracker.Budget{Scope: tracker.ScopeGlobal, Period: tracker.BudgetDaily, Unit: tracker.UnitCost, Limit: 10},
+			Used: 5, Fraction: 0.5},
+		{Budget: tracker.Budget{Scope: tracker.ScopeModel, Name: "gpt-5*", Match: "gpt-5*", Period: tracker.BudgetMonthly, Unit: tracker.UnitTokens, Limit: 1000},
+			Used: 1500, Fraction: 1.5, Exceeded: true},
+	}}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"text", "[##########----------]   50%"},
+		{"json", `"exceeded": true`},
+		{"csv", "model,gpt-5*,monthly,tokens,1000,1500,1.5000,true,0,0"},
+		{"markdown", "| model gpt-5* | monthly | `[####################]` 150.0% | 1.5K | 1.0K |"},
+	}
+	for _, tt := range tests {
+		r, _ := NewRenderer(tt.format)
+		var buf bytes.Buffer
+		if err := r.RenderBudgets(&buf, report); err != nil {
+			t.Fatalf("%s: RenderBudgets() error = %v", tt.format, err)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("%s: output missing %q:\n%s", tt.format, tt.want, buf.String())
+		}
+	}
+}
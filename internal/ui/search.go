@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+// SearchReport is the result of a full-text search.
+// The JSON renderer fills in SchemaVersion.
+type SearchReport struct {
+	SchemaVersion int                    `json:"schema_version"`
+	Query         string                 `json:"query"`
+	Results       []tracker.SearchResult `json:"results"`
+}
+
+// highlight replaces the snippet markers with start and end and joins its lines
+func highlight(snippet, start, end string) string {
+	snippet = strings.Join(strings.Fields(snippet), " ")
+	return strings.NewReplacer(tracker.SnippetStart, start, tracker.SnippetEnd, end).Replace(snippet)
+}
+
+// withPlainSnippets returns a copy of results with matches marked by [ and ]
+func withPlainSnippets(results []tracker.SearchResult) []tracker.SearchResult {
+	out := make([]tracker.SearchResult, len(results))
+	for i, r := range results {
+		r.Snippet = highlight(r.Snippet, "[", "]")
+		out[i] = r
+	}
+	return out
+}
+
+// writeSearch writes the colored text form of search results
+func writeSearch(w io.Writer, report *SearchReport) {
+	fmt.Fprintf(w, "\n%sSearch: %s%s\n", ColorBold, report.Query, ColorReset)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+
+	if len(report.Results) == 0 {
+		fmt.Fprintf(w, "  %sNo matching messages%s\n", ColorYellow, ColorReset)
+		return
+	}
+	for i, r := range report.Results {
+		fmt.Fprintf(w, "\n  %d. %s%s%s  %s  %s  %s  %s\n", i+1, ColorCyan, shortID(r.ExternalID), ColorReset,
+			FormatDateTime(r.Timestamp), tracker.DisplayName(r.Source), projectName(r.ProjectPath), r.Role)
+		fmt.Fprintf(w, "     %s\n", highlight(r.Snippet, ColorBold+ColorYellow, ColorReset))
+	}
+	fmt.Fprintf(w, "\n  %d results\n", len(report.Results))
+}
+
+func (textRenderer) RenderSearch(w io.Writer, report *SearchReport) error {
+	writeSearch(w, report)
+	return nil
+}
+
+func (jsonRenderer) RenderSearch(w io.Writer, report *SearchReport) error {
+	out := *report
+	out.SchemaVersion = JSONSchemaVersion
+	out.Results = withPlainSnippets(report.Results)
+	return writeJSON(w, out)
+}
+
+func (r delimitedRenderer) RenderSearch(w io.Writer, report *SearchReport) error {
+	t := table{header: []string{"external_id", "agent", "project", "role", "timestamp", "snippet"}}
+	for _, res := range withPlainSnippets(report.Results) {
+		t.rows = append(t.rows, []string{res.ExternalID, res.Source, res.ProjectPath, res.Role,
+			strconv.FormatInt(res.Timestamp, 10), res.Snippet})
+	}
+	return r.write(w, t)
+}
+
+func (markdownRenderer) RenderSearch(w io.Writer, report *SearchReport) error {
+	fmt.Fprintf(w, "## Search: %s\n\n", report.Query)
+	t := table{header: []string{"Session", "Time", "Agent", "Project", "Role", "Snippet"}}
+	for _, r := range report.Results {
+		t.rows = append(t.rows, []string{r.ExternalID, FormatDateTime(r.Timestamp), tracker.DisplayName(r.Source),
+			projectName(r.ProjectPath), r.Role, highlight(r.Snippet, "**", "**")})
+	}
+	writeMarkdownSection(w, fmt.Sprintf("%d results", len(report.Results)), t)
+	return nil
+}
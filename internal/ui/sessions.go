@@ -0,0 +1,314 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+// SessionsReport is one page of the sessions list.
+// The JSON renderer fills in SchemaVersion.
+type SessionsReport struct {
+	SchemaVersion int                  `json:"schema_version"`
+	Total         int64                `json:"total"` // matching sessions across all pages
+	Page          int                  `json:"page"`
+	Limit         int                  `json:"limit"`
+	SortBy        tracker.SortOrder    `json:"sort_by"`
+	Sessions      []tracker.SessionRow `json:"sessions"`
+}
+
+// Pages returns the number of pages of the report
+func (r *SessionsReport) Pages() int {
+	if r.Limit <= 0 || r.Total == 0 {
+		return 1
+	}
+	return int((r.Total + int64(r.Limit) - 1) / int64(r.Limit))
+}
+
+// SessionReport is the JSON document written by the session command
+type SessionReport struct {
+	SchemaVersion int `json:"schema_version"`
+	*tracker.SessionDetail
+}
+
+// shortID returns the first characters of an external ID, enough to select it with the session command
+func shortID(id string) string {
+	return truncate(id, 13)
+}
+
+// sessionDuration formats the duration of a session, or "-" if it has not ended
+func sessionDuration(s tracker.SessionRow) string {
+	if s.EndedAt == nil {
+		return "-"
+	}
+	return FormatDuration(*s.EndedAt - s.StartedAt)
+}
+
+// firstLine returns the first non-empty line of s, shortened to n runes
+func firstLine(s string, n int) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return truncate(line, n)
+		}
+	}
+	return ""
+}
+
+// formatTime formats a Unix timestamp as a time of day
+func formatTime(timestamp int64) string {
+	return time.Unix(timestamp, 0).In(location).Format("15:04:05")
+}
+
+// writeSessions writes the colored text form of the sessions list
+func writeSessions(w io.Writer, report *SessionsReport) {
+	fmt.Fprintf(w, "\n%sSessions (%s)%s\n", ColorBold, sortLabel(report.SortBy), ColorReset)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+
+	if len(report.Sessions) == 0 {
+		fmt.Fprintf(w, "  %sNo matching sessions%s\n", ColorYellow, ColorReset)
+		return
+	}
+
+	fmt.Fprintf(w, "  %-13s %-19s %-8s %-20s %-16s %8s %8s %6s %8s\n",
+		"ID", "Started", "Agent", "Model", "Project", "Duration", "Tokens", "Msgs", "Cost")
+	fmt.Fprintf(w, "  %s\n", strings.Repeat("-", 116))
+	for _, s := range report.Sessions {
+		model := s.Model
+		if model == "" {
+			model = "(unknown)"
+		}
+		fmt.Fprintf(w, "  %-13s %-19s %-8s %-20s %-16s %8s %8s %6d %8s\n",
+			shortID(s.ExternalID),
+			FormatDateTime(s.StartedAt),
+			truncate(tracker.DisplayName(s.Source), 8),
+			truncate(model, 20),
+			truncate(projectName(s.ProjectPath), 16),
+			sessionDuration(s),
+			FormatTokens(s.TotalTokens),
+			s.MessageCount,
+			FormatCost(s.Cost))
+	}
+	fmt.Fprintf(w, "\n  Page %d of %d (%d sessions)\n", report.Page, report.Pages(), report.Total)
+}
+
+// writeSessionDetail writes the colored text form of a single session
+func writeSessionDetail(w io.Writer, d *tracker.SessionDetail) {
+	s := d.Session
+	fmt.Fprintf(w, "\n%sSession %s%s\n", ColorBold, s.ExternalID, ColorReset)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+	fmt.Fprintf(w, "  Agent:      %s\n", tracker.DisplayName(s.Source))
+	fmt.Fprintf(w, "  Project:    %s\n", s.ProjectPath)
+	fmt.Fprintf(w, "  Model:      %s\n", s.Model)
+	fmt.Fprintf(w, "  Provider:   %s\n", s.Provider)
+	fmt.Fprintf(w, "  Start:      %s\n", FormatDateTime(s.StartedAt))
+	if s.EndedAt != nil {
+		fmt.Fprintf(w, "  End:        %s\n", FormatDateTime(*s.EndedAt))
+	}
+	fmt.Fprintf(w, "  Duration:   %s\n", sessionDuration(s))
+
+	fmt.Fprintf(w, "\n%s%sTokens and Cost%s\n", ColorBold, ColorMagenta, ColorReset)
+	for _, row := range []struct {
+		label  string
+		tokens int64
+		cost   float64
+	}{
+		{"Input", s.InputTokens, d.CostBreakdown.Input},
+		{"Output", s.OutputTokens, d.CostBreakdown.Output},
+		{"Cache Write", s.CacheCreationTokens, d.CostBreakdown.CacheWrite},
+		{"Cache Read", s.CacheReadTokens, d.CostBreakdown.CacheRead},
+		{"Reasoning", s.ReasoningTokens, d.CostBreakdown.Reasoning},
+	} {
+		fmt.Fprintf(w, "  %-12s %10s %10s\n", row.label+":", FormatTokens(row.tokens), FormatCost(row.cost))
+	}
+	fmt.Fprintf(w, "  %-12s %10s %10s\n", "Total:", FormatTokens(s.TotalTokens), FormatCost(s.Cost))
+
+	if len(d.Models) > 1 {
+		fmt.Fprintf(w, "\n%s%sModels%s\n", ColorBold, ColorMagenta, ColorReset)
+		for _, m := range d.Models {
+			fmt.Fprintf(w, "  %-30s %10s %10s\n", truncate(m.Model, 30), FormatTokens(m.TotalTokens), FormatCost(m.Cost))
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s%sMessages (%d)%s\n", ColorBold, ColorCyan, len(d.Messages), ColorReset)
+	if len(d.Messages) == 0 {
+		fmt.Fprintf(w, "  %sNo messages%s\n", ColorYellow, ColorReset)
+	}
+	for _, m := range d.Messages {
+		fmt.Fprintf(w, "  %s  %-9s %s\n", formatTime(m.Timestamp), m.Role, firstLine(m.Content, 100))
+	}
+
+	fmt.Fprintf(w, "\n%s%sTool Calls (%d)%s\n", ColorBold, ColorBlue, len(d.ToolCalls), ColorReset)
+	if len(d.ToolCalls) == 0 {
+		fmt.Fprintf(w, "  %sNo tool calls%s\n", ColorYellow, ColorReset)
+	}
+	for _, tc := range d.ToolCalls {
+		fmt.Fprintf(w, "  %s  %-16s %s\n", formatTime(tc.Timestamp), truncate(tc.ToolName, 16), firstLine(tc.Arguments, 90))
+	}
+}
+
+// sessionsTable has one row per session
+func sessionsTable(sessions []tracker.SessionRow) table {
+	t := table{header: []string{
+		"external_id", "agent", "project", "model", "started_at", "ended_at", "input_tokens", "output_tokens",
+		"cache_creation_tokens", "cache_read_tokens", "reasoning_tokens", "total_tokens", "messages", "cost",
+	}}
+	for _, s := range sessions {
+		var ended string
+		if s.EndedAt != nil {
+			ended = strconv.FormatInt(*s.EndedAt, 10)
+		}
+		t.rows = append(t.rows, []string{
+			s.ExternalID, s.Source, s.ProjectPath, s.Model, strconv.FormatInt(s.StartedAt, 10), ended,
+			strconv.FormatInt(s.InputTokens, 10), strconv.FormatInt(s.OutputTokens, 10),
+			strconv.FormatInt(s.CacheCreationTokens, 10), strconv.FormatInt(s.CacheReadTokens, 10),
+			strconv.FormatInt(s.ReasoningTokens, 10), strconv.FormatInt(s.TotalTokens, 10),
+			strconv.FormatInt(s.MessageCount, 10), formatCostValue(s.Cost),
+		})
+	}
+	return t
+}
+
+// timelineEntry is either a message or a tool call of a session
+type timelineEntry struct {
+	Message  *tracker.MessageRow
+	ToolCall *tracker.ToolCallRow
+}
+
+// timeline merges the messages and tool calls of a session in time order
+func timeline(d *tracker.SessionDetail) []timelineEntry {
+	entries := make([]timelineEntry, 0, len(d.Messages)+len(d.ToolCalls))
+	i, j := 0, 0
+	for i < len(d.Messages) || j < len(d.ToolCalls) {
+		if j == len(d.ToolCalls) || (i < len(d.Messages) && d.Messages[i].Timestamp <= d.ToolCalls[j].Timestamp) {
+			entries = append(entries, timelineEntry{Message: &d.Messages[i]})
+			i++
+		} else {
+			entries = append(entries, timelineEntry{ToolCall: &d.ToolCalls[j]})
+			j++
+		}
+	}
+	return entries
+}
+
+// timelineTable has one row per message and tool call, in time order
+func timelineTable(d *tracker.SessionDetail) table {
+	t := table{header: []string{"timestamp", "kind", "name", "content"}}
+	for _, e := range timeline(d) {
+		if m := e.Message; m != nil {
+			t.rows = append(t.rows, []string{strconv.FormatInt(m.Timestamp, 10), "message", m.Role, m.Content})
+		} else {
+			tc := e.ToolCall
+			t.rows = append(t.rows, []string{strconv.FormatInt(tc.Timestamp, 10), "tool_call", tc.ToolName, tc.Arguments})
+		}
+	}
+	return t
+}
+
+func (textRenderer) RenderSessions(w io.Writer, report *SessionsReport) error {
+	writeSessions(w, report)
+	return nil
+}
+
+func (textRenderer) RenderSession(w io.Writer, detail *tracker.SessionDetail) error {
+	writeSessionDetail(w, detail)
+	return nil
+}
+
+func (jsonRenderer) RenderSessions(w io.Writer, report *SessionsReport) error {
+	out := *report
+	out.SchemaVersion = JSONSchemaVersion
+	if out.Sessions == nil {
+		out.Sessions = []tracker.SessionRow{}
+	}
+	return writeJSON(w, out)
+}
+
+func (jsonRenderer) RenderSession(w io.Writer, detail *tracker.SessionDetail) error {
+	out := *detail
+	if out.Models == nil {
+		out.Models = []tracker.SessionModelRow{}
+	}
+	if out.Messages == nil {
+		out.Messages = []tracker.MessageRow{}
+	}
+	if out.ToolCalls == nil {
+		out.ToolCalls = []tracker.ToolCallRow{}
+	}
+	return writeJSON(w, SessionReport{SchemaVersion: JSONSchemaVersion, SessionDetail: &out})
+}
+
+func (r delimitedRenderer) RenderSessions(w io.Writer, report *SessionsReport) error {
+	return r.write(w, sessionsTable(report.Sessions))
+}
+
+func (r delimitedRenderer) RenderSession(w io.Writer, detail *tracker.SessionDetail) error {
+	return r.write(w, timelineTable(detail))
+}
+
+func (markdownRenderer) RenderSessions(w io.Writer, report *SessionsReport) error {
+	fmt.Fprintf(w, "## Sessions\n\n")
+	t := table{header: []string{"ID", "Started", "Agent", "Model", "Project", "Duration", "Tokens", "Messages", "Cost"}}
+	for _, s := range report.Sessions {
+		t.rows = append(t.rows, []string{s.ExternalID, FormatDateTime(s.StartedAt), tracker.DisplayName(s.Source), s.Model,
+			projectName(s.ProjectPath), sessionDuration(s), FormatTokens(s.TotalTokens),
+			strconv.FormatInt(s.MessageCount, 10), FormatCost(s.Cost)})
+	}
+	writeMarkdownSection(w, fmt.Sprintf("Page %d of %d (%d sessions, %s)", report.Page, report.Pages(), report.Total, sortLabel(report.SortBy)), t)
+	return nil
+}
+
+func (markdownRenderer) RenderSession(w io.Writer, d *tracker.SessionDetail) error {
+	s := d.Session
+	fmt.Fprintf(w, "## Session %s\n\n", s.ExternalID)
+	ended := "-"
+	if s.EndedAt != nil {
+		ended = FormatDateTime(*s.EndedAt)
+	}
+	writeMarkdownSection(w, "Metadata", table{
+		header: []string{"Field", "Value"},
+		rows: [][]string{
+			{"Agent", tracker.DisplayName(s.Source)},
+			{"Project", s.ProjectPath},
+			{"Model", s.Model},
+			{"Provider", s.Provider},
+			{"Start", FormatDateTime(s.StartedAt)},
+			{"End", ended},
+			{"Duration", sessionDuration(s)},
+		},
+	})
+	writeMarkdownSection(w, "Tokens and Cost", table{
+		header: []string{"Type", "Tokens", "Cost"},
+		rows: [][]string{
+			{"Input", FormatTokens(s.InputTokens), FormatCost(d.CostBreakdown.Input)},
+			{"Output", FormatTokens(s.OutputTokens), FormatCost(d.CostBreakdown.Output)},
+			{"Cache Write", FormatTokens(s.CacheCreationTokens), FormatCost(d.CostBreakdown.CacheWrite)},
+			{"Cache Read", FormatTokens(s.CacheReadTokens), FormatCost(d.CostBreakdown.CacheRead)},
+			{"Reasoning", FormatTokens(s.ReasoningTokens), FormatCost(d.CostBreakdown.Reasoning)},
+			{"Total", FormatTokens(s.TotalTokens), FormatCost(s.Cost)},
+		},
+	})
+	if len(d.Models) > 1 {
+		models := table{header: []string{"Model", "Tokens", "Cost"}}
+		for _, m := range d.Models {
+			models.rows = append(models.rows, []string{m.Model, FormatTokens(m.TotalTokens), FormatCost(m.Cost)})
+		}
+		writeMarkdownSection(w, "Models", models)
+	}
+
+	messages := table{header: []string{"Time", "Role", "Content"}}
+	for _, m := range d.Messages {
+		messages.rows = append(messages.rows, []string{formatTime(m.Timestamp), m.Role, firstLine(m.Content, 100)})
+	}
+	writeMarkdownSection(w, "Messages", messages)
+
+	toolCalls := table{header: []string{"Time", "Tool", "Arguments"}}
+	for _, tc := range d.ToolCalls {
+		toolCalls.rows = append(toolCalls.rows, []string{formatTime(tc.Timestamp), tc.ToolName, firstLine(tc.Arguments, 90)})
+	}
+	writeMarkdownSection(w, "Tool Calls", toolCalls)
+	return nil
+}
@@ -0,0 +1,143 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+// ToolsReport is the JSON document written by the tools command.
+// The JSON renderer fills in SchemaVersion.
+type ToolsReport struct {
+	SchemaVersion int `json:"schema_version"`
+	*tracker.ToolStats
+}
+
+// formatRate formats a fraction as a percentage
+func formatRate(rate float64) string {
+	return fmt.Sprintf("%.1f%%", rate*100)
+}
+
+// formatTimedLatency formats a duration in milliseconds, or "-" without timed calls
+func formatTimedLatency(timed int64, ms int64) string {
+	if timed == 0 {
+		return "-"
+	}
+	return formatLatency(ms)
+}
+
+// writeTools writes the colored text form of the tool report
+func writeTools(w io.Writer, stats *tracker.ToolStats) {
+	fmt.Fprintf(w, "\n%sTool Usage%s\n", ColorBold, ColorReset)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+
+	if stats.CallCount == 0 {
+		fmt.Fprintf(w, "  %sNo tool calls recorded%s\n", ColorYellow, ColorReset)
+		return
+	}
+	fmt.Fprintf(w, "  %d calls in %d sessions, %d failed\n", stats.CallCount, stats.SessionCount, stats.ErrorCount)
+
+	fmt.Fprintf(w, "\n%s%sTools%s\n", ColorBold, ColorCyan, ColorReset)
+	fmt.Fprintf(w, "  %-24s %8s %8s %8s %10s %10s %10s\n", "", "Calls", "Errors", "Rate", "Mean", "p50", "p95")
+	for _, u := range stats.Tools {
+		fmt.Fprintf(w, "  %-24s %8d %8d %8s %10s %10s %10s\n", truncate(u.ToolName, 24), u.Calls, u.Errors,
+			formatRate(u.ErrorRate), formatTimedLatency(u.TimedCalls, int64(u.MeanDurationMs)),
+			formatTimedLatency(u.TimedCalls, u.P50DurationMs), formatTimedLatency(u.TimedCalls, u.P95DurationMs))
+	}
+
+	writeToolGroups(w, "By Agent", stats.Agents, func(name string) string { return name })
+	writeToolGroups(w, "By Project", stats.Projects, projectName)
+
+	if len(stats.Commands) > 0 {
+		fmt.Fprintf(w, "\n%s%sShell Commands%s\n", ColorBold, ColorCyan, ColorReset)
+		fmt.Fprintf(w, "  %-40s %8s %8s\n", "", "Runs", "Errors")
+		for _, c := range stats.Commands {
+			fmt.Fprintf(w, "  %-40s %8d %8d\n", truncate(c.Command, 40), c.Runs, c.Errors)
+		}
+	}
+
+	if len(stats.Files) > 0 {
+		fmt.Fprintf(w, "\n%s%sEdited Files%s\n", ColorBold, ColorCyan, ColorReset)
+		fmt.Fprintf(w, "  %-50s %8s\n", "", "Edits")
+		for _, f := range stats.Files {
+			fmt.Fprintf(w, "  %-50s %8d\n", truncate(f.Path, 50), f.Edits)
+		}
+	}
+}
+
+// writeToolGroups writes the tool calls of each agent or project, naming them with label
+func writeToolGroups(w io.Writer, title string, groups []tracker.ToolGroupUsage, label func(string) string) {
+	fmt.Fprintf(w, "\n%s%s%s%s\n", ColorBold, ColorCyan, title, ColorReset)
+	fmt.Fprintf(w, "  %-40s %8s %8s %8s  %s\n", "", "Calls", "Errors", "Rate", "Top Tool")
+	for _, g := range groups {
+		fmt.Fprintf(w, "  %-40s %8d %8d %8s  %s\n", truncate(label(g.Name), 40), g.Calls, g.Errors,
+			formatRate(g.ErrorRate), g.TopTool)
+	}
+}
+
+// toolsTable has one row per tool
+func toolsTable(stats *tracker.ToolStats) table {
+	t := table{header: []string{"tool_name", "calls", "errors", "error_rate", "timed_calls", "mean_duration_ms",
+		"p50_duration_ms", "p95_duration_ms"}}
+	for _, u := range stats.Tools {
+		t.rows = append(t.rows, []string{u.ToolName, strconv.FormatInt(u.Calls, 10), strconv.FormatInt(u.Errors, 10),
+			strconv.FormatFloat(u.ErrorRate, 'f', 4, 64), strconv.FormatInt(u.TimedCalls, 10),
+			strconv.FormatFloat(u.MeanDurationMs, 'f', 1, 64), strconv.FormatInt(u.P50DurationMs, 10),
+			strconv.FormatInt(u.P95DurationMs, 10)})
+	}
+	return t
+}
+
+// toolGroupsTable has one row per agent or project
+func toolGroupsTable(name string, groups []tracker.ToolGroupUsage) table {
+	t := table{header: []string{name, "Calls", "Errors", "Error Rate", "Top Tool"}}
+	for _, g := range groups {
+		t.rows = append(t.rows, []string{g.Name, strconv.FormatInt(g.Calls, 10), strconv.FormatInt(g.Errors, 10),
+			formatRate(g.ErrorRate), g.TopTool})
+	}
+	return t
+}
+
+func (textRenderer) RenderTools(w io.Writer, report *ToolsReport) error {
+	writeTools(w, report.ToolStats)
+	return nil
+}
+
+func (jsonRenderer) RenderTools(w io.Writer, report *ToolsReport) error {
+	return writeJSON(w, ToolsReport{SchemaVersion: JSONSchemaVersion, ToolStats: report.ToolStats})
+}
+
+func (r delimitedRenderer) RenderTools(w io.Writer, report *ToolsReport) error {
+	return r.write(w, toolsTable(report.ToolStats))
+}
+
+func (markdownRenderer) RenderTools(w io.Writer, report *ToolsReport) error {
+	stats := report.ToolStats
+	fmt.Fprintf(w, "## Tool Usage\n\n%d calls in %d sessions, %d failed\n\n", stats.CallCount, stats.SessionCount, stats.ErrorCount)
+
+	tools := table{header: []string{"Tool", "Calls", "Errors", "Error Rate", "Mean Duration", "p50", "p95"}}
+	for _, u := range stats.Tools {
+		tools.rows = append(tools.rows, []string{u.ToolName, strconv.FormatInt(u.Calls, 10), strconv.FormatInt(u.Errors, 10),
+			formatRate(u.ErrorRate), formatTimedLatency(u.TimedCalls, int64(u.MeanDurationMs)),
+			formatTimedLatency(u.TimedCalls, u.P50DurationMs), formatTimedLatency(u.TimedCalls, u.P95DurationMs)})
+	}
+	writeMarkdownSection(w, "Tools", tools)
+	writeMarkdownSection(w, "By Agent", toolGroupsTable("Agent", stats.Agents))
+	writeMarkdownSection(w, "By Project", toolGroupsTable("Project", stats.Projects))
+
+	commands := table{header: []string{"Command", "Runs", "Errors"}}
+	for _, c := range stats.Commands {
+		commands.rows = append(commands.rows, []string{"`" + c.Command + "`", strconv.FormatInt(c.Runs, 10), strconv.FormatInt(c.Errors, 10)})
+	}
+	writeMarkdownSection(w, "Shell Commands", commands)
+
+	files := table{header: []string{"File", "Edits"}}
+	for _, f := range stats.Files {
+		files.rows = append(files.rows, []string{f.Path, strconv.FormatInt(f.Edits, 10)})
+	}
+	writeMarkdownSection(w, "Edited Files", files)
+	return nil
+}
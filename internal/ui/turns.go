@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ari/agent-usage/internal/tracker"
+)
+
+// TurnsReport is the JSON document written by the usage turns command.
+// The JSON renderer fills in SchemaVersion.
+type TurnsReport struct {
+	SchemaVersion int `json:"schema_version"`
+	*tracker.TurnStats
+}
+
+// distributionLabels are the display names of the token types of a distribution
+var distributionLabels = map[string]string{
+	"input":          "Input",
+	"output":         "Output",
+	"cache_creation": "Cache Write",
+	"cache_read":     "Cache Read",
+	"reasoning":      "Reasoning",
+	"total":          "Total",
+	"context":        "Context",
+}
+
+// formatLatency formats a latency in milliseconds
+func formatLatency(ms int64) string {
+	if ms < 60_000 {
+		return fmt.Sprintf("%.1fs", float64(ms)/1000)
+	}
+	return FormatDuration(ms / 1000)
+}
+
+// writeTurns writes the colored text form of the turn report
+func writeTurns(w io.Writer, stats *tracker.TurnStats) {
+	fmt.Fprintf(w, "\n%sTurn Usage%s\n", ColorBold, ColorReset)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+
+	if stats.TurnCount == 0 {
+		fmt.Fprintf(w, "  %sNo turns recorded%s\n", ColorYellow, ColorReset)
+		return
+	}
+	fmt.Fprintf(w, "  %d turns in %d sessions\n", stats.TurnCount, stats.SessionCount)
+
+	fmt.Fprintf(w, "\n%s%sTokens per Turn%s\n", ColorBold, ColorMagenta, ColorReset)
+	fmt.Fprintf(w, "  %-12s %10s %10s %10s %10s %10s\n", "", "Mean", "p50", "p90", "p99", "Max")
+	for _, d := range stats.Distribution {
+		fmt.Fprintf(w, "  %-12s %10s %10s %10s %10s %10s\n", distributionLabels[d.Type]+":",
+			FormatTokens(int64(d.Mean)), FormatTokens(d.P50), FormatTokens(d.P90), FormatTokens(d.P99), FormatTokens(d.Max))
+	}
+
+	fmt.Fprintf(w, "\n%s%sSlowest Turns%s\n", ColorBold, ColorCyan, ColorReset)
+	if len(stats.Slowest) == 0 {
+		fmt.Fprintf(w, "  %sNo turn latencies recorded%s\n", ColorYellow, ColorReset)
+	}
+	for i, t := range stats.Slowest {
+		fmt.Fprintf(w, "  %2d. %-13s #%-4d %-19s %-20s %8s %8s\n", i+1, shortID(t.ExternalID), t.TurnIndex,
+			FormatDateTime(t.Timestamp), truncate(t.Model, 20), formatLatency(t.LatencyMs), FormatTokens(t.TotalTokens))
+	}
+
+	fmt.Fprintf(w, "\n%s%sFastest Context Growth%s\n", ColorBold, ColorBlue, ColorReset)
+	if len(stats.FastestGrowth) == 0 {
+		fmt.Fprintf(w, "  %sNo sessions with more than one turn%s\n", ColorYellow, ColorReset)
+	}
+	for i, g := range stats.FastestGrowth {
+		fmt.Fprintf(w, "  %2d. %-13s %-16s %5d turns  %8s -> %-8s %8s/turn\n", i+1, shortID(g.ExternalID),
+			truncate(projectName(g.ProjectPath), 16), g.Turns, FormatTokens(g.FirstContext), FormatTokens(g.LastContext),
+			FormatTokens(int64(g.GrowthPerTurn)))
+	}
+}
+
+// distributionTable has one row per token type
+func distributionTable(stats *tracker.TurnStats) table {
+	t := table{header: []string{"type", "turns", "mean", "p50", "p90", "p99", "max"}}
+	for _, d := range stats.Distribution {
+		t.rows = append(t.rows, []string{d.Type, strconv.FormatInt(stats.TurnCount, 10), strconv.FormatFloat(d.Mean, 'f', 1, 64),
+			strconv.FormatInt(d.P50, 10), strconv.FormatInt(d.P90, 10), strconv.FormatInt(d.P99, 10), strconv.FormatInt(d.Max, 10)})
+	}
+	return t
+}
+
+func (textRenderer) RenderTurns(w io.Writer, report *TurnsReport) error {
+	writeTurns(w, report.TurnStats)
+	return nil
+}
+
+func (jsonRenderer) RenderTurns(w io.Writer, report *TurnsReport) error {
+	return writeJSON(w, TurnsReport{SchemaVersion: JSONSchemaVersion, TurnStats: report.TurnStats})
+}
+
+func (r delimitedRenderer) RenderTurns(w io.Writer, report *TurnsReport) error {
+	return r.write(w, distributionTable(report.TurnStats))
+}
+
+func (markdownRenderer) RenderTurns(w io.Writer, report *TurnsReport) error {
+	stats := report.TurnStats
+	fmt.Fprintf(w, "## Turn Usage\n\n%d turns in %d sessions\n\n", stats.TurnCount, stats.SessionCount)
+
+	dist := table{header: []string{"Tokens", "Mean", "p50", "p90", "p99", "Max"}}
+	for _, d := range stats.Distribution {
+		dist.rows = append(dist.rows, []string{distributionLabels[d.Type], FormatTokens(int64(d.Mean)),
+			FormatTokens(d.P50), FormatTokens(d.P90), FormatTokens(d.P99), FormatTokens(d.Max)})
+	}
+	writeMarkdownSection(w, "Tokens per Turn", dist)
+
+	slowest := table{header: []string{"Session", "Turn", "Time", "Model", "Latency", "Tokens"}}
+	for _, t := range stats.Slowest {
+		slowest.rows = append(slowest.rows, []string{t.ExternalID, strconv.Itoa(t.TurnIndex), FormatDateTime(t.Timestamp),
+			t.Model, formatLatency(t.LatencyMs), FormatTokens(t.TotalTokens)})
+	}
+	writeMarkdownSection(w, "Slowest Turns", slowest)
+
+	growth := table{header: []string{"Session", "Project", "Turns", "First Context", "Last Context", "Growth per Turn"}}
+	for _, g := range stats.FastestGrowth {
+		growth.rows = append(growth.rows, []string{g.ExternalID, projectName(g.ProjectPath), strconv.FormatInt(g.Turns, 10),
+			FormatTokens(g.FirstContext), FormatTokens(g.LastContext), FormatTokens(int64(g.GrowthPerTurn))})
+	}
+	writeMarkdownSection(w, "Fastest Context Growth", growth)
+	return nil
+}
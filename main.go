@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	_ "time/tzdata"
 
 	"github.com/ari/agent-usage/cmd"
 )